@@ -0,0 +1,154 @@
+package article
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FrontMatter holds the article metadata read from the Markdown header.
+type FrontMatter struct {
+	Title       string
+	Summary     string
+	Image       string
+	PublishedAt *time.Time
+	Tags        []string
+}
+
+// parseFrontMatter splits a leading "---" delimited block from src. Only the
+// subset of YAML used for article metadata is understood: "key: value" pairs
+// with optional quotes, and tags given either inline ([a, b]) or as a "- item"
+// list. Documents without front matter are returned unchanged.
+func parseFrontMatter(src string) (FrontMatter, string, error) {
+	var fm FrontMatter
+
+	src = strings.TrimPrefix(src, "\ufeff")
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	lines := strings.Split(src, "\n")
+	if strings.TrimRight(lines[0], " \t") != "---" {
+		return fm, src, nil
+	}
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], " \t") == "---" {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return fm, "", fmt.Errorf("front matter is not closed with ---")
+	}
+	header := lines[1:end]
+	body := strings.Join(lines[end+1:], "\n")
+
+	var listKey string
+	for i, line := range header {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if item, isItem := strings.CutPrefix(trimmed, "- "); isItem && listKey != "" {
+			if listKey == "tags" {
+				fm.Tags = appendTag(fm.Tags, unquote(item))
+			}
+			continue
+		}
+
+		key, value, found := strings.Cut(trimmed, ":")
+		if !found {
+			return fm, "", fmt.Errorf("front matter line %d: expected key: value", i+2)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		listKey = ""
+		if value == "" {
+			listKey = key
+			continue
+		}
+
+		switch key {
+		case "title":
+			fm.Title = unquote(value)
+		case "summary":
+			fm.Summary = unquote(value)
+		case "image":
+			fm.Image = unquote(value)
+		case "published_at":
+			t, err := parsePublishedAt(unquote(value))
+			if err != nil {
+				return fm, "", fmt.Errorf("front matter published_at: %w", err)
+			}
+			fm.PublishedAt = &t
+		case "tags":
+			inline := strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+			for _, tag := range strings.Split(inline, ",") {
+				fm.Tags = appendTag(fm.Tags, unquote(strings.TrimSpace(tag)))
+			}
+		}
+	}
+
+	return fm, body, nil
+}
+
+// parsePublishedAt accepts a unix timestamp, RFC3339 or a plain date.
+func parsePublishedAt(v string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("unsupported time %q (want unix seconds, RFC3339 or YYYY-MM-DD)", v)
+}
+
+func unquote(v string) string {
+	if len(v) >= 2 && (v[0] == '"' && v[len(v)-1] == '"' || v[0] == '\'' && v[len(v)-1] == '\'') {
+		if v[0] == '"' {
+			if s, err := strconv.Unquote(v); err == nil {
+				return s
+			}
+		}
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
+func appendTag(tags []string, tag string) []string {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	if tag == "" {
+		return tags
+	}
+	for _, existing := range tags {
+		if existing == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
+
+// IdentifierFromPath derives the article's d tag from its file name, e.g.
+// "notes/Release Notes v1.2.md" becomes "release-notes-v1.2".
+func IdentifierFromPath(path string) string {
+	base := filepath.Base(path)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(base) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_':
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimRight(b.String(), "-")
+}
@@ -0,0 +1,85 @@
+package article
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	src := "---\r\n" +
+		"title: \"Release notes: v1.2\"\r\n" +
+		"summary: What's new\r\n" +
+		"image: https://example.com/cover.png\r\n" +
+		"published_at: 2024-05-01T09:00:00Z\r\n" +
+		"tags: [Nostr, '#release', nostr]\r\n" +
+		"---\r\n" +
+		"# Heading\r\n\r\nBody\r\n"
+
+	fm, body, err := parseFrontMatter(src)
+	if err != nil {
+		t.Fatalf("parseFrontMatter() unexpected error: %v", err)
+	}
+	if fm.Title != "Release notes: v1.2" || fm.Summary != "What's new" || fm.Image != "https://example.com/cover.png" {
+		t.Fatalf("unexpected front matter: %+v", fm)
+	}
+	if fm.PublishedAt == nil || !fm.PublishedAt.Equal(time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected published_at: %v", fm.PublishedAt)
+	}
+	if !reflect.DeepEqual(fm.Tags, []string{"nostr", "release"}) {
+		t.Fatalf("unexpected tags: %v", fm.Tags)
+	}
+	if body != "# Heading\n\nBody\n" {
+		t.Fatalf("unexpected body: %q", body)
+	}
+}
+
+func TestParseFrontMatterVariants(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		wantTags []string
+		wantBody string
+		wantErr  bool
+	}{
+		{name: "no front matter", src: "# Title\n", wantBody: "# Title\n"},
+		{name: "empty block", src: "---\n---\nbody", wantBody: "body"},
+		{name: "block list tags", src: "---\ntags:\n  - go\n  - nostr\ntitle: x\n---\nbody", wantTags: []string{"go", "nostr"}, wantBody: "body"},
+		{name: "unclosed", src: "---\ntitle: x\nbody", wantErr: true},
+		{name: "invalid line", src: "---\nnot yaml\n---\n", wantErr: true},
+		{name: "invalid date", src: "---\npublished_at: soon\n---\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, body, err := parseFrontMatter(tt.src)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseFrontMatter() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFrontMatter() unexpected error: %v", err)
+			}
+			if body != tt.wantBody || !reflect.DeepEqual(fm.Tags, tt.wantTags) {
+				t.Fatalf("parseFrontMatter() = %+v, %q", fm, body)
+			}
+		})
+	}
+}
+
+func TestIdentifierFromPath(t *testing.T) {
+	tests := map[string]string{
+		"post.md":                      "post",
+		"notes/Release Notes v1.2.md":  "release-notes-v1.2",
+		"/tmp/2024-05-01_changelog.md": "2024-05-01_changelog",
+		"日本語の記事.markdown":              "日本語の記事",
+		"--weird--name!!.md":           "weird-name",
+	}
+	for path, want := range tests {
+		if got := IdentifierFromPath(path); got != want {
+			t.Fatalf("IdentifierFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package article
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ANSI escape sequences used when rendering to a terminal.
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiDim       = "\x1b[2m"
+	ansiItalic    = "\x1b[3m"
+	ansiUnderline = "\x1b[4m"
+	ansiCyan      = "\x1b[36m"
+)
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	bulletPattern  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedPattern = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	rulePattern    = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+
+	imagePattern  = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)[^)]*\)`)
+	linkPattern   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+	codePattern   = regexp.MustCompile("`([^`]+)`")
+	boldPattern   = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicPattern = regexp.MustCompile(`\*([^*\s][^*]*)\*|\b_([^_\s][^_]*)_\b`)
+)
+
+// markdownRenderer writes a terminal friendly rendering of Markdown. It
+// covers the constructs common in release notes rather than all of
+// CommonMark; anything unrecognised is printed as-is.
+type markdownRenderer struct {
+	// color enables ANSI styling; without it only the markup is simplified.
+	color bool
+}
+
+func (r markdownRenderer) render(w io.Writer, src string) error {
+	var (
+		b         strings.Builder
+		inFence   bool
+		fenceMark string
+	)
+
+	for _, line := range strings.Split(stripControl(src), "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if inFence {
+			if strings.HasPrefix(trimmed, fenceMark) {
+				inFence = false
+				continue
+			}
+			b.WriteString("    " + r.style(line, ansiCyan) + "\n")
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence, fenceMark = true, trimmed[:3]
+			continue
+		}
+
+		switch {
+		case headingPattern.MatchString(trimmed):
+			m := headingPattern.FindStringSubmatch(trimmed)
+			b.WriteString(r.heading(len(m[1]), r.inline(m[2])) + "\n")
+		case rulePattern.MatchString(line):
+			b.WriteString(r.style(strings.Repeat("─", 40), ansiDim) + "\n")
+		case strings.HasPrefix(trimmed, ">"):
+			quote := strings.TrimSpace(strings.TrimLeft(trimmed, ">"))
+			b.WriteString(r.style("│ ", ansiDim) + r.style(r.inline(quote), ansiItalic) + "\n")
+		case bulletPattern.MatchString(line):
+			m := bulletPattern.FindStringSubmatch(line)
+			b.WriteString(m[1] + "  • " + r.inline(m[2]) + "\n")
+		case orderedPattern.MatchString(line):
+			m := orderedPattern.FindStringSubmatch(line)
+			b.WriteString(fmt.Sprintf("%s  %s. %s\n", m[1], m[2], r.inline(m[3])))
+		default:
+			b.WriteString(r.inline(line) + "\n")
+		}
+	}
+
+	_, err := io.WriteString(w, strings.TrimRight(b.String(), "\n")+"\n")
+	return err
+}
+
+func (r markdownRenderer) heading(level int, text string) string {
+	if !r.color {
+		return strings.Repeat("#", level) + " " + text
+	}
+	if level <= 2 {
+		return ansiBold + ansiUnderline + text + ansiReset
+	}
+	return ansiBold + text + ansiReset
+}
+
+// inline rewrites images, links, code spans and emphasis within one line.
+func (r markdownRenderer) inline(s string) string {
+	s = imagePattern.ReplaceAllStringFunc(s, func(m string) string {
+		sub := imagePattern.FindStringSubmatch(m)
+		return fmt.Sprintf("[image: %s] (%s)", sub[1], r.style(sub[2], ansiUnderline))
+	})
+	s = linkPattern.ReplaceAllStringFunc(s, func(m string) string {
+		sub := linkPattern.FindStringSubmatch(m)
+		if sub[1] == sub[2] {
+			return r.style(sub[2], ansiUnderline)
+		}
+		return fmt.Sprintf("%s (%s)", sub[1], r.style(sub[2], ansiUnderline))
+	})
+	s = codePattern.ReplaceAllStringFunc(s, func(m string) string {
+		return r.style(codePattern.FindStringSubmatch(m)[1], ansiCyan)
+	})
+	s = boldPattern.ReplaceAllStringFunc(s, func(m string) string {
+		sub := boldPattern.FindStringSubmatch(m)
+		return r.style(sub[1]+sub[2], ansiBold)
+	})
+	s = italicPattern.ReplaceAllStringFunc(s, func(m string) string {
+		sub := italicPattern.FindStringSubmatch(m)
+		return r.style(sub[1]+sub[2], ansiItalic)
+	})
+	return s
+}
+
+func (r markdownRenderer) style(s, code string) string {
+	if !r.color || s == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// stripControl removes C0 and C1 control characters except newline and tab,
+// so relay-supplied text cannot smuggle escape sequences such as cursor moves,
+// OSC 52 clipboard writes or OSC 8 hyperlinks into the terminal.
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if r < 0x20 || (r >= 0x7f && r <= 0x9f) {
+			return -1
+		}
+		return r
+	}, s)
+}
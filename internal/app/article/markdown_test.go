@@ -0,0 +1,56 @@
+package article
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownRendererPlain(t *testing.T) {
+	src := strings.Join([]string{
+		"# Title",
+		"Some **bold**, *italic* and `code` with a [link](https://example.com).",
+		"",
+		"- item one",
+		"1. first",
+		"> quoted",
+		"---",
+		"```go",
+		"fmt.Println(\"**not bold**\")",
+		"```",
+		"![cover](https://example.com/c.png)",
+		"snake_case_name stays",
+	}, "\n")
+
+	var buf bytes.Buffer
+	if err := (markdownRenderer{}).render(&buf, src); err != nil {
+		t.Fatalf("render() unexpected error: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"# Title",
+		"Some bold, italic and code with a link (https://example.com).",
+		"",
+		"  • item one",
+		"  1. first",
+		"│ quoted",
+		strings.Repeat("─", 40),
+		"    fmt.Println(\"**not bold**\")",
+		"[image: cover] (https://example.com/c.png)",
+		"snake_case_name stays",
+	}, "\n") + "\n"
+	if buf.String() != want {
+		t.Fatalf("render() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestMarkdownRendererColor(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (markdownRenderer{color: true}).render(&buf, "## Section\n**bold**"); err != nil {
+		t.Fatalf("render() unexpected error: %v", err)
+	}
+	want := ansiBold + ansiUnderline + "Section" + ansiReset + "\n" + ansiBold + "bold" + ansiReset + "\n"
+	if buf.String() != want {
+		t.Fatalf("render() = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,300 @@
+package article
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"noscli/internal/app/profile"
+	"noscli/internal/nostr"
+)
+
+// KindLongForm is the NIP-23 long-form content kind.
+const KindLongForm = 30023
+
+// PublishRequest describes a Markdown article to publish.
+type PublishRequest struct {
+	Relays []string
+	// Identifier is the d tag; publishing again with the same one replaces the article.
+	Identifier string
+	// Markdown is the source including optional front matter.
+	Markdown string
+	// DryRun prints the signed event instead of publishing it.
+	DryRun bool
+}
+
+// ShowRequest selects an article to display.
+type ShowRequest struct {
+	Relays  []string
+	Pointer nostr.EntityPointer
+	// Color enables ANSI styling of the rendered Markdown.
+	Color bool
+}
+
+// Client exposes the subset of nostr client functionality needed by the article service.
+type Client interface {
+	FetchLatest(ctx context.Context, relays []string, filter nostr.Filter) ([]nostr.Event, error)
+	PublishMany(ctx context.Context, relays []string, evt nostr.Event) ([]nostr.PublishResult, error)
+}
+
+// Profiles resolves the author shown with an article.
+type Profiles interface {
+	Resolve(ctx context.Context, pubkey string) (profile.Profile, bool)
+}
+
+// Service publishes and reads NIP-23 long-form articles.
+type Service struct {
+	client   Client
+	profiles Profiles
+	logger   *slog.Logger
+}
+
+// NewService creates a Service that relies on the given nostr client.
+// profiles may be nil, in which case authors are shown as truncated hex.
+func NewService(client Client, profiles Profiles, logger *slog.Logger) *Service {
+	return &Service{client: client, profiles: profiles, logger: logger}
+}
+
+// Publish signs the article as a kind 30023 event and sends it to every relay.
+// When the front matter has no published_at, the value of an earlier version
+// is kept so re-publishing updates the article without changing its date.
+func (s *Service) Publish(ctx context.Context, req PublishRequest, w io.Writer) error {
+	if req.Identifier == "" {
+		return errors.New("identifier is required")
+	}
+	if !req.DryRun && len(req.Relays) == 0 {
+		return errors.New("relay is required")
+	}
+
+	fm, body, err := parseFrontMatter(req.Markdown)
+	if err != nil {
+		return err
+	}
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return errors.New("article body is empty")
+	}
+
+	keys, err := nostr.LoadKeysFromEnv()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	createdAt := now.Unix()
+	publishedAt := now.Unix()
+	if fm.PublishedAt != nil {
+		publishedAt = fm.PublishedAt.Unix()
+	}
+	if previous, ok := s.latest(ctx, req.Relays, nostr.EntityPointer{Identifier: req.Identifier, PubKey: keys.Public, Kind: KindLongForm}); ok {
+		if v, found := previous.TagValue("published_at"); found && fm.PublishedAt == nil {
+			if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+				publishedAt = unix
+			}
+		}
+		// 置き換えが確実に反映されるよう、時計がずれていても以前の版より新しくする
+		if createdAt <= previous.CreatedAt {
+			createdAt = previous.CreatedAt + 1
+		}
+	}
+
+	evt := nostr.Event{
+		PubKey:    keys.Public,
+		CreatedAt: createdAt,
+		Kind:      KindLongForm,
+		Tags:      buildTags(req.Identifier, fm, publishedAt),
+		Content:   body,
+	}
+	if err := nostr.SignEvent(&evt, keys.Private); err != nil {
+		return fmt.Errorf("sign event: %w", err)
+	}
+
+	naddr, err := nostr.EncodeNaddr(nostr.EntityPointer{
+		Identifier: req.Identifier,
+		PubKey:     evt.PubKey,
+		Kind:       evt.Kind,
+		Relays:     firstN(req.Relays, 2),
+	})
+	if err != nil {
+		return err
+	}
+
+	if req.DryRun {
+		b, err := json.Marshal(evt)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	}
+
+	results, err := s.client.PublishMany(ctx, req.Relays, evt)
+	for _, res := range results {
+		status := "ok"
+		if res.Err != nil {
+			status = "failed: " + res.Err.Error()
+		}
+		if _, werr := fmt.Fprintf(w, "published: d:%s relay:%s %s\n", req.Identifier, res.Relay, status); werr != nil {
+			return werr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", naddr)
+	return err
+}
+
+// Show fetches the newest version of the article and renders it to w.
+func (s *Service) Show(ctx context.Context, req ShowRequest, w io.Writer) error {
+	relays := mergeRelays(req.Pointer.Relays, req.Relays)
+	if len(relays) == 0 {
+		return errors.New("relay is required")
+	}
+
+	evt, ok := s.latest(ctx, relays, req.Pointer)
+	if !ok {
+		return fmt.Errorf("article %d:%s:%s not found", req.Pointer.Kind, req.Pointer.PubKey, req.Pointer.Identifier)
+	}
+
+	// タイトル等はリレー由来のため、装飾を付ける前に制御文字を取り除く
+	md := markdownRenderer{color: req.Color}
+	title, _ := evt.TagValue("title")
+	if title == "" {
+		title = req.Pointer.Identifier
+	}
+	if _, err := fmt.Fprintln(w, md.heading(1, stripControl(singleLine(title)))); err != nil {
+		return err
+	}
+
+	published := evt.CreatedAtTime()
+	if v, found := evt.TagValue("published_at"); found {
+		if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+			published = time.Unix(unix, 0)
+		}
+	}
+	meta := fmt.Sprintf("by %s · published %s", s.authorLabel(ctx, evt.PubKey), published.Local().Format("2006-01-02"))
+	if evt.CreatedAtTime().Sub(published) > time.Minute {
+		meta += " · updated " + evt.CreatedAtTime().Local().Format("2006-01-02")
+	}
+	if tags := hashtags(evt); len(tags) > 0 {
+		meta += " · #" + strings.Join(tags, " #")
+	}
+	if _, err := fmt.Fprintln(w, md.style(stripControl(singleLine(meta)), ansiDim)); err != nil {
+		return err
+	}
+	if summary, _ := evt.TagValue("summary"); summary != "" {
+		if _, err := fmt.Fprintln(w, md.style(stripControl(summary), ansiItalic)); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+
+	return md.render(w, evt.Content)
+}
+
+// authorLabel renders the author's profile name when known, otherwise truncated hex.
+func (s *Service) authorLabel(ctx context.Context, pubkey string) string {
+	if s.profiles != nil {
+		if p, ok := s.profiles.Resolve(ctx, pubkey); ok {
+			if label := p.Label(); label != "" {
+				return label
+			}
+		}
+	}
+	return truncateHex(pubkey)
+}
+
+// latest returns the newest event at the given address across relays.
+func (s *Service) latest(ctx context.Context, relays []string, p nostr.EntityPointer) (nostr.Event, bool) {
+	if len(relays) == 0 {
+		return nostr.Event{}, false
+	}
+	filter := nostr.Filter{
+		Authors: []string{p.PubKey},
+		Kinds:   []int{p.Kind},
+		Tags:    map[string][]string{"d": {p.Identifier}},
+	}
+	events, err := s.client.FetchLatest(ctx, relays, filter)
+	if err != nil {
+		s.logger.Debug("fetch article failed", "identifier", p.Identifier, "error", err)
+		return nostr.Event{}, false
+	}
+	// FetchLatest は同じアドレスの版を最新の 1 件にまとめるため、フィルタに合う最初のイベントが目的の版
+	for _, evt := range events {
+		if filter.Matches(evt) {
+			return evt, true
+		}
+	}
+	return nostr.Event{}, false
+}
+
+// buildTags assembles the NIP-23 tags in the order other clients emit them.
+func buildTags(identifier string, fm FrontMatter, publishedAt int64) [][]string {
+	tags := [][]string{{"d", identifier}}
+	if fm.Title != "" {
+		tags = append(tags, []string{"title", fm.Title})
+	}
+	if fm.Summary != "" {
+		tags = append(tags, []string{"summary", fm.Summary})
+	}
+	if fm.Image != "" {
+		tags = append(tags, []string{"image", fm.Image})
+	}
+	tags = append(tags, []string{"published_at", strconv.FormatInt(publishedAt, 10)})
+	for _, t := range fm.Tags {
+		tags = append(tags, []string{"t", t})
+	}
+	return tags
+}
+
+func hashtags(evt nostr.Event) []string {
+	var tags []string
+	for _, tag := range evt.Tags {
+		if len(tag) >= 2 && tag[0] == "t" {
+			tags = append(tags, tag[1])
+		}
+	}
+	return tags
+}
+
+func mergeRelays(lists ...[]string) []string {
+	var merged []string
+	seen := make(map[string]struct{})
+	for _, list := range lists {
+		for _, relay := range list {
+			if _, ok := seen[relay]; ok || relay == "" {
+				continue
+			}
+			seen[relay] = struct{}{}
+			merged = append(merged, relay)
+		}
+	}
+	return merged
+}
+
+func firstN(list []string, n int) []string {
+	if len(list) > n {
+		return list[:n]
+	}
+	return list
+}
+
+func truncateHex(in string) string {
+	if len(in) <= 12 {
+		return in
+	}
+	return fmt.Sprintf("%s...%s", in[:6], in[len(in)-4:])
+}
+
+func singleLine(in string) string {
+	return strings.Join(strings.Fields(in), " ")
+}
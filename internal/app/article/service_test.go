@@ -0,0 +1,172 @@
+package article
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"noscli/internal/app/profile"
+	"noscli/internal/nostr"
+)
+
+type memoryRelay struct {
+	events    []nostr.Event
+	published []nostr.Event
+}
+
+func (m *memoryRelay) FetchLatest(_ context.Context, _ []string, filter nostr.Filter) ([]nostr.Event, error) {
+	var out []nostr.Event
+	for _, evt := range m.events {
+		if filter.Matches(evt) {
+			out = append(out, evt)
+		}
+	}
+	return nostr.LatestPerAddress(out), nil
+}
+
+func (m *memoryRelay) PublishMany(_ context.Context, relays []string, evt nostr.Event) ([]nostr.PublishResult, error) {
+	m.published = append(m.published, evt)
+	m.events = append(m.events, evt)
+	results := make([]nostr.PublishResult, len(relays))
+	for i, relay := range relays {
+		results[i] = nostr.PublishResult{Relay: relay}
+	}
+	return results, nil
+}
+
+func setKey(t *testing.T) string {
+	t.Helper()
+	priv := bytes.Repeat([]byte{0x07}, 32)
+	nsec, err := nostr.EncodeNsec(priv)
+	if err != nil {
+		t.Fatalf("EncodeNsec: %v", err)
+	}
+	t.Setenv("NOSTR_NSEC", nsec)
+	pub, err := nostr.PublicKey(priv)
+	if err != nil {
+		t.Fatalf("PublicKey: %v", err)
+	}
+	return pub
+}
+
+func TestServicePublishAndRepublish(t *testing.T) {
+	pub := setKey(t)
+	relay := &memoryRelay{}
+	svc := NewService(relay, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	relays := []string{"wss://relay.example"}
+
+	first := "---\ntitle: v1.2\ntags: [release]\n---\nFirst draft\n"
+	var out bytes.Buffer
+	if err := svc.Publish(context.Background(), PublishRequest{Relays: relays, Identifier: "notes", Markdown: first}, &out); err != nil {
+		t.Fatalf("Publish() unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "naddr1") {
+		t.Fatalf("output does not include naddr: %s", out.String())
+	}
+
+	original := relay.published[0]
+	if original.Kind != KindLongForm || original.PubKey != pub || original.Content != "First draft" {
+		t.Fatalf("unexpected event: %+v", original)
+	}
+	for _, want := range [][]string{{"d", "notes"}, {"title", "v1.2"}, {"t", "release"}} {
+		if v, _ := original.TagValue(want[0]); v != want[1] {
+			t.Fatalf("tag %s = %q, want %q", want[0], v, want[1])
+		}
+	}
+	publishedAt, _ := original.TagValue("published_at")
+
+	// Pretend the first version came from a clock ahead of ours.
+	relay.events[0].CreatedAt = time.Now().Add(time.Hour).Unix()
+	if err := nostr.SignEvent(&relay.events[0], bytes.Repeat([]byte{0x07}, 32)); err != nil {
+		t.Fatalf("SignEvent: %v", err)
+	}
+
+	if err := svc.Publish(context.Background(), PublishRequest{Relays: relays, Identifier: "notes", Markdown: "Second draft"}, io.Discard); err != nil {
+		t.Fatalf("Publish() unexpected error: %v", err)
+	}
+	updated := relay.published[1]
+	if v, _ := updated.TagValue("published_at"); v != publishedAt {
+		t.Fatalf("published_at changed on re-publish: %s -> %s", publishedAt, v)
+	}
+	if updated.CreatedAt <= relay.events[0].CreatedAt {
+		t.Fatalf("re-published created_at %d is not newer than %d", updated.CreatedAt, relay.events[0].CreatedAt)
+	}
+}
+
+func TestServiceShow(t *testing.T) {
+	pub := setKey(t)
+	relay := &memoryRelay{}
+	svc := NewService(relay, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	relays := []string{"wss://relay.example"}
+
+	for _, md := range []string{
+		"---\ntitle: Old\n---\nold body",
+		"---\ntitle: Release notes\nsummary: Highlights\npublished_at: 1700000000\n---\n## Changes\n- **faster** sync",
+	} {
+		if err := svc.Publish(context.Background(), PublishRequest{Relays: relays, Identifier: "notes", Markdown: md}, io.Discard); err != nil {
+			t.Fatalf("Publish() unexpected error: %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	req := ShowRequest{Pointer: nostr.EntityPointer{Identifier: "notes", PubKey: pub, Kind: KindLongForm, Relays: relays}}
+	if err := svc.Show(context.Background(), req, &out); err != nil {
+		t.Fatalf("Show() unexpected error: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{"# Release notes\n", "published 2023-11-1", "Highlights\n", "## Changes\n", "  • faster sync\n"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "old body") {
+		t.Fatalf("output shows an outdated version:\n%s", got)
+	}
+
+	missing := ShowRequest{Pointer: nostr.EntityPointer{Identifier: "other", PubKey: pub, Kind: KindLongForm}, Relays: relays}
+	if err := svc.Show(context.Background(), missing, io.Discard); err == nil {
+		t.Fatalf("Show() expected error for a missing article")
+	}
+}
+
+type staticProfiles map[string]profile.Profile
+
+func (p staticProfiles) Resolve(_ context.Context, pubkey string) (profile.Profile, bool) {
+	prof, ok := p[pubkey]
+	return prof, ok
+}
+
+func TestServiceShowStripsControlCharacters(t *testing.T) {
+	pub := setKey(t)
+	relay := &memoryRelay{}
+	profiles := staticProfiles{pub: {PubKey: pub, Name: "alice\x1b[2J"}}
+	svc := NewService(relay, profiles, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	relays := []string{"wss://relay.example"}
+
+	clipboard := "\x1b]52;c;cm0gLXJmIH4=\x07"
+	md := "---\ntitle: \"Hi" + clipboard + "\"\nsummary: \"\x1b[1Asum\u009b2J\"\n---\nbody" + clipboard + " **bold**\r\nnext\tline"
+	if err := svc.Publish(context.Background(), PublishRequest{Relays: relays, Identifier: "esc", Markdown: md}, io.Discard); err != nil {
+		t.Fatalf("Publish() unexpected error: %v", err)
+	}
+
+	var out bytes.Buffer
+	req := ShowRequest{Pointer: nostr.EntityPointer{Identifier: "esc", PubKey: pub, Kind: KindLongForm, Relays: relays}, Color: true}
+	if err := svc.Show(context.Background(), req, &out); err != nil {
+		t.Fatalf("Show() unexpected error: %v", err)
+	}
+
+	got := out.String()
+	if strings.Contains(got, "\x1b]") || strings.Contains(got, "\x07") || strings.Contains(got, "\u009b") || strings.Contains(got, "\x1b[2J") || strings.Contains(got, "\x1b[1A") {
+		t.Fatalf("output contains relay-supplied control sequences: %q", got)
+	}
+	for _, want := range []string{"Hi]52;c;cm0gLXJmIH4=", "by @alice[2J", "[1Asum2J", "body]52;c;cm0gLXJmIH4= ", "next\tline"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("output missing %q: %q", want, got)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package dm
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"noscli/internal/nip44"
+	"noscli/internal/nostr"
+)
+
+// Event kinds used by NIP-17 private direct messages and NIP-59 gift wraps.
+const (
+	KindChatMessage = 14
+	KindSeal        = 13
+	KindGiftWrap    = 1059
+	KindDMRelayList = 10050
+)
+
+// timestampJitter is how far into the past seal and gift wrap timestamps are
+// randomized so relays cannot correlate them with the real send time.
+const timestampJitter = 2 * 24 * time.Hour
+
+// rumor is an unsigned event. It is serialized without a sig field so it can
+// never be published on its own.
+type rumor struct {
+	ID        string     `json:"id"`
+	PubKey    string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+}
+
+// newRumor builds a kind 14 chat message from sender to recipient.
+func newRumor(sender, recipient, content string, now time.Time) (rumor, error) {
+	evt := nostr.Event{
+		PubKey:    sender,
+		CreatedAt: now.Unix(),
+		Kind:      KindChatMessage,
+		Tags:      [][]string{{"p", recipient}},
+		Content:   content,
+	}
+	id, err := evt.ComputeID()
+	if err != nil {
+		return rumor{}, err
+	}
+	return rumor{
+		ID:        id,
+		PubKey:    evt.PubKey,
+		CreatedAt: evt.CreatedAt,
+		Kind:      evt.Kind,
+		Tags:      evt.Tags,
+		Content:   evt.Content,
+	}, nil
+}
+
+// giftWrap seals the rumor with the sender key and wraps the seal with a fresh
+// ephemeral key addressed to receiver.
+func giftWrap(r rumor, senderPriv []byte, receiver string, now time.Time) (nostr.Event, error) {
+	rumorJSON, err := json.Marshal(r)
+	if err != nil {
+		return nostr.Event{}, fmt.Errorf("marshal rumor: %w", err)
+	}
+	sealKey, err := nip44.ConversationKey(senderPriv, receiver)
+	if err != nil {
+		return nostr.Event{}, fmt.Errorf("seal conversation key: %w", err)
+	}
+	sealContent, err := nip44.Encrypt(string(rumorJSON), sealKey)
+	if err != nil {
+		return nostr.Event{}, fmt.Errorf("encrypt rumor: %w", err)
+	}
+
+	seal := nostr.Event{
+		PubKey:    r.PubKey,
+		CreatedAt: randomPast(now),
+		Kind:      KindSeal,
+		Tags:      [][]string{},
+		Content:   sealContent,
+	}
+	if err := nostr.SignEvent(&seal, senderPriv); err != nil {
+		return nostr.Event{}, fmt.Errorf("sign seal: %w", err)
+	}
+
+	ephemeral, err := nostr.GeneratePrivateKey()
+	if err != nil {
+		return nostr.Event{}, err
+	}
+	ephemeralPub, err := nostr.PublicKey(ephemeral)
+	if err != nil {
+		return nostr.Event{}, err
+	}
+	sealJSON, err := json.Marshal(seal)
+	if err != nil {
+		return nostr.Event{}, fmt.Errorf("marshal seal: %w", err)
+	}
+	wrapKey, err := nip44.ConversationKey(ephemeral, receiver)
+	if err != nil {
+		return nostr.Event{}, fmt.Errorf("wrap conversation key: %w", err)
+	}
+	wrapContent, err := nip44.Encrypt(string(sealJSON), wrapKey)
+	if err != nil {
+		return nostr.Event{}, fmt.Errorf("encrypt seal: %w", err)
+	}
+
+	wrap := nostr.Event{
+		PubKey:    ephemeralPub,
+		CreatedAt: randomPast(now),
+		Kind:      KindGiftWrap,
+		Tags:      [][]string{{"p", receiver}},
+		Content:   wrapContent,
+	}
+	if err := nostr.SignEvent(&wrap, ephemeral); err != nil {
+		return nostr.Event{}, fmt.Errorf("sign gift wrap: %w", err)
+	}
+	return wrap, nil
+}
+
+// unwrap opens a gift wrap addressed to priv and returns the inner rumor.
+// The seal signature is verified and the rumor author must match the seal
+// signer so a third party cannot impersonate someone else.
+func unwrap(wrap nostr.Event, priv []byte) (nostr.Event, error) {
+	if wrap.Kind != KindGiftWrap {
+		return nostr.Event{}, fmt.Errorf("unexpected kind: %d", wrap.Kind)
+	}
+
+	wrapKey, err := nip44.ConversationKey(priv, wrap.PubKey)
+	if err != nil {
+		return nostr.Event{}, fmt.Errorf("wrap conversation key: %w", err)
+	}
+	sealJSON, err := nip44.Decrypt(wrap.Content, wrapKey)
+	if err != nil {
+		return nostr.Event{}, fmt.Errorf("decrypt gift wrap: %w", err)
+	}
+	var seal nostr.Event
+	if err := json.Unmarshal([]byte(sealJSON), &seal); err != nil {
+		return nostr.Event{}, fmt.Errorf("decode seal: %w", err)
+	}
+	if seal.Kind != KindSeal {
+		return nostr.Event{}, fmt.Errorf("unexpected seal kind: %d", seal.Kind)
+	}
+	if err := seal.Verify(); err != nil {
+		return nostr.Event{}, fmt.Errorf("verify seal: %w", err)
+	}
+
+	sealKey, err := nip44.ConversationKey(priv, seal.PubKey)
+	if err != nil {
+		return nostr.Event{}, fmt.Errorf("seal conversation key: %w", err)
+	}
+	rumorJSON, err := nip44.Decrypt(seal.Content, sealKey)
+	if err != nil {
+		return nostr.Event{}, fmt.Errorf("decrypt seal: %w", err)
+	}
+	var inner nostr.Event
+	if err := json.Unmarshal([]byte(rumorJSON), &inner); err != nil {
+		return nostr.Event{}, fmt.Errorf("decode rumor: %w", err)
+	}
+	if inner.PubKey != seal.PubKey {
+		return nostr.Event{}, errors.New("rumor author does not match seal signer")
+	}
+	id, err := inner.ComputeID()
+	if err != nil {
+		return nostr.Event{}, err
+	}
+	if inner.ID != id {
+		return nostr.Event{}, errors.New("rumor id mismatch")
+	}
+	inner.Relay = wrap.Relay
+	return inner, nil
+}
+
+// randomPast returns a unix timestamp uniformly chosen within timestampJitter before now.
+func randomPast(now time.Time) int64 {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(timestampJitter/time.Second)))
+	if err != nil {
+		return now.Unix()
+	}
+	return now.Unix() - n.Int64()
+}
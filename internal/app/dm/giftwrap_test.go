@@ -0,0 +1,134 @@
+package dm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"noscli/internal/nip44"
+	"noscli/internal/nostr"
+)
+
+func TestGiftWrapRoundTrip(t *testing.T) {
+	senderPriv := bytes.Repeat([]byte{0x01}, 32)
+	receiverPriv := bytes.Repeat([]byte{0x02}, 32)
+	sender := mustPublicKey(t, senderPriv)
+	receiver := mustPublicKey(t, receiverPriv)
+	now := time.Unix(1_700_000_000, 0)
+
+	r, err := newRumor(sender, receiver, "こんにちは", now)
+	if err != nil {
+		t.Fatalf("newRumor() unexpected error: %v", err)
+	}
+	wrap, err := giftWrap(r, senderPriv, receiver, now)
+	if err != nil {
+		t.Fatalf("giftWrap() unexpected error: %v", err)
+	}
+
+	if wrap.Kind != KindGiftWrap {
+		t.Fatalf("wrap kind = %d, want %d", wrap.Kind, KindGiftWrap)
+	}
+	if wrap.PubKey == sender {
+		t.Fatalf("gift wrap must be signed by an ephemeral key")
+	}
+	if p, _ := wrap.TagValue("p"); p != receiver {
+		t.Fatalf("wrap p tag = %q, want %q", p, receiver)
+	}
+	if wrap.CreatedAt > now.Unix() || wrap.CreatedAt < now.Add(-timestampJitter).Unix() {
+		t.Fatalf("wrap created_at %d not within randomization window", wrap.CreatedAt)
+	}
+	if err := wrap.Verify(); err != nil {
+		t.Fatalf("wrap Verify() failed: %v", err)
+	}
+
+	inner, err := unwrap(wrap, receiverPriv)
+	if err != nil {
+		t.Fatalf("unwrap() unexpected error: %v", err)
+	}
+	if inner.Kind != KindChatMessage || inner.Content != "こんにちは" || inner.PubKey != sender {
+		t.Fatalf("unexpected rumor: %+v", inner)
+	}
+	if inner.CreatedAt != now.Unix() {
+		t.Fatalf("rumor created_at = %d, want %d", inner.CreatedAt, now.Unix())
+	}
+	if inner.Sig != "" {
+		t.Fatalf("rumor must not be signed, got sig %q", inner.Sig)
+	}
+
+	if _, err := unwrap(wrap, bytes.Repeat([]byte{0x03}, 32)); err == nil {
+		t.Fatalf("unwrap() with a foreign key expected error")
+	}
+}
+
+func TestUnwrapRejectsImpersonation(t *testing.T) {
+	attackerPriv := bytes.Repeat([]byte{0x01}, 32)
+	receiverPriv := bytes.Repeat([]byte{0x02}, 32)
+	victim := mustPublicKey(t, bytes.Repeat([]byte{0x04}, 32))
+	receiver := mustPublicKey(t, receiverPriv)
+	now := time.Unix(1_700_000_000, 0)
+
+	// The attacker signs the seal but claims the rumor was written by the victim.
+	r, err := newRumor(victim, receiver, "trust me", now)
+	if err != nil {
+		t.Fatalf("newRumor() unexpected error: %v", err)
+	}
+	rumorJSON, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("marshal rumor: %v", err)
+	}
+	key, err := nip44.ConversationKey(attackerPriv, receiver)
+	if err != nil {
+		t.Fatalf("ConversationKey() unexpected error: %v", err)
+	}
+	content, err := nip44.Encrypt(string(rumorJSON), key)
+	if err != nil {
+		t.Fatalf("Encrypt() unexpected error: %v", err)
+	}
+	seal := nostr.Event{
+		PubKey:    mustPublicKey(t, attackerPriv),
+		CreatedAt: now.Unix(),
+		Kind:      KindSeal,
+		Tags:      [][]string{},
+		Content:   content,
+	}
+	if err := nostr.SignEvent(&seal, attackerPriv); err != nil {
+		t.Fatalf("SignEvent() unexpected error: %v", err)
+	}
+	sealJSON, err := json.Marshal(seal)
+	if err != nil {
+		t.Fatalf("marshal seal: %v", err)
+	}
+	ephemeral := bytes.Repeat([]byte{0x05}, 32)
+	wrapKey, err := nip44.ConversationKey(ephemeral, receiver)
+	if err != nil {
+		t.Fatalf("ConversationKey() unexpected error: %v", err)
+	}
+	wrapContent, err := nip44.Encrypt(string(sealJSON), wrapKey)
+	if err != nil {
+		t.Fatalf("Encrypt() unexpected error: %v", err)
+	}
+	wrap := nostr.Event{
+		PubKey:    mustPublicKey(t, ephemeral),
+		CreatedAt: now.Unix(),
+		Kind:      KindGiftWrap,
+		Tags:      [][]string{{"p", receiver}},
+		Content:   wrapContent,
+	}
+
+	_, err = unwrap(wrap, receiverPriv)
+	if err == nil || !strings.Contains(err.Error(), "does not match seal signer") {
+		t.Fatalf("unwrap() error = %v, want author mismatch", err)
+	}
+}
+
+func mustPublicKey(t *testing.T, priv []byte) string {
+	t.Helper()
+
+	pub, err := nostr.PublicKey(priv)
+	if err != nil {
+		t.Fatalf("PublicKey() unexpected error: %v", err)
+	}
+	return pub
+}
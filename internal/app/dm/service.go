@@ -0,0 +1,415 @@
+package dm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"noscli/internal/nip04"
+	"noscli/internal/nostr"
+)
+
+// SendRequest represents a direct message to send.
+type SendRequest struct {
+	Relay     string
+	Recipient string
+	// RelayHints are extra relays where the recipient can be found, e.g. from NIP-05.
+	RelayHints []string
+	Content    string
+	// Legacy sends a NIP-04 kind 4 message instead of a NIP-17 gift wrap,
+	// for contacts whose clients cannot read NIP-17 yet.
+	Legacy bool
+}
+
+// ListRequest selects stored direct messages to show.
+type ListRequest struct {
+	Relay string
+	// With limits the output to the conversation with this hex pubkey when set.
+	With  string
+	Limit int
+}
+
+// WatchRequest represents a live direct message subscription.
+type WatchRequest struct {
+	Relay string
+}
+
+// Message is a decrypted direct message.
+type Message struct {
+	ID        string
+	From      string
+	To        string
+	Content   string
+	CreatedAt time.Time
+	Relay     string
+	// Legacy marks messages received as NIP-04 kind 4 events.
+	Legacy bool
+}
+
+// Client exposes the subset of nostr client functionality needed by the dm service.
+type Client interface {
+	Publish(ctx context.Context, relay string, evt nostr.Event) error
+	Fetch(ctx context.Context, relay string, filter nostr.Filter) ([]nostr.Event, error)
+	FetchLatest(ctx context.Context, relays []string, filter nostr.Filter) ([]nostr.Event, error)
+	Stream(ctx context.Context, relay string, filter nostr.Filter) (<-chan nostr.Event, <-chan error)
+}
+
+// Service sends and reads NIP-17 private direct messages.
+type Service struct {
+	client Client
+	logger *slog.Logger
+}
+
+// NewService creates a Service that relies on the given nostr client.
+func NewService(client Client, logger *slog.Logger) *Service {
+	return &Service{client: client, logger: logger}
+}
+
+// Send gift-wraps the message for the recipient and for ourselves, publishing
+// the recipient copy to their NIP-17 relay list when one is found.
+func (s *Service) Send(ctx context.Context, req SendRequest, w io.Writer) error {
+	if strings.TrimSpace(req.Relay) == "" {
+		return errors.New("relay is required")
+	}
+	if req.Recipient == "" {
+		return errors.New("recipient is required")
+	}
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		return errors.New("content is empty")
+	}
+
+	keys, err := nostr.LoadKeysFromEnv()
+	if err != nil {
+		return err
+	}
+
+	if req.Legacy {
+		return s.sendLegacy(ctx, req, content, keys, w)
+	}
+
+	now := time.Now()
+	r, err := newRumor(keys.Public, req.Recipient, content, now)
+	if err != nil {
+		return err
+	}
+
+	toRecipient, err := giftWrap(r, keys.Private, req.Recipient, now)
+	if err != nil {
+		return err
+	}
+	toSelf, err := giftWrap(r, keys.Private, keys.Public, now)
+	if err != nil {
+		return err
+	}
+
+	relays := s.dmRelays(ctx, req.Relay, req.RelayHints, req.Recipient)
+	published := 0
+	for _, relay := range relays {
+		if err := s.client.Publish(ctx, relay, toRecipient); err != nil {
+			s.logger.Warn("publish gift wrap failed", "relay", relay, "error", err)
+			continue
+		}
+		published++
+	}
+	if published == 0 {
+		return fmt.Errorf("failed to deliver message to %d relay(s)", len(relays))
+	}
+
+	if err := s.client.Publish(ctx, req.Relay, toSelf); err != nil {
+		s.logger.Warn("publish self copy failed", "relay", req.Relay, "error", err)
+	}
+
+	_, err = fmt.Fprintf(w, "sent: id:%s to:%s relays:%s\n", shortID(r.ID), truncateHex(req.Recipient), strings.Join(relays, ","))
+	return err
+}
+
+// sendLegacy publishes a NIP-04 kind 4 message to the request relay.
+func (s *Service) sendLegacy(ctx context.Context, req SendRequest, content string, keys nostr.Keys, w io.Writer) error {
+	s.logger.Warn("sending legacy NIP-04 message; metadata is visible to relays", "recipient", req.Recipient)
+
+	secret, err := nip04.SharedSecret(keys.Private, req.Recipient)
+	if err != nil {
+		return err
+	}
+	encrypted, err := nip04.Encrypt(content, secret)
+	if err != nil {
+		return fmt.Errorf("encrypt message: %w", err)
+	}
+
+	evt := nostr.Event{
+		PubKey:    keys.Public,
+		CreatedAt: time.Now().Unix(),
+		Kind:      nip04.KindEncryptedDirectMessage,
+		Tags:      [][]string{{"p", req.Recipient}},
+		Content:   encrypted,
+	}
+	if err := nostr.SignEvent(&evt, keys.Private); err != nil {
+		return fmt.Errorf("sign event: %w", err)
+	}
+	if err := s.client.Publish(ctx, req.Relay, evt); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "sent (legacy NIP-04): id:%s to:%s relays:%s\n", shortID(evt.ID), truncateHex(req.Recipient), req.Relay)
+	return err
+}
+
+// List fetches gift wraps and legacy NIP-04 messages addressed to us (plus
+// the NIP-04 messages we sent) and prints the decrypted messages oldest first.
+func (s *Service) List(ctx context.Context, req ListRequest, w io.Writer) error {
+	if strings.TrimSpace(req.Relay) == "" {
+		return errors.New("relay is required")
+	}
+
+	keys, err := nostr.LoadKeysFromEnv()
+	if err != nil {
+		return err
+	}
+
+	received := nostr.Filter{
+		Kinds: []int{KindGiftWrap, nip04.KindEncryptedDirectMessage},
+		Tags:  map[string][]string{"p": {keys.Public}},
+		Limit: req.Limit,
+	}
+	events, err := s.client.Fetch(ctx, req.Relay, received)
+	if err != nil {
+		return err
+	}
+
+	// NIP-17 では自分宛ての控えが届くが、NIP-04 の送信分は自分が author のイベントとして取得する
+	sentLegacy := nostr.Filter{
+		Authors: []string{keys.Public},
+		Kinds:   []int{nip04.KindEncryptedDirectMessage},
+		Limit:   req.Limit,
+	}
+	sent, err := s.client.Fetch(ctx, req.Relay, sentLegacy)
+	if err != nil {
+		s.logger.Warn("fetch sent legacy messages failed", "relay", req.Relay, "error", err)
+	}
+	events = append(events, sent...)
+
+	seen := make(map[string]struct{})
+	var messages []Message
+	for _, evt := range events {
+		msg, ok := s.open(evt, keys)
+		if !ok {
+			continue
+		}
+		if _, dup := seen[msg.ID]; dup {
+			continue
+		}
+		seen[msg.ID] = struct{}{}
+		if req.With != "" && msg.From != req.With && msg.To != req.With {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+
+	for _, msg := range messages {
+		if err := renderMessage(w, msg, keys.Public); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Watch streams gift wraps addressed to us and prints messages sent after the call started.
+func (s *Service) Watch(ctx context.Context, req WatchRequest, w io.Writer) error {
+	if strings.TrimSpace(req.Relay) == "" {
+		return errors.New("relay is required")
+	}
+
+	keys, err := nostr.LoadKeysFromEnv()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	// gift wrap の created_at は過去にずらされているため、その幅だけ遡って購読する
+	since := start.Add(-timestampJitter)
+	filter := nostr.Filter{
+		Kinds: []int{KindGiftWrap, nip04.KindEncryptedDirectMessage},
+		Tags:  map[string][]string{"p": {keys.Public}},
+		Since: &since,
+	}
+
+	events, errs := s.client.Stream(ctx, req.Relay, filter)
+	seen := make(map[string]struct{})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case evt, ok := <-events:
+			if !ok {
+				events = nil
+				if errs == nil {
+					return nil
+				}
+				continue
+			}
+			msg, ok := s.open(evt, keys)
+			if !ok || msg.CreatedAt.Before(start.Truncate(time.Second)) {
+				continue
+			}
+			if _, dup := seen[msg.ID]; dup {
+				continue
+			}
+			seen[msg.ID] = struct{}{}
+			if err := renderMessage(w, msg, keys.Public); err != nil {
+				return err
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				if events == nil {
+					return nil
+				}
+				continue
+			}
+			if err == nil || errors.Is(err, context.Canceled) {
+				continue
+			}
+			s.logger.Warn("dm stream error", "error", err)
+		}
+	}
+}
+
+// open decrypts a gift wrap or legacy NIP-04 event into a Message.
+func (s *Service) open(evt nostr.Event, keys nostr.Keys) (Message, bool) {
+	if evt.Kind == nip04.KindEncryptedDirectMessage {
+		return s.openLegacy(evt, keys)
+	}
+
+	wrap := evt
+	inner, err := unwrap(wrap, keys.Private)
+	if err != nil {
+		s.logger.Debug("ignore undecryptable gift wrap", "relay", wrap.Relay, "id", wrap.ID, "error", err)
+		return Message{}, false
+	}
+	if inner.Kind != KindChatMessage {
+		return Message{}, false
+	}
+	to, _ := inner.TagValue("p")
+	return Message{
+		ID:        inner.ID,
+		From:      inner.PubKey,
+		To:        to,
+		Content:   inner.Content,
+		CreatedAt: inner.CreatedAtTime(),
+		Relay:     inner.Relay,
+	}, true
+}
+
+// openLegacy decrypts a NIP-04 kind 4 event sent to or by us.
+func (s *Service) openLegacy(evt nostr.Event, keys nostr.Keys) (Message, bool) {
+	to, _ := evt.TagValue("p")
+	peer := evt.PubKey
+	if evt.PubKey == keys.Public {
+		peer = to
+	}
+
+	secret, err := nip04.SharedSecret(keys.Private, peer)
+	if err != nil {
+		s.logger.Debug("ignore legacy message with invalid peer", "relay", evt.Relay, "id", evt.ID, "error", err)
+		return Message{}, false
+	}
+	content, err := nip04.Decrypt(evt.Content, secret)
+	if err != nil {
+		s.logger.Debug("ignore undecryptable legacy message", "relay", evt.Relay, "id", evt.ID, "error", err)
+		return Message{}, false
+	}
+
+	return Message{
+		ID:        evt.ID,
+		From:      evt.PubKey,
+		To:        to,
+		Content:   content,
+		CreatedAt: evt.CreatedAtTime(),
+		Relay:     evt.Relay,
+		Legacy:    true,
+	}, true
+}
+
+// dmRelays returns the recipient's kind 10050 relays, looked up on fallback
+// and the relay hints. Without a relay list the message goes to fallback and
+// the hints.
+func (s *Service) dmRelays(ctx context.Context, fallback string, hints []string, recipient string) []string {
+	candidates := []string{fallback}
+	for _, hint := range hints {
+		if hint = strings.TrimSpace(hint); hint != "" && !slices.Contains(candidates, hint) {
+			candidates = append(candidates, hint)
+		}
+	}
+
+	filter := nostr.Filter{
+		Authors: []string{recipient},
+		Kinds:   []int{KindDMRelayList},
+		Limit:   1,
+	}
+
+	// kind 10050 は置き換え可能イベントなので、FetchLatest が最新版だけを返す
+	events, err := s.client.FetchLatest(ctx, candidates, filter)
+	if err != nil {
+		s.logger.Debug("fetch dm relay list failed", "relays", candidates, "error", err)
+		return candidates
+	}
+	if len(events) == 0 {
+		return candidates
+	}
+	latest := events[0]
+
+	var relays []string
+	for _, tag := range latest.Tags {
+		if len(tag) >= 2 && tag[0] == "relay" && strings.TrimSpace(tag[1]) != "" {
+			relays = append(relays, tag[1])
+		}
+	}
+	if len(relays) == 0 {
+		return candidates
+	}
+	return relays
+}
+
+func renderMessage(w io.Writer, msg Message, self string) error {
+	ts := msg.CreatedAt.Local().Format("2006-01-02 15:04:05")
+	content := strings.ReplaceAll(strings.TrimSpace(msg.Content), "\n", " ")
+	label := ""
+	if msg.Legacy {
+		label = " [legacy NIP-04]"
+	}
+	_, err := fmt.Fprintf(w, "[%s]%s %s -> %s: %s (id:%s)\n", ts, label, displayKey(msg.From, self), displayKey(msg.To, self), content, shortID(msg.ID))
+	return err
+}
+
+func displayKey(pub, self string) string {
+	if pub == self {
+		return "me"
+	}
+	return truncateHex(pub)
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+func truncateHex(in string) string {
+	if len(in) <= 12 {
+		return in
+	}
+	return fmt.Sprintf("%s...%s", in[:6], in[len(in)-4:])
+}
@@ -0,0 +1,195 @@
+package dm
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+type mockClient struct {
+	published map[string][]nostr.Event
+	fetch     func(relay string, filter nostr.Filter) []nostr.Event
+}
+
+func (m *mockClient) Publish(_ context.Context, relay string, evt nostr.Event) error {
+	if m.published == nil {
+		m.published = make(map[string][]nostr.Event)
+	}
+	m.published[relay] = append(m.published[relay], evt)
+	return nil
+}
+
+func (m *mockClient) Fetch(_ context.Context, relay string, filter nostr.Filter) ([]nostr.Event, error) {
+	if m.fetch == nil {
+		return nil, nil
+	}
+	return m.fetch(relay, filter), nil
+}
+
+// FetchLatest mirrors the client's contract of returning only the newest
+// replaceable event, which is what the relay list lookup relies on.
+func (m *mockClient) FetchLatest(ctx context.Context, relays []string, filter nostr.Filter) ([]nostr.Event, error) {
+	var merged []nostr.Event
+	for _, relay := range relays {
+		events, _ := m.Fetch(ctx, relay, filter)
+		merged = append(merged, events...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].CreatedAt > merged[j].CreatedAt })
+	if len(merged) > 0 && merged[0].IsReplaceable() {
+		merged = merged[:1]
+	}
+	return merged, nil
+}
+
+func (m *mockClient) Stream(context.Context, string, nostr.Filter) (<-chan nostr.Event, <-chan error) {
+	events := make(chan nostr.Event)
+	errs := make(chan error)
+	close(events)
+	close(errs)
+	return events, errs
+}
+
+func TestServiceSendAndList(t *testing.T) {
+	senderPriv := bytes.Repeat([]byte{0x01}, 32)
+	receiverPriv := bytes.Repeat([]byte{0x02}, 32)
+	receiver := mustPublicKey(t, receiverPriv)
+
+	nsec, err := nostr.EncodeNsec(senderPriv)
+	if err != nil {
+		t.Fatalf("EncodeNsec: %v", err)
+	}
+	t.Setenv("NOSTR_NSEC", nsec)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := &mockClient{
+		fetch: func(relay string, filter nostr.Filter) []nostr.Event {
+			if len(filter.Kinds) == 1 && filter.Kinds[0] == KindDMRelayList {
+				return []nostr.Event{{
+					Kind:      KindDMRelayList,
+					CreatedAt: 1,
+					Tags:      [][]string{{"relay", "wss://inbox.example.com"}},
+				}}
+			}
+			return nil
+		},
+	}
+	svc := NewService(client, logger)
+
+	req := SendRequest{Relay: "wss://relay.example.com", Recipient: receiver, Content: "hello"}
+	if err := svc.Send(ctx, req, io.Discard); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+
+	inbox := client.published["wss://inbox.example.com"]
+	if len(inbox) != 1 {
+		t.Fatalf("inbox publications = %d, want 1", len(inbox))
+	}
+	own := client.published["wss://relay.example.com"]
+	if len(own) != 1 {
+		t.Fatalf("self copy publications = %d, want 1", len(own))
+	}
+
+	// The sender reads their own copy back through List.
+	client.fetch = func(string, nostr.Filter) []nostr.Event {
+		return append(own, inbox...)
+	}
+	var buf bytes.Buffer
+	if err := svc.List(ctx, ListRequest{Relay: "wss://relay.example.com"}, &buf); err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	out := buf.String()
+	if strings.Count(out, "\n") != 1 {
+		t.Fatalf("List() output should contain exactly one message, got %q", out)
+	}
+	if !strings.Contains(out, "me -> ") || !strings.Contains(out, ": hello") {
+		t.Fatalf("List() output = %q", out)
+	}
+}
+
+func TestServiceLegacyNIP04(t *testing.T) {
+	senderPriv := bytes.Repeat([]byte{0x01}, 32)
+	receiverPriv := bytes.Repeat([]byte{0x02}, 32)
+	receiver := mustPublicKey(t, receiverPriv)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	senderNsec, err := nostr.EncodeNsec(senderPriv)
+	if err != nil {
+		t.Fatalf("EncodeNsec: %v", err)
+	}
+	t.Setenv("NOSTR_NSEC", senderNsec)
+
+	client := &mockClient{}
+	svc := NewService(client, logger)
+	req := SendRequest{Relay: "wss://relay.example.com", Recipient: receiver, Content: "old school", Legacy: true}
+	if err := svc.Send(ctx, req, io.Discard); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+
+	published := client.published["wss://relay.example.com"]
+	if len(published) != 1 || published[0].Kind != 4 {
+		t.Fatalf("expected one kind 4 event, got %#v", published)
+	}
+	if strings.Contains(published[0].Content, "old school") {
+		t.Fatalf("content must be encrypted: %q", published[0].Content)
+	}
+
+	// The recipient reads it back as a labelled legacy message.
+	receiverNsec, err := nostr.EncodeNsec(receiverPriv)
+	if err != nil {
+		t.Fatalf("EncodeNsec: %v", err)
+	}
+	t.Setenv("NOSTR_NSEC", receiverNsec)
+
+	client.fetch = func(_ string, filter nostr.Filter) []nostr.Event {
+		if len(filter.Tags["p"]) > 0 {
+			return published
+		}
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := svc.List(ctx, ListRequest{Relay: "wss://relay.example.com"}, &buf); err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "[legacy NIP-04]") || !strings.Contains(out, "-> me: old school") {
+		t.Fatalf("List() output = %q", out)
+	}
+}
+
+func TestDMRelaysUsesNewestListAcrossHints(t *testing.T) {
+	client := &mockClient{
+		fetch: func(relay string, filter nostr.Filter) []nostr.Event {
+			switch relay {
+			case "wss://relay.example.com":
+				return []nostr.Event{{Kind: KindDMRelayList, CreatedAt: 1, Tags: [][]string{{"relay", "wss://stale.example.com"}}}}
+			case "wss://hint.example.com":
+				return []nostr.Event{{Kind: KindDMRelayList, CreatedAt: 2, Tags: [][]string{{"relay", "wss://fresh.example.com"}}}}
+			}
+			return nil
+		},
+	}
+	svc := NewService(client, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	got := svc.dmRelays(context.Background(), "wss://relay.example.com", []string{"wss://hint.example.com"}, "recipient")
+	if len(got) != 1 || got[0] != "wss://fresh.example.com" {
+		t.Fatalf("dmRelays() = %v, want the newest relay list", got)
+	}
+
+	client.fetch = nil
+	got = svc.dmRelays(context.Background(), "wss://relay.example.com", []string{"wss://hint.example.com", "wss://relay.example.com"}, "recipient")
+	if len(got) != 2 || got[0] != "wss://relay.example.com" || got[1] != "wss://hint.example.com" {
+		t.Fatalf("dmRelays() without a list = %v, want fallback and hints", got)
+	}
+}
@@ -0,0 +1,117 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+// Request represents an arbitrary event to sign and publish.
+type Request struct {
+	Relays []string
+	// Event is either an unsigned template, which is completed and signed with
+	// the local key, or an already signed event, which is verified as-is.
+	Event nostr.Event
+	// DryRun prints the signed event instead of publishing it.
+	DryRun bool
+}
+
+// Client exposes the subset of nostr client functionality needed by the event service.
+type Client interface {
+	PublishMany(ctx context.Context, relays []string, evt nostr.Event) ([]nostr.PublishResult, error)
+}
+
+// Service signs and publishes events of any kind.
+type Service struct {
+	client Client
+	logger *slog.Logger
+}
+
+// NewService creates a Service that relies on the given nostr client.
+func NewService(client Client, logger *slog.Logger) *Service {
+	return &Service{client: client, logger: logger}
+}
+
+// Run prepares req.Event and publishes it to every relay, writing one result
+// line per relay to w. With DryRun the signed event JSON is written instead.
+func (s *Service) Run(ctx context.Context, req Request, w io.Writer) error {
+	if !req.DryRun && len(req.Relays) == 0 {
+		return errors.New("relay is required")
+	}
+
+	evt, err := prepare(req.Event, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if req.DryRun {
+		b, err := json.Marshal(evt)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	}
+
+	results, err := s.client.PublishMany(ctx, req.Relays, evt)
+	for _, res := range results {
+		status := "ok"
+		if res.Err != nil {
+			status = "failed: " + res.Err.Error()
+		}
+		if _, werr := fmt.Fprintf(w, "published: id:%s kind:%d relay:%s %s\n", shortID(evt.ID), evt.Kind, res.Relay, status); werr != nil {
+			return werr
+		}
+	}
+	return err
+}
+
+// prepare verifies a signed event, or fills in and signs an unsigned one.
+func prepare(evt nostr.Event, now time.Time) (nostr.Event, error) {
+	if evt.Sig != "" {
+		if err := evt.Verify(); err != nil {
+			return nostr.Event{}, fmt.Errorf("invalid signed event: %w", err)
+		}
+		return evt, nil
+	}
+
+	if evt.Kind < 0 || evt.Kind > 65535 {
+		return nostr.Event{}, fmt.Errorf("invalid kind: %d", evt.Kind)
+	}
+
+	keys, err := nostr.LoadKeysFromEnv()
+	if err != nil {
+		return nostr.Event{}, err
+	}
+	if evt.PubKey != "" && evt.PubKey != keys.Public {
+		return nostr.Event{}, fmt.Errorf("event pubkey %s does not match the signing key", evt.PubKey)
+	}
+
+	evt.PubKey = keys.Public
+	if evt.CreatedAt == 0 {
+		evt.CreatedAt = now.Unix()
+	}
+	if evt.Tags == nil {
+		// NIP-01 はタグを配列として直列化するため null を避ける
+		evt.Tags = [][]string{}
+	}
+	// 署名し直すため、テンプレート側の ID は無視する
+	evt.ID = ""
+	if err := nostr.SignEvent(&evt, keys.Private); err != nil {
+		return nostr.Event{}, fmt.Errorf("sign event: %w", err)
+	}
+	return evt, nil
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,152 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"noscli/internal/nostr"
+)
+
+type recordingClient struct {
+	published []nostr.Event
+	fail      map[string]error
+}
+
+func (c *recordingClient) PublishMany(_ context.Context, relays []string, evt nostr.Event) ([]nostr.PublishResult, error) {
+	c.published = append(c.published, evt)
+	results := make([]nostr.PublishResult, 0, len(relays))
+	failed := 0
+	for _, relay := range relays {
+		err := c.fail[relay]
+		if err != nil {
+			failed++
+		}
+		results = append(results, nostr.PublishResult{Relay: relay, Err: err})
+	}
+	if failed == len(relays) {
+		return results, errors.New("all relays failed")
+	}
+	return results, nil
+}
+
+func setKey(t *testing.T, seed byte) string {
+	t.Helper()
+	priv := bytes.Repeat([]byte{seed}, 32)
+	nsec, err := nostr.EncodeNsec(priv)
+	if err != nil {
+		t.Fatalf("EncodeNsec: %v", err)
+	}
+	t.Setenv("NOSTR_NSEC", nsec)
+	pub, err := nostr.PublicKey(priv)
+	if err != nil {
+		t.Fatalf("PublicKey: %v", err)
+	}
+	return pub
+}
+
+func TestServiceRunSignsTemplate(t *testing.T) {
+	pub := setKey(t, 0x01)
+	client := &recordingClient{fail: map[string]error{"wss://down.example": errors.New("dial failed")}}
+	svc := NewService(client, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	req := Request{
+		Relays: []string{"wss://up.example", "wss://down.example"},
+		Event:  nostr.Event{Kind: 30023, Tags: [][]string{{"d", "slug"}}, Content: "# title"},
+	}
+	var buf bytes.Buffer
+	if err := svc.Run(context.Background(), req, &buf); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	if len(client.published) != 1 {
+		t.Fatalf("expected one publish, got %d", len(client.published))
+	}
+	evt := client.published[0]
+	if evt.PubKey != pub || evt.Kind != 30023 || evt.CreatedAt == 0 {
+		t.Fatalf("unexpected event: %+v", evt)
+	}
+	if err := evt.Verify(); err != nil {
+		t.Fatalf("published event does not verify: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "relay:wss://up.example ok") || !strings.Contains(out, "relay:wss://down.example failed: dial failed") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
+
+func TestServiceRunDryRun(t *testing.T) {
+	setKey(t, 0x02)
+	client := &recordingClient{}
+	svc := NewService(client, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var buf bytes.Buffer
+	if err := svc.Run(context.Background(), Request{Event: nostr.Event{Kind: 1, Content: "hi"}, DryRun: true}, &buf); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if len(client.published) != 0 {
+		t.Fatalf("dry run must not publish")
+	}
+
+	var evt nostr.Event
+	if err := json.Unmarshal(buf.Bytes(), &evt); err != nil {
+		t.Fatalf("dry run output is not an event: %v", err)
+	}
+	if err := evt.Verify(); err != nil {
+		t.Fatalf("dry run event does not verify: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"tags":[]`) {
+		t.Fatalf("tags must serialize as an empty array: %s", buf.String())
+	}
+}
+
+func TestServiceRunSignedEvent(t *testing.T) {
+	setKey(t, 0x03)
+	otherPriv := bytes.Repeat([]byte{0x04}, 32)
+	otherPub, err := nostr.PublicKey(otherPriv)
+	if err != nil {
+		t.Fatalf("PublicKey: %v", err)
+	}
+	signed := nostr.Event{PubKey: otherPub, CreatedAt: 1700000000, Kind: 1, Tags: [][]string{}, Content: "signed elsewhere"}
+	if err := nostr.SignEvent(&signed, otherPriv); err != nil {
+		t.Fatalf("SignEvent: %v", err)
+	}
+
+	tampered := signed
+	tampered.Content = "changed"
+
+	tests := []struct {
+		name    string
+		evt     nostr.Event
+		wantErr bool
+	}{
+		{name: "valid signature is published unchanged", evt: signed},
+		{name: "tampered event is rejected", evt: tampered, wantErr: true},
+		{name: "foreign pubkey without signature is rejected", evt: nostr.Event{PubKey: signed.PubKey, Kind: 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &recordingClient{}
+			svc := NewService(client, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			err := svc.Run(context.Background(), Request{Relays: []string{"wss://relay.example"}, Event: tt.evt}, io.Discard)
+			if tt.wantErr {
+				if err == nil || len(client.published) != 0 {
+					t.Fatalf("Run() expected error without publishing, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+			if len(client.published) != 1 || client.published[0].ID != signed.ID || client.published[0].Sig != signed.Sig {
+				t.Fatalf("signed event was modified: %+v", client.published)
+			}
+		})
+	}
+}
@@ -0,0 +1,381 @@
+// Package mirror copies an author's history from one relay to another.
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+// ErrRejected is returned by Run when the destination rejected at least one event.
+var ErrRejected = errors.New("destination rejected some events")
+
+const (
+	defaultPageSize    = 500
+	defaultConcurrency = 4
+)
+
+// Request describes what to copy and where.
+type Request struct {
+	From string
+	To   string
+	// Filter selects the events to copy, typically by author and kinds. Its
+	// Until, if set, is where paging starts; Limit is ignored.
+	Filter nostr.Filter
+	// PageSize is the limit of each REQ; zero uses defaultPageSize.
+	PageSize int
+	// Concurrency bounds the publishes in flight; zero uses defaultConcurrency.
+	Concurrency int
+}
+
+// Client exposes the subset of nostr client functionality needed by the mirror service.
+type Client interface {
+	Fetch(ctx context.Context, relay string, filter nostr.Filter) ([]nostr.Event, error)
+	PublishAck(ctx context.Context, relay string, evt nostr.Event) (string, error)
+	Reconcile(ctx context.Context, relay string, filter nostr.Filter, local []nostr.Event) (nostr.Diff, error)
+	FetchIDs(ctx context.Context, relay string, ids []string) ([]nostr.Event, error)
+	// RelayStats reports, in Invalid, the events the client dropped because
+	// their ID or signature did not verify.
+	RelayStats(relay string) (nostr.RelayStats, bool)
+}
+
+// Store is the local event cache used to reconcile both relays with NIP-77.
+type Store interface {
+	Query(filter nostr.Filter) ([]nostr.Event, error)
+	Save(evt nostr.Event) error
+}
+
+// Checkpoint stores the until cursor of an interrupted copy.
+type Checkpoint interface {
+	Load() (time.Time, error)
+	Save(time.Time) error
+	Clear() error
+}
+
+// Result counts the outcome of a copy.
+type Result struct {
+	Copied    int
+	Duplicate int
+	Rejected  int
+	Invalid   int
+}
+
+// Service copies events between relays.
+type Service struct {
+	client     Client
+	store      Store
+	checkpoint Checkpoint
+	logger     *slog.Logger
+}
+
+// NewService creates a Service. store may be nil to always page with REQ;
+// checkpoint may be nil to always start from the newest event.
+func NewService(client Client, store Store, checkpoint Checkpoint, logger *slog.Logger) *Service {
+	return &Service{client: client, store: store, checkpoint: checkpoint, logger: logger}
+}
+
+// Run copies every verified event matching req.Filter from req.From to req.To.
+// When both relays support NIP-77, only the events missing locally are
+// fetched and only those missing on req.To are published; otherwise Run
+// pages backwards through req.From with until cursors. Rejected and invalid
+// events are written to w, followed by a summary.
+func (s *Service) Run(ctx context.Context, req Request, w io.Writer) error {
+	if req.From == "" || req.To == "" {
+		return errors.New("source and destination relays are required")
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = defaultPageSize
+	}
+
+	// 署名が不正なイベントはクライアントが受信時に捨てるため、その件数は統計の差分から数える
+	dropped := s.dropped(req.From)
+
+	var res Result
+	reconciled, err := s.copyReconciled(ctx, req, &res, w)
+	if err != nil {
+		return err
+	}
+	if !reconciled {
+		if err := s.copyPaged(ctx, req, &res, w); err != nil {
+			return err
+		}
+	}
+
+	if n := s.dropped(req.From) - dropped; n > 0 {
+		res.Invalid += int(n)
+		fmt.Fprintf(w, "invalid: %d events from %s failed signature verification\n", n, req.From)
+	}
+
+	if s.checkpoint != nil {
+		if err := s.checkpoint.Clear(); err != nil {
+			return fmt.Errorf("clear checkpoint: %w", err)
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "copied: %d duplicate: %d rejected: %d invalid: %d\n", res.Copied, res.Duplicate, res.Rejected, res.Invalid); err != nil {
+		return err
+	}
+	if res.Rejected > 0 {
+		return ErrRejected
+	}
+	return nil
+}
+
+// copyReconciled reconciles the local store with req.From, fetches what it
+// lacks, then reconciles the result with req.To and publishes only what the
+// destination lacks. It reports false, having published nothing, when there
+// is no store or either relay does not support NIP-77.
+func (s *Service) copyReconciled(ctx context.Context, req Request, res *Result, w io.Writer) (bool, error) {
+	if s.store == nil {
+		return false, nil
+	}
+	filter := req.Filter
+	filter.Limit = 0
+	local, err := s.store.Query(filter)
+	if err != nil {
+		return false, fmt.Errorf("query local store: %w", err)
+	}
+
+	fromDiff, err := s.client.Reconcile(ctx, req.From, filter, local)
+	if errors.Is(err, nostr.ErrNegentropyUnavailable) {
+		s.logger.Info("falling back to REQ paging", "relay", req.From, "error", err)
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reconcile with %s: %w", req.From, err)
+	}
+
+	// source は送信元が持つイベント: ローカルのうち送信元にも残っているものと取得した不足分
+	gone := idSet(fromDiff.Have)
+	var source []nostr.Event
+	for _, evt := range local {
+		if _, ok := gone[evt.ID]; !ok {
+			source = append(source, evt)
+		}
+	}
+	fetched, err := s.client.FetchIDs(ctx, req.From, fromDiff.Need)
+	if err != nil {
+		return false, fmt.Errorf("fetch from %s: %w", req.From, err)
+	}
+	for _, evt := range fetched {
+		if err := verify(filter, evt); err != nil {
+			res.Invalid++
+			fmt.Fprintf(w, "invalid %s: %v\n", evt.ID, err)
+			continue
+		}
+		if err := s.store.Save(evt); err != nil {
+			s.logger.Warn("cache event failed", "id", evt.ID, "error", err)
+		}
+		source = append(source, evt)
+	}
+
+	toDiff, err := s.client.Reconcile(ctx, req.To, filter, source)
+	if errors.Is(err, nostr.ErrNegentropyUnavailable) {
+		// 全件をまとめて送ると中断時に最初からやり直しになるため、チェックポイントのある REQ のページングに任せる
+		s.logger.Info("falling back to REQ paging", "relay", req.To, "error", err)
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reconcile with %s: %w", req.To, err)
+	}
+
+	missing := idSet(toDiff.Have)
+	var pending []nostr.Event
+	for _, evt := range source {
+		if _, ok := missing[evt.ID]; ok {
+			pending = append(pending, evt)
+		}
+	}
+	res.Duplicate += len(source) - len(pending)
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].CreatedAt > pending[j].CreatedAt })
+	s.logger.Info("reconciled", "source", len(source), "fetched", len(fetched), "publish", len(pending))
+
+	for start := 0; start < len(pending); start += req.PageSize {
+		if err := s.copyPage(ctx, req, pending[start:min(start+req.PageSize, len(pending))], res, w); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// dropped returns how many events from relay the client has discarded so far.
+func (s *Service) dropped(relay string) int64 {
+	stats, _ := s.client.RelayStats(relay)
+	return stats.Invalid
+}
+
+func idSet(ids []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+// copyPaged pages backwards through req.From with until cursors and
+// republishes every verified event to req.To. After each page the cursor is
+// saved, so an interrupted run resumes where it stopped.
+func (s *Service) copyPaged(ctx context.Context, req Request, res *Result, w io.Writer) error {
+	until := req.Filter.Until
+	if s.checkpoint != nil {
+		saved, err := s.checkpoint.Load()
+		if err != nil {
+			return fmt.Errorf("load checkpoint: %w", err)
+		}
+		if !saved.IsZero() {
+			s.logger.Info("resuming from checkpoint", "until", saved)
+			until = &saved
+		}
+	}
+
+	// seen holds the IDs at the page boundary, which the next page, whose
+	// until is inclusive, returns again.
+	seen := make(map[string]struct{})
+	limit := req.PageSize
+	for {
+		filter := req.Filter
+		filter.Until = until
+		filter.Limit = limit
+
+		events, err := s.client.Fetch(ctx, req.From, filter)
+		if err != nil {
+			return fmt.Errorf("fetch from %s: %w", req.From, err)
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		oldest := events[0].CreatedAt
+		unseen := 0
+		var fresh []nostr.Event
+		for _, evt := range events {
+			oldest = min(oldest, evt.CreatedAt)
+			if _, ok := seen[evt.ID]; ok {
+				continue
+			}
+			unseen++
+			if err := verify(filter, evt); err != nil {
+				res.Invalid++
+				fmt.Fprintf(w, "invalid %s: %v\n", evt.ID, err)
+				continue
+			}
+			fresh = append(fresh, evt)
+		}
+
+		if err := s.copyPage(ctx, req, fresh, res, w); err != nil {
+			return err
+		}
+
+		// 新しいイベントが無いのは、ページ全体が境界の 1 秒に収まっている場合。
+		// 上限を広げて取り直し、リレーがそれ以上返さなければその秒を飛ばす
+		if unseen == 0 && len(events) >= limit {
+			limit *= 2
+			continue
+		}
+		next := oldest
+		if unseen == 0 {
+			next--
+		}
+		if until == nil || next != until.Unix() {
+			limit = req.PageSize
+		}
+		seen = make(map[string]struct{})
+		for _, evt := range events {
+			if evt.CreatedAt == next {
+				seen[evt.ID] = struct{}{}
+			}
+		}
+		if next < 0 {
+			break
+		}
+		t := time.Unix(next, 0).UTC()
+		until = &t
+		if s.checkpoint != nil {
+			if err := s.checkpoint.Save(t); err != nil {
+				return fmt.Errorf("save checkpoint: %w", err)
+			}
+		}
+		s.logger.Info("copied page", "events", len(fresh), "until", t)
+	}
+	return nil
+}
+
+// verify checks the signature and that the source relay honoured the filter.
+func verify(filter nostr.Filter, evt nostr.Event) error {
+	if err := evt.Verify(); err != nil {
+		return err
+	}
+	if !filter.Matches(evt) {
+		return errors.New("does not match the filter")
+	}
+	return nil
+}
+
+// copyPage publishes events to req.To with at most req.Concurrency in flight.
+// Rejections are counted; any other error stops the copy before the page is
+// checkpointed.
+func (s *Service) copyPage(ctx context.Context, req Request, events []nostr.Event, res *Result, w io.Writer) error {
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, concurrency)
+	for _, evt := range events {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			msg, err := s.client.PublishAck(ctx, req.To, evt)
+
+			mu.Lock()
+			defer mu.Unlock()
+			var rejected *nostr.RejectedError
+			switch {
+			case errors.As(err, &rejected):
+				res.Rejected++
+				fmt.Fprintf(w, "rejected %s: %s\n", evt.ID, rejected.Message)
+			case err != nil:
+				if firstErr == nil {
+					firstErr = fmt.Errorf("publish to %s: %w", req.To, err)
+					cancel()
+				}
+			case strings.HasPrefix(msg, "duplicate:"):
+				res.Duplicate++
+			default:
+				res.Copied++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,369 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+var testKey = bytes.Repeat([]byte{0x05}, 32)
+
+func signedNote(t *testing.T, content string, createdAt int64) nostr.Event {
+	t.Helper()
+	pub, err := nostr.PublicKey(testKey)
+	if err != nil {
+		t.Fatalf("PublicKey() error: %v", err)
+	}
+	evt := nostr.Event{PubKey: pub, CreatedAt: createdAt, Kind: nostr.KindTextNote, Tags: [][]string{}, Content: content}
+	if err := nostr.SignEvent(&evt, testKey); err != nil {
+		t.Fatalf("SignEvent() error: %v", err)
+	}
+	return evt
+}
+
+// fakeClient serves source from memory like a relay, newest first up to the
+// limit, and records what is published. Like nostr.Client it drops events
+// that fail verification, counting them in RelayStats. have holds the contents already on
+// the destination; Reconcile is only supported when negentropy is set, and
+// only with the source when plainDest is also set.
+type fakeClient struct {
+	source     []nostr.Event
+	have       map[string]bool
+	reject     map[string]string
+	failOn     string
+	negentropy bool
+	plainDest  bool
+	filters    []nostr.Filter
+	fetchedIDs []string
+	invalid    int64
+
+	mu        sync.Mutex
+	published []string
+}
+
+func (c *fakeClient) Fetch(_ context.Context, _ string, filter nostr.Filter) ([]nostr.Event, error) {
+	c.filters = append(c.filters, filter)
+	var out []nostr.Event
+	for _, evt := range c.source {
+		if filter.Until == nil || evt.CreatedAt <= filter.Until.Unix() {
+			out = append(out, evt)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt > out[j].CreatedAt })
+	if filter.Limit > 0 && len(out) > filter.Limit {
+		out = out[:filter.Limit]
+	}
+	return c.verified(out), nil
+}
+
+func (c *fakeClient) verified(events []nostr.Event) []nostr.Event {
+	var out []nostr.Event
+	for _, evt := range events {
+		if evt.Verify() != nil {
+			c.invalid++
+			continue
+		}
+		out = append(out, evt)
+	}
+	return out
+}
+
+func (c *fakeClient) RelayStats(relay string) (nostr.RelayStats, bool) {
+	if relay != "wss://a" {
+		return nostr.RelayStats{}, false
+	}
+	return nostr.RelayStats{Relay: relay, Invalid: c.invalid}, true
+}
+
+func (c *fakeClient) PublishAck(_ context.Context, relay string, evt nostr.Event) (string, error) {
+	if evt.Content == c.failOn {
+		return "", errors.New("connection reset")
+	}
+	if msg, ok := c.reject[evt.Content]; ok {
+		return "", &nostr.RejectedError{Relay: relay, EventID: evt.ID, Message: msg}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.published = append(c.published, evt.Content)
+	if c.have[evt.Content] {
+		return "duplicate: already have this event", nil
+	}
+	return "", nil
+}
+
+func (c *fakeClient) Reconcile(_ context.Context, relay string, _ nostr.Filter, local []nostr.Event) (nostr.Diff, error) {
+	if !c.negentropy || (c.plainDest && relay == "wss://b") {
+		return nostr.Diff{}, nostr.ErrNegentropyUnavailable
+	}
+	var diff nostr.Diff
+	if relay == "wss://b" {
+		for _, evt := range local {
+			if !c.have[evt.Content] {
+				diff.Have = append(diff.Have, evt.ID)
+			}
+		}
+		return diff, nil
+	}
+	remote := make(map[string]bool)
+	for _, evt := range c.source {
+		remote[evt.ID] = true
+	}
+	for _, evt := range local {
+		if !remote[evt.ID] {
+			diff.Have = append(diff.Have, evt.ID)
+		}
+		delete(remote, evt.ID)
+	}
+	for id := range remote {
+		diff.Need = append(diff.Need, id)
+	}
+	return diff, nil
+}
+
+func (c *fakeClient) FetchIDs(_ context.Context, _ string, ids []string) ([]nostr.Event, error) {
+	c.fetchedIDs = append(c.fetchedIDs, ids...)
+	want := make(map[string]bool)
+	for _, id := range ids {
+		want[id] = true
+	}
+	var out []nostr.Event
+	for _, evt := range c.source {
+		if want[evt.ID] {
+			out = append(out, evt)
+		}
+	}
+	return c.verified(out), nil
+}
+
+type memoryStore struct {
+	events []nostr.Event
+}
+
+func (s *memoryStore) Query(nostr.Filter) ([]nostr.Event, error) { return s.events, nil }
+
+func (s *memoryStore) Save(evt nostr.Event) error {
+	s.events = append(s.events, evt)
+	return nil
+}
+
+type memoryCheckpoint struct {
+	t     time.Time
+	saves []time.Time
+}
+
+func (c *memoryCheckpoint) Load() (time.Time, error) { return c.t, nil }
+
+func (c *memoryCheckpoint) Save(t time.Time) error {
+	c.t = t
+	c.saves = append(c.saves, t)
+	return nil
+}
+
+func (c *memoryCheckpoint) Clear() error {
+	c.t = time.Time{}
+	return nil
+}
+
+func newService(client Client, checkpoint Checkpoint) *Service {
+	return NewService(client, nil, checkpoint, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestServiceRun(t *testing.T) {
+	forged := signedNote(t, "forged", 100)
+	forged.Content = "tampered"
+	client := &fakeClient{
+		source: []nostr.Event{
+			signedNote(t, "a", 110),
+			signedNote(t, "b", 105),
+			signedNote(t, "c", 105),
+			forged,
+			signedNote(t, "d", 90),
+		},
+		have:   map[string]bool{"b": true},
+		reject: map[string]string{"d": "blocked: not on the allowlist"},
+	}
+	checkpoint := &memoryCheckpoint{}
+
+	var buf bytes.Buffer
+	err := newService(client, checkpoint).Run(context.Background(), Request{From: "wss://a", To: "wss://b", PageSize: 2}, &buf)
+	if !errors.Is(err, ErrRejected) {
+		t.Fatalf("Run() error = %v, want ErrRejected", err)
+	}
+
+	sort.Strings(client.published)
+	if got := strings.Join(client.published, ","); got != "a,b,c" {
+		t.Fatalf("published = %s, want a,b,c", got)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		// The client drops the forged event; the service counts it from the relay stats.
+		"invalid: 1 events from wss://a failed signature verification",
+		"rejected ",
+		"blocked: not on the allowlist",
+		"copied: 2 duplicate: 1 rejected: 1 invalid: 1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	// The first page ends at 105; the finished run clears the checkpoint.
+	if len(checkpoint.saves) == 0 || checkpoint.saves[0].Unix() != 105 {
+		t.Fatalf("checkpoint saves = %v", checkpoint.saves)
+	}
+	if !checkpoint.t.IsZero() {
+		t.Fatalf("checkpoint not cleared: %v", checkpoint.t)
+	}
+}
+
+func TestServiceRunResumesFromCheckpoint(t *testing.T) {
+	client := &fakeClient{source: []nostr.Event{
+		signedNote(t, "new", 200),
+		signedNote(t, "boundary", 150),
+		signedNote(t, "old", 100),
+	}}
+	checkpoint := &memoryCheckpoint{t: time.Unix(150, 0)}
+
+	var buf bytes.Buffer
+	if err := newService(client, checkpoint).Run(context.Background(), Request{From: "wss://a", To: "wss://b"}, &buf); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if client.filters[0].Until == nil || client.filters[0].Until.Unix() != 150 {
+		t.Fatalf("first filter until = %v, want 150", client.filters[0].Until)
+	}
+	sort.Strings(client.published)
+	if got := strings.Join(client.published, ","); got != "boundary,old" {
+		t.Fatalf("published = %s, want boundary,old", got)
+	}
+}
+
+func TestServiceRunKeepsCheckpointOnError(t *testing.T) {
+	client := &fakeClient{
+		source: []nostr.Event{
+			signedNote(t, "a", 300),
+			signedNote(t, "b", 200),
+			signedNote(t, "c", 100),
+		},
+		failOn: "b",
+	}
+	checkpoint := &memoryCheckpoint{}
+
+	err := newService(client, checkpoint).Run(context.Background(), Request{From: "wss://a", To: "wss://b", PageSize: 1}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Fatalf("Run() error = %v, want publish failure", err)
+	}
+	// The page holding a was copied; the failed page is not checkpointed.
+	if checkpoint.t.Unix() != 300 {
+		t.Fatalf("checkpoint = %v, want 300", checkpoint.t.Unix())
+	}
+}
+
+func TestServiceRunPagesThroughCrowdedSecond(t *testing.T) {
+	// More events share a second than fit in a page.
+	client := &fakeClient{source: []nostr.Event{
+		signedNote(t, "x", 100),
+		signedNote(t, "y", 100),
+		signedNote(t, "z", 100),
+		signedNote(t, "older", 50),
+	}}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- newService(client, nil).Run(context.Background(), Request{From: "wss://a", To: "wss://b", PageSize: 2}, io.Discard)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Run() did not finish")
+	}
+	sort.Strings(client.published)
+	if got := strings.Join(client.published, ","); got != "older,x,y,z" {
+		t.Fatalf("published = %s, want older,x,y,z", got)
+	}
+}
+
+func TestServiceRunReconciles(t *testing.T) {
+	a, b, c := signedNote(t, "a", 100), signedNote(t, "b", 200), signedNote(t, "c", 300)
+	stale := signedNote(t, "stale", 50)
+	client := &fakeClient{
+		source:     []nostr.Event{a, b, c},
+		have:       map[string]bool{"b": true},
+		negentropy: true,
+	}
+	store := &memoryStore{events: []nostr.Event{a, stale}}
+	checkpoint := &memoryCheckpoint{t: time.Unix(150, 0)}
+
+	var buf bytes.Buffer
+	svc := NewService(client, store, checkpoint, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := svc.Run(context.Background(), Request{From: "wss://a", To: "wss://b"}, &buf); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if len(client.filters) != 0 {
+		t.Fatalf("paged with REQ: %v", client.filters)
+	}
+	if len(client.fetchedIDs) != 2 || len(store.events) != 4 {
+		t.Fatalf("fetched %d events, store holds %d; want 2 and 4", len(client.fetchedIDs), len(store.events))
+	}
+	// stale is cached but gone from the source; b is already on the destination.
+	sort.Strings(client.published)
+	if got := strings.Join(client.published, ","); got != "a,c" {
+		t.Fatalf("published = %s, want a,c", got)
+	}
+	if !strings.Contains(buf.String(), "copied: 2 duplicate: 1 rejected: 0 invalid: 0\n") {
+		t.Fatalf("unexpected summary:\n%s", buf.String())
+	}
+	if !checkpoint.t.IsZero() {
+		t.Fatalf("checkpoint not cleared: %v", checkpoint.t)
+	}
+}
+
+func TestServiceRunFallsBackWithoutNegentropy(t *testing.T) {
+	client := &fakeClient{source: []nostr.Event{signedNote(t, "a", 100)}}
+	store := &memoryStore{}
+
+	svc := NewService(client, store, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := svc.Run(context.Background(), Request{From: "wss://a", To: "wss://b"}, io.Discard); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if len(client.filters) == 0 || strings.Join(client.published, ",") != "a" {
+		t.Fatalf("filters = %v, published = %v; want a REQ copy of a", client.filters, client.published)
+	}
+}
+
+func TestServiceRunPagesWhenDestinationLacksNegentropy(t *testing.T) {
+	a, b, c := signedNote(t, "a", 100), signedNote(t, "b", 200), signedNote(t, "c", 300)
+	client := &fakeClient{source: []nostr.Event{a, b, c}, negentropy: true, plainDest: true}
+	store := &memoryStore{}
+	checkpoint := &memoryCheckpoint{}
+
+	svc := NewService(client, store, checkpoint, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := svc.Run(context.Background(), Request{From: "wss://a", To: "wss://b", PageSize: 2}, io.Discard); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	// The source was reconciled into the store, but publishing is paged with
+	// REQ so that an interrupted run resumes from the checkpoint.
+	if len(store.events) != 3 || len(client.filters) == 0 {
+		t.Fatalf("store holds %d events, %d REQs; want 3 and paging", len(store.events), len(client.filters))
+	}
+	sort.Strings(client.published)
+	if got := strings.Join(client.published, ","); got != "a,b,c" {
+		t.Fatalf("published = %s, want each event once", got)
+	}
+	if len(checkpoint.saves) == 0 || checkpoint.saves[0].Unix() != 200 {
+		t.Fatalf("checkpoint saves = %v", checkpoint.saves)
+	}
+}
@@ -0,0 +1,152 @@
+package notifications
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+// Event kinds that can notify the user.
+const (
+	KindRepost        = 6
+	KindReaction      = 7
+	KindGenericRepost = 16
+	KindZapReceipt    = 9735
+)
+
+// Type classifies a notification.
+type Type string
+
+// Notification types rendered by the service.
+const (
+	TypeReply    Type = "reply"
+	TypeMention  Type = "mention"
+	TypeReaction Type = "reaction"
+	TypeRepost   Type = "repost"
+	TypeZap      Type = "zap"
+)
+
+// Notification is an event that references the user, already classified.
+type Notification struct {
+	Type Type
+	ID   string
+	// From is the pubkey of the user who caused the notification. For zaps
+	// this is the zap request author rather than the LNURL server.
+	From       string
+	Content    string
+	Target     string
+	AmountMsat int64
+	CreatedAt  time.Time
+	Relay      string
+}
+
+// classify converts an event into a notification; ok is false for kinds that
+// are not notifications.
+func classify(evt nostr.Event) (Notification, bool) {
+	n := Notification{
+		ID:        evt.ID,
+		From:      evt.PubKey,
+		Content:   evt.Content,
+		Target:    lastTagValue(evt, "e"),
+		CreatedAt: evt.CreatedAtTime(),
+		Relay:     evt.Relay,
+	}
+
+	switch evt.Kind {
+	case nostr.KindTextNote:
+		if n.Target != "" {
+			n.Type = TypeReply
+		} else {
+			n.Type = TypeMention
+		}
+	case KindReaction:
+		n.Type = TypeReaction
+	case KindRepost, KindGenericRepost:
+		n.Type = TypeRepost
+		n.Content = ""
+	case KindZapReceipt:
+		n.Type = TypeZap
+		n.Content = ""
+		if desc, ok := evt.TagValue("description"); ok {
+			var request nostr.Event
+			if err := json.Unmarshal([]byte(desc), &request); err == nil {
+				n.From = request.PubKey
+				n.Content = request.Content
+				if amount, ok := request.TagValue("amount"); ok {
+					n.AmountMsat, _ = strconv.ParseInt(amount, 10, 64)
+				}
+			}
+		}
+		if n.AmountMsat == 0 {
+			if invoice, ok := evt.TagValue("bolt11"); ok {
+				n.AmountMsat = bolt11AmountMsat(invoice)
+			}
+		}
+	default:
+		return Notification{}, false
+	}
+
+	return n, true
+}
+
+// lastTagValue returns the value of the last tag named name. NIP-10 puts the
+// direct parent last when markers are not used.
+func lastTagValue(evt nostr.Event, name string) string {
+	value := ""
+	for _, tag := range evt.Tags {
+		if len(tag) >= 2 && tag[0] == name {
+			value = tag[1]
+			if len(tag) >= 4 && tag[3] == "reply" {
+				return value
+			}
+		}
+	}
+	return value
+}
+
+// bolt11AmountMsat extracts the amount encoded in the human readable part of
+// a BOLT-11 invoice (e.g. "lnbc210n1..." is 21 sats). It returns 0 when the
+// invoice has no amount or cannot be parsed.
+func bolt11AmountMsat(invoice string) int64 {
+	invoice = strings.ToLower(invoice)
+	sep := strings.LastIndexByte(invoice, '1')
+	if sep < 0 || !strings.HasPrefix(invoice, "ln") {
+		return 0
+	}
+	hrp := invoice[2:sep]
+
+	// skip the currency prefix (bc, tb, bcrt, ...)
+	start := strings.IndexAny(hrp, "0123456789")
+	if start < 0 {
+		return 0
+	}
+	amount := hrp[start:]
+	multiplier := byte(0)
+	if last := amount[len(amount)-1]; last < '0' || last > '9' {
+		multiplier = last
+		amount = amount[:len(amount)-1]
+	}
+	value, err := strconv.ParseInt(amount, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	// 1 BTC = 100,000,000,000 msat
+	switch multiplier {
+	case 0:
+		return value * 100_000_000_000
+	case 'm':
+		return value * 100_000_000
+	case 'u':
+		return value * 100_000
+	case 'n':
+		return value * 100
+	case 'p':
+		return value / 10
+	default:
+		return 0
+	}
+}
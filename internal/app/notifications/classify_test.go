@@ -0,0 +1,108 @@
+package notifications
+
+import (
+	"testing"
+
+	"noscli/internal/nostr"
+)
+
+func TestClassify(t *testing.T) {
+	zapRequest := `{"id":"zr","pubkey":"zapper","created_at":1,"kind":9734,"tags":[["p","me"],["amount","21000"]],"content":"great post","sig":""}`
+
+	tests := []struct {
+		name       string
+		evt        nostr.Event
+		wantOK     bool
+		wantType   Type
+		wantFrom   string
+		wantTarget string
+		wantMsat   int64
+	}{
+		{
+			name:     "mention",
+			evt:      nostr.Event{Kind: 1, PubKey: "alice", Tags: [][]string{{"p", "me"}}},
+			wantOK:   true,
+			wantType: TypeMention,
+			wantFrom: "alice",
+		},
+		{
+			name:       "reply uses marked reply tag",
+			evt:        nostr.Event{Kind: 1, PubKey: "alice", Tags: [][]string{{"e", "parent", "", "reply"}, {"e", "root", "", "root"}, {"p", "me"}}},
+			wantOK:     true,
+			wantType:   TypeReply,
+			wantFrom:   "alice",
+			wantTarget: "parent",
+		},
+		{
+			name:       "reaction",
+			evt:        nostr.Event{Kind: 7, PubKey: "bob", Content: "+", Tags: [][]string{{"e", "note"}, {"p", "me"}}},
+			wantOK:     true,
+			wantType:   TypeReaction,
+			wantFrom:   "bob",
+			wantTarget: "note",
+		},
+		{
+			name:       "generic repost",
+			evt:        nostr.Event{Kind: 16, PubKey: "carol", Tags: [][]string{{"e", "note"}, {"p", "me"}}},
+			wantOK:     true,
+			wantType:   TypeRepost,
+			wantFrom:   "carol",
+			wantTarget: "note",
+		},
+		{
+			name: "zap receipt uses zap request author and amount",
+			evt: nostr.Event{Kind: 9735, PubKey: "lnurl-server", Tags: [][]string{
+				{"p", "me"},
+				{"bolt11", "lnbc210n1xyz"},
+				{"description", zapRequest},
+			}},
+			wantOK:   true,
+			wantType: TypeZap,
+			wantFrom: "zapper",
+			wantMsat: 21000,
+		},
+		{
+			name:   "unrelated kind",
+			evt:    nostr.Event{Kind: 3, PubKey: "dave"},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, ok := classify(tt.evt)
+			if ok != tt.wantOK {
+				t.Fatalf("classify() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if n.Type != tt.wantType || n.From != tt.wantFrom || n.Target != tt.wantTarget || n.AmountMsat != tt.wantMsat {
+				t.Fatalf("classify() = %+v", n)
+			}
+		})
+	}
+}
+
+func TestBolt11AmountMsat(t *testing.T) {
+	tests := []struct {
+		invoice string
+		want    int64
+	}{
+		{invoice: "lnbc210n1pj9x", want: 21_000},
+		{invoice: "lnbc1u1pj9x", want: 100_000},
+		{invoice: "lnbc25m1pvjluez", want: 2_500_000_000},
+		{invoice: "LNBC10P1PJ9X", want: 1},
+		{invoice: "lntb20m1pvjluez", want: 2_000_000_000},
+		{invoice: "lnbc1pvjluez", want: 0},
+		{invoice: "not-an-invoice", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.invoice, func(t *testing.T) {
+			if got := bolt11AmountMsat(tt.invoice); got != tt.want {
+				t.Fatalf("bolt11AmountMsat(%q) = %d, want %d", tt.invoice, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+// defaultLookback is how far back the first run looks when no cursor is stored.
+const defaultLookback = 24 * time.Hour
+
+// Request represents a notifications query.
+type Request struct {
+	Relays []string
+	// Follow keeps the subscription open and prints new notifications as they arrive.
+	Follow bool
+}
+
+// Client exposes the subset of nostr client functionality needed by the notifications service.
+type Client interface {
+	FetchMany(ctx context.Context, relays []string, filter nostr.Filter) ([]nostr.Event, error)
+	StreamMany(ctx context.Context, relays []string, filter nostr.Filter) (<-chan nostr.Event, <-chan error)
+}
+
+// Cursor stores the created_at of the newest notification already shown and
+// the IDs shown at that second.
+type Cursor interface {
+	LoadSeen() (time.Time, []string, error)
+	SaveSeen(time.Time, []string) error
+}
+
+// seenCapacity bounds the IDs remembered to drop notifications shown before.
+const seenCapacity = 4096
+
+// Service fetches and renders events that reference the user.
+type Service struct {
+	client Client
+	cursor Cursor
+	logger *slog.Logger
+}
+
+// NewService creates a Service that relies on the given nostr client and cursor.
+func NewService(client Client, cursor Cursor, logger *slog.Logger) *Service {
+	return &Service{client: client, cursor: cursor, logger: logger}
+}
+
+// Run prints notifications newer than the stored cursor and advances it.
+func (s *Service) Run(ctx context.Context, req Request, w io.Writer) error {
+	if len(req.Relays) == 0 {
+		return errors.New("relay is required")
+	}
+
+	keys, err := nostr.LoadKeysFromEnv()
+	if err != nil {
+		return err
+	}
+
+	last, boundary, err := s.cursor.LoadSeen()
+	if err != nil {
+		s.logger.Warn("load notification cursor failed", "error", err)
+		last, boundary = time.Time{}, nil
+	}
+	// since は包含なので、前回表示した同じ秒のイベントも返る。それらは ID で落とす
+	since := last
+	if last.IsZero() {
+		since = time.Now().Add(-defaultLookback)
+	}
+	seen := nostr.NewSeenSet(seenCapacity)
+	for _, id := range boundary {
+		seen.Add(id)
+	}
+
+	filter := nostr.Filter{
+		Kinds: []int{nostr.KindTextNote, KindRepost, KindReaction, KindGenericRepost, KindZapReceipt},
+		Tags:  map[string][]string{"p": {keys.Public}},
+		Since: &since,
+	}
+
+	events, err := s.client.FetchMany(ctx, req.Relays, filter)
+	if err != nil {
+		return err
+	}
+
+	pos := position{at: last, ids: boundary}
+	moved := false
+	// FetchMany は新しい順なので古い順に表示する
+	for i := len(events) - 1; i >= 0; i-- {
+		evt := events[i]
+		if !seen.Add(evt.ID) {
+			continue
+		}
+		shown, err := s.render(w, evt, keys.Public)
+		if err != nil {
+			return err
+		}
+		if shown && pos.advance(evt) {
+			moved = true
+		}
+	}
+	if moved {
+		s.save(pos)
+	}
+
+	if !req.Follow {
+		return nil
+	}
+
+	if pos.at.After(since) {
+		followSince := pos.at
+		filter.Since = &followSince
+	}
+	stream, errs := s.client.StreamMany(ctx, req.Relays, filter)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case evt, ok := <-stream:
+			if !ok {
+				stream = nil
+				if errs == nil {
+					return nil
+				}
+				continue
+			}
+			if !seen.Add(evt.ID) {
+				continue
+			}
+			shown, err := s.render(w, evt, keys.Public)
+			if err != nil {
+				return err
+			}
+			if shown && pos.advance(evt) {
+				s.save(pos)
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				if stream == nil {
+					return nil
+				}
+				continue
+			}
+			if err == nil || errors.Is(err, context.Canceled) {
+				continue
+			}
+			s.logger.Warn("notifications stream error", "error", err)
+		}
+	}
+}
+
+// render prints evt if it is a notification not caused by self.
+func (s *Service) render(w io.Writer, evt nostr.Event, self string) (bool, error) {
+	n, ok := classify(evt)
+	if !ok || n.From == self {
+		return false, nil
+	}
+	return true, renderNotification(w, n)
+}
+
+func (s *Service) save(pos position) {
+	if err := s.cursor.SaveSeen(pos.at, pos.ids); err != nil {
+		s.logger.Warn("save notification cursor failed", "error", err)
+	}
+}
+
+// position is the cursor: the created_at of the newest notification shown and
+// the IDs shown at that second.
+type position struct {
+	at  time.Time
+	ids []string
+}
+
+// advance moves pos to evt unless evt is older, reporting whether it changed.
+func (p *position) advance(evt nostr.Event) bool {
+	switch {
+	case !p.at.IsZero() && evt.CreatedAt < p.at.Unix():
+		return false
+	case p.at.IsZero() || evt.CreatedAt > p.at.Unix():
+		p.at = evt.CreatedAtTime()
+		p.ids = []string{evt.ID}
+	default:
+		p.ids = append(p.ids, evt.ID)
+	}
+	return true
+}
+
+func renderNotification(w io.Writer, n Notification) error {
+	ts := n.CreatedAt.Local().Format("2006-01-02 15:04:05")
+	author := truncateHex(n.From)
+
+	var detail string
+	switch n.Type {
+	case TypeReply, TypeMention:
+		detail = ": " + sanitizeContent(n.Content)
+	case TypeReaction:
+		detail = ": " + reactionLabel(n.Content)
+	case TypeZap:
+		detail = fmt.Sprintf(": %d sats", n.AmountMsat/1000)
+		if c := strings.TrimSpace(n.Content); c != "" {
+			detail += fmt.Sprintf(" %q", c)
+		}
+	}
+
+	target := ""
+	if n.Target != "" && n.Type != TypeMention {
+		target = " to:" + shortID(n.Target)
+	}
+
+	_, err := fmt.Fprintf(w, "[%s] %-8s %s%s (id:%s%s)\n", ts, n.Type, author, detail, shortID(n.ID), target)
+	return err
+}
+
+func reactionLabel(content string) string {
+	switch content {
+	case "", "+":
+		return "+"
+	case "-":
+		return "-"
+	default:
+		return sanitizeContent(content)
+	}
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+func truncateHex(in string) string {
+	if len(in) <= 12 {
+		return in
+	}
+	return fmt.Sprintf("%s...%s", in[:6], in[len(in)-4:])
+}
+
+func sanitizeContent(in string) string {
+	trimmed := strings.TrimSpace(in)
+	if trimmed == "" {
+		return "(no content)"
+	}
+	trimmed = strings.ReplaceAll(trimmed, "\n", " ")
+	trimmed = strings.ReplaceAll(trimmed, "\r", " ")
+	return trimmed
+}
@@ -0,0 +1,123 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+type mockClient struct {
+	events  []nostr.Event
+	filters []nostr.Filter
+}
+
+func (m *mockClient) FetchMany(_ context.Context, _ []string, filter nostr.Filter) ([]nostr.Event, error) {
+	m.filters = append(m.filters, filter)
+	var out []nostr.Event
+	for _, evt := range m.events {
+		if filter.Since != nil && evt.CreatedAt < filter.Since.Unix() {
+			continue
+		}
+		out = append(out, evt)
+	}
+	return out, nil
+}
+
+func (m *mockClient) StreamMany(context.Context, []string, nostr.Filter) (<-chan nostr.Event, <-chan error) {
+	events := make(chan nostr.Event)
+	errs := make(chan error)
+	close(events)
+	close(errs)
+	return events, errs
+}
+
+type memoryCursor struct {
+	t   time.Time
+	ids []string
+}
+
+func (c *memoryCursor) LoadSeen() (time.Time, []string, error) { return c.t, c.ids, nil }
+
+func (c *memoryCursor) SaveSeen(t time.Time, ids []string) error {
+	c.t = t
+	c.ids = ids
+	return nil
+}
+
+func TestServiceRun(t *testing.T) {
+	priv := bytes.Repeat([]byte{0x01}, 32)
+	nsec, err := nostr.EncodeNsec(priv)
+	if err != nil {
+		t.Fatalf("EncodeNsec: %v", err)
+	}
+	t.Setenv("NOSTR_NSEC", nsec)
+	self, err := nostr.PublicKey(priv)
+	if err != nil {
+		t.Fatalf("PublicKey: %v", err)
+	}
+
+	now := time.Now().Unix()
+	client := &mockClient{
+		// FetchMany returns newest first.
+		events: []nostr.Event{
+			{ID: "own-reply", Kind: 1, PubKey: self, CreatedAt: now - 10, Tags: [][]string{{"p", self}}},
+			{ID: "reaction1", Kind: 7, PubKey: "bob", CreatedAt: now - 20, Content: "🤙", Tags: [][]string{{"e", "note1"}}},
+			{ID: "mention1", Kind: 1, PubKey: "alice", CreatedAt: now - 30, Content: "hi\nthere"},
+		},
+	}
+	cursor := &memoryCursor{}
+	svc := NewService(client, cursor, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var buf bytes.Buffer
+	if err := svc.Run(ctx, Request{Relays: []string{"wss://relay.example.com"}}, &buf); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 notifications, got %q", buf.String())
+	}
+	if !strings.Contains(lines[0], "mention  alice: hi there") {
+		t.Fatalf("first line = %q, want oldest mention", lines[0])
+	}
+	if !strings.Contains(lines[1], "reaction bob: 🤙") || !strings.Contains(lines[1], "to:note1") {
+		t.Fatalf("second line = %q, want reaction", lines[1])
+	}
+	if got := cursor.t.Unix(); got != now-20 {
+		t.Fatalf("cursor = %d, want %d (newest shown, own events ignored)", got, now-20)
+	}
+
+	// A second run asks from the cursor's second and drops what was shown there.
+	client.events = append([]nostr.Event{{ID: "reaction2", Kind: 7, PubKey: "carol", CreatedAt: now - 20, Content: "+", Tags: [][]string{{"e", "note1"}}}}, client.events...)
+	buf.Reset()
+	if err := svc.Run(ctx, Request{Relays: []string{"wss://relay.example.com"}}, &buf); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if got := client.filters[1].Since.Unix(); got != now-20 {
+		t.Fatalf("second run since = %d, want %d", got, now-20)
+	}
+	if got := strings.TrimSpace(buf.String()); strings.Count(got, "\n") != 0 || !strings.Contains(got, "reaction carol: +") {
+		t.Fatalf("second run should show only the new reaction in the same second, got %q", buf.String())
+	}
+	if strings.Join(cursor.ids, ",") != "reaction1,reaction2" {
+		t.Fatalf("cursor ids = %v, want both reactions", cursor.ids)
+	}
+
+	// A third run shows nothing new.
+	buf.Reset()
+	if err := svc.Run(ctx, Request{Relays: []string{"wss://relay.example.com"}}, &buf); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("third run should show nothing new, got %q", buf.String())
+	}
+}
@@ -2,29 +2,37 @@ package post
 
 import (
 	"context"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/btcsuite/btcd/btcec/v2"
-	"github.com/btcsuite/btcd/btcec/v2/schnorr"
-	"github.com/btcsuite/btcutil/bech32"
-
 	"noscli/internal/nostr"
 )
 
 // Request represents a post request.
 type Request struct {
-	Relay   string
+	Relay string
+	// Relays publishes the same signed note to each of these relays instead of Relay.
+	Relays  []string
 	Content string
 	ReplyTo string
+	// Tags holds additional tags appended after the generated ones.
+	Tags [][]string
+	// ContentWarning adds a NIP-36 content-warning tag with the given reason when non-empty.
+	ContentWarning string
+	// ExpireAfter adds a NIP-40 expiration tag relative to the creation time when positive.
+	ExpireAfter time.Duration
+	// PoW is the NIP-13 difficulty (leading zero bits) to mine before signing.
+	PoW int
 }
 
+// defaultRetryPoW is used when a relay demands proof of work without saying how much.
+const defaultRetryPoW = 20
+
 // Client exposes the subset of nostr client functionality needed by the post service.
 type Client interface {
 	Publish(ctx context.Context, relay string, evt nostr.Event) error
@@ -41,9 +49,15 @@ func NewService(client Client, logger *slog.Logger) *Service {
 	return &Service{client: client, logger: logger}
 }
 
-// Run executes the post request and writes a short result to w.
+// Run executes the post request and writes a short result per relay to w.
+// A relay that fails does not stop the others; the failures are returned
+// together.
 func (s *Service) Run(ctx context.Context, req Request, w io.Writer) error {
-	if strings.TrimSpace(req.Relay) == "" {
+	relays := req.Relays
+	if len(relays) == 0 && strings.TrimSpace(req.Relay) != "" {
+		relays = []string{req.Relay}
+	}
+	if len(relays) == 0 {
 		return errors.New("relay is required")
 	}
 	content := strings.TrimSpace(req.Content)
@@ -51,128 +65,128 @@ func (s *Service) Run(ctx context.Context, req Request, w io.Writer) error {
 		return errors.New("content is empty")
 	}
 
-	priv, pub, err := loadKeysFromEnv()
+	keys, err := nostr.LoadKeysFromEnv()
 	if err != nil {
 		return err
 	}
 
+	createdAt := time.Now()
 	evt := nostr.Event{
-		PubKey:    pub,
-		CreatedAt: time.Now().Unix(),
+		PubKey:    keys.Public,
+		CreatedAt: createdAt.Unix(),
 		Kind:      nostr.KindTextNote,
-		Tags:      [][]string{},
+		Tags:      buildTags(req, content, createdAt),
 		Content:   content,
 	}
-	if req.ReplyTo != "" {
-		evt.Tags = append(evt.Tags, []string{"e", req.ReplyTo})
-	}
 
-	if err := nostr.SignEvent(&evt, priv); err != nil {
-		return fmt.Errorf("sign event: %w", err)
-	}
-
-	if err := s.client.Publish(ctx, req.Relay, evt); err != nil {
+	if err := mineAndSign(ctx, &evt, keys.Private, req.PoW); err != nil {
 		return err
 	}
 
-	prefixForPreview := evt.ID
-	if len(prefixForPreview) > 8 {
-		prefixForPreview = prefixForPreview[:8]
-	}
-	if _, err := fmt.Fprintf(w, "published: id:%s relay:%s\n", prefixForPreview, req.Relay); err != nil {
-		return err
+	var errs []error
+	for _, relay := range relays {
+		if err := s.publish(ctx, relay, &evt, keys.Private, req.PoW); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		prefixForPreview := evt.ID
+		if len(prefixForPreview) > 8 {
+			prefixForPreview = prefixForPreview[:8]
+		}
+		if _, err := fmt.Fprintf(w, "published: id:%s relay:%s\n", prefixForPreview, relay); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
-// loadKeysFromEnv reads NOSTR_NSEC and returns the raw private key and public key (both 32-byte hex).
-func loadKeysFromEnv() ([]byte, string, error) {
-	nsec := strings.TrimSpace(os.Getenv("NOSTR_NSEC"))
-	if nsec == "" {
-		return nil, "", errors.New("NOSTR_NSEC is not set")
+// publish sends evt to relay. When the relay demands proof of work, evt is
+// mined to the requested difficulty, re-signed and sent again; relays after
+// it then receive the mined event.
+func (s *Service) publish(ctx context.Context, relay string, evt *nostr.Event, priv []byte, pow int) error {
+	err := s.client.Publish(ctx, relay, *evt)
+	if err == nil {
+		return nil
 	}
-	priv, err := decodeNsec(nsec)
-	if err != nil {
-		return nil, "", fmt.Errorf("decode NOSTR_NSEC: %w", err)
+	target, ok := retryPoWTarget(err, pow)
+	if !ok {
+		return err
 	}
-
-	// Derive public key using the same curve as verification.
-	pubHex, err := derivePubKeyHex(priv)
-	if err != nil {
-		return nil, "", err
+	s.logger.Info("relay requires proof of work, retrying", "relay", relay, "difficulty", target)
+	if err := mineAndSign(ctx, evt, priv, target); err != nil {
+		return err
 	}
-
-	return priv, pubHex, nil
+	return s.client.Publish(ctx, relay, *evt)
 }
 
-func derivePubKeyHex(priv []byte) (string, error) {
-	if len(priv) != 32 {
-		return "", fmt.Errorf("invalid private key length: %d", len(priv))
+// mineAndSign optionally mines a NIP-13 nonce and then signs the event.
+func mineAndSign(ctx context.Context, evt *nostr.Event, priv []byte, pow int) error {
+	if pow > 0 {
+		if err := nostr.MinePoW(ctx, evt, pow); err != nil {
+			return fmt.Errorf("mine pow: %w", err)
+		}
 	}
-
-	sk, _ := btcec.PrivKeyFromBytes(priv)
-	if sk == nil {
-		return "", errors.New("invalid private key")
+	if err := nostr.SignEvent(evt, priv); err != nil {
+		return fmt.Errorf("sign event: %w", err)
 	}
-	pubKeyBytes := schnorr.SerializePubKey(sk.PubKey())
-	return hex.EncodeToString(pubKeyBytes), nil
+	return nil
 }
 
-// decodeNsec decodes a NIP-19 nsec bech32 string and returns the raw 32-byte private key.
-func decodeNsec(nsec string) ([]byte, error) {
-	hrp, data, err := bech32.Decode(nsec)
-	if err != nil {
-		return nil, err
-	}
-	if hrp != "nsec" {
-		return nil, fmt.Errorf("unexpected HRP: %s", hrp)
+// retryPoWTarget decides whether a publish error is a "pow:" rejection worth
+// retrying and returns the difficulty to mine for the retry.
+func retryPoWTarget(err error, current int) (int, bool) {
+	var rejected *nostr.RejectedError
+	if !errors.As(err, &rejected) {
+		return 0, false
 	}
-
-	// Convert 5-bit groups back to 8-bit bytes.
-	eightBits, err := convertBits(data, 5, 8, false)
-	if err != nil {
-		return nil, err
+	required, ok := rejected.RequiredPoW()
+	if !ok {
+		return 0, false
 	}
-	if len(eightBits) != 32 {
-		return nil, fmt.Errorf("unexpected nsec length: %d", len(eightBits))
+	if required <= current {
+		// 要求値が読み取れない、または既に満たしているはずの場合は既定値まで引き上げる
+		required = max(defaultRetryPoW, current+4)
 	}
-
-	// For nsec, payload is just the 32-byte private key.
-	return eightBits, nil
+	return required, true
 }
 
-// convertBits converts a slice of data where each element is fromBits wide into
-// a slice where each element is toBits wide. It is used for bech32 encoding/decoding.
-func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
-	var ret []byte
-	var acc uint
-	var bits uint
-	maxv := uint((1 << toBits) - 1)
-	maxAcc := uint((1 << (fromBits + toBits - 1)) - 1)
-
-	for _, value := range data {
-		v := uint(value)
-		if v>>fromBits != 0 {
-			return nil, fmt.Errorf("invalid data range: %d", value)
-		}
-		acc = ((acc << fromBits) | v) & maxAcc
-		bits += fromBits
-		for bits >= toBits {
-			bits -= toBits
-			ret = append(ret, byte((acc>>bits)&maxv))
+// buildTags assembles the tags for a text note: the reply reference first,
+// then hashtags found in content as "t" tags, the NIP-36/NIP-40 tags, then
+// any extra request tags. Exact duplicates are dropped so "--tag t=foo" with
+// "#foo" yields one tag.
+func buildTags(req Request, content string, createdAt time.Time) [][]string {
+	tags := [][]string{}
+	seen := make(map[string]struct{})
+	add := func(tag []string) {
+		key := strings.Join(tag, "\x00")
+		if _, ok := seen[key]; ok {
+			return
 		}
+		seen[key] = struct{}{}
+		tags = append(tags, tag)
 	}
 
-	if pad {
-		if bits > 0 {
-			ret = append(ret, byte((acc<<(toBits-bits))&maxv))
+	if req.ReplyTo != "" {
+		add([]string{"e", req.ReplyTo})
+	}
+	for _, hashtag := range nostr.ExtractHashtags(content) {
+		add([]string{"t", hashtag})
+	}
+	if req.ContentWarning != "" {
+		add([]string{nostr.TagContentWarning, req.ContentWarning})
+	}
+	if req.ExpireAfter > 0 {
+		expiresAt := createdAt.Add(req.ExpireAfter).Unix()
+		add([]string{nostr.TagExpiration, strconv.FormatInt(expiresAt, 10)})
+	}
+	for _, tag := range req.Tags {
+		if len(tag) == 0 || tag[0] == "" {
+			continue
 		}
-	} else if bits >= fromBits {
-		return nil, fmt.Errorf("illegal zero padding")
-	} else if ((acc << (toBits - bits)) & maxv) != 0 {
-		return nil, fmt.Errorf("non-zero padding")
+		add(tag)
 	}
 
-	return ret, nil
+	return tags
 }
@@ -6,12 +6,11 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
-	"github.com/btcsuite/btcutil/bech32"
-
 	"noscli/internal/nostr"
 )
 
@@ -32,9 +31,9 @@ func (m *mockClient) Publish(_ context.Context, relay string, evt nostr.Event) e
 
 func TestServiceRun(t *testing.T) {
 	privKey := bytes.Repeat([]byte{0x01}, 32)
-	nsec, err := encodeNsec(privKey)
+	nsec, err := nostr.EncodeNsec(privKey)
 	if err != nil {
-		t.Fatalf("encodeNsec: %v", err)
+		t.Fatalf("EncodeNsec: %v", err)
 	}
 
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
@@ -198,14 +197,141 @@ func TestServiceRun(t *testing.T) {
 	}
 }
 
-func encodeNsec(priv []byte) (string, error) {
-	if len(priv) != 32 {
-		return "", errors.New("invalid private key length")
+func TestBuildTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     Request
+		content string
+		want    [][]string
+	}{
+		{
+			name:    "no tags",
+			content: "hello nostr",
+			want:    [][]string{},
+		},
+		{
+			name:    "reply and hashtags",
+			req:     Request{ReplyTo: "abcdef"},
+			content: "hello #Nostr #のすきー",
+			want: [][]string{
+				{"e", "abcdef"},
+				{"t", "nostr"},
+				{"t", "のすきー"},
+			},
+		},
+		{
+			name: "content warning and expiration",
+			req: Request{
+				ContentWarning: "spoiler",
+				ExpireAfter:    24 * time.Hour,
+			},
+			content: "ending revealed",
+			want: [][]string{
+				{"content-warning", "spoiler"},
+				{"expiration", "1700086400"},
+			},
+		},
+		{
+			name: "extra tags are appended and deduplicated",
+			req: Request{
+				Tags: [][]string{{"t", "nostr"}, {"client", "noscli"}, {"", "ignored"}},
+			},
+			content: "#nostr",
+			want: [][]string{
+				{"t", "nostr"},
+				{"client", "noscli"},
+			},
+		},
 	}
 
-	data, err := convertBits(priv, 8, 5, true)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildTags(tt.req, tt.content, time.Unix(1_700_000_000, 0))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("buildTags() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+type powRejectingClient struct {
+	calls []nostr.Event
+}
+
+func (m *powRejectingClient) Publish(_ context.Context, relay string, evt nostr.Event) error {
+	m.calls = append(m.calls, evt)
+	if nostr.Difficulty(evt.ID) < 8 {
+		return &nostr.RejectedError{Relay: relay, EventID: evt.ID, Message: "pow: difficulty too low, need 8"}
+	}
+	return nil
+}
+
+func TestServiceRunRetriesWithPoW(t *testing.T) {
+	nsec, err := nostr.EncodeNsec(bytes.Repeat([]byte{0x01}, 32))
+	if err != nil {
+		t.Fatalf("EncodeNsec: %v", err)
+	}
+	t.Setenv("NOSTR_NSEC", nsec)
+
+	client := &powRejectingClient{}
+	svc := NewService(client, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req := Request{Relay: "wss://relay.example.com", Content: "hello"}
+	if err := svc.Run(ctx, req, io.Discard); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	if len(client.calls) != 2 {
+		t.Fatalf("Publish calls = %d, want 2", len(client.calls))
+	}
+	retried := client.calls[1]
+	if got := nostr.Difficulty(retried.ID); got < 8 {
+		t.Fatalf("retried event difficulty = %d, want >= 8", got)
+	}
+	if err := retried.Verify(); err != nil {
+		t.Fatalf("Verify() failed for mined event: %v", err)
+	}
+}
+
+// relayFailingClient records publishes and fails those sent to failRelay.
+type relayFailingClient struct {
+	mockClient
+	failRelay string
+}
+
+func (c *relayFailingClient) Publish(ctx context.Context, relay string, evt nostr.Event) error {
+	c.mockClient.Publish(ctx, relay, evt)
+	if relay == c.failRelay {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestServiceRunPublishesToEveryRelay(t *testing.T) {
+	nsec, err := nostr.EncodeNsec(bytes.Repeat([]byte{0x01}, 32))
 	if err != nil {
-		return "", err
+		t.Fatalf("EncodeNsec: %v", err)
+	}
+	t.Setenv("NOSTR_NSEC", nsec)
+
+	client := &relayFailingClient{failRelay: "wss://b"}
+	svc := NewService(client, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var buf bytes.Buffer
+	req := Request{Relays: []string{"wss://a", "wss://b", "wss://c"}, Content: "hello"}
+	err = svc.Run(context.Background(), req, &buf)
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("Run() error = %v, want the failure of wss://b", err)
+	}
+
+	// One signed note goes to every relay; the failing one does not stop the rest.
+	if len(client.calls) != 3 || client.calls[0].evt.ID != client.calls[2].evt.ID || client.calls[2].relay != "wss://c" {
+		t.Fatalf("Publish calls = %+v, want the same note sent to all three relays", client.calls)
+	}
+	if got := strings.Count(buf.String(), "published:"); got != 2 {
+		t.Fatalf("output = %q, want two published lines", buf.String())
 	}
-	return bech32.Encode("nsec", data)
 }
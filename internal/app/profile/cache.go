@@ -0,0 +1,97 @@
+package profile
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache keeps profiles in memory for a limited time. Authors without any
+// metadata are cached too so they are not fetched over and over.
+type Cache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	profile   Profile
+	found     bool
+	fetchedAt time.Time
+}
+
+// NewCache creates a Cache whose entries expire after ttl.
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// Get returns the cached profile. fresh is false when the entry is missing
+// or older than the TTL; a stale profile is still returned so callers can
+// render it while a refresh is in flight.
+func (c *Cache) Get(pubkey string) (p Profile, found bool, fresh bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[pubkey]
+	if !ok {
+		return Profile{}, false, false
+	}
+	return entry.profile, entry.found, c.now().Sub(entry.fetchedAt) < c.ttl
+}
+
+// Put stores p unless a newer version is already cached.
+func (c *Cache) Put(p Profile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[p.PubKey]; ok && entry.found && entry.profile.CreatedAt.After(p.CreatedAt) {
+		entry.fetchedAt = c.now()
+		c.entries[p.PubKey] = entry
+		return
+	}
+	if entry, ok := c.entries[p.PubKey]; ok && entry.profile.Verified && entry.profile.NIP05 == p.NIP05 {
+		p.Verified = true
+	}
+	c.entries[p.PubKey] = cacheEntry{profile: p, found: true, fetchedAt: c.now()}
+}
+
+// SetVerified records the NIP-05 verification result for pubkey, provided
+// the cached profile still claims the same identifier.
+func (c *Cache) SetVerified(pubkey, nip05 string, verified bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[pubkey]
+	if !ok || !entry.found || entry.profile.NIP05 != nip05 {
+		return
+	}
+	entry.profile.Verified = verified
+	c.entries[pubkey] = entry
+}
+
+// PutStale stores p as already expired, e.g. when seeded from the local store,
+// so it is shown immediately but still refreshed from relays.
+func (c *Cache) PutStale(p Profile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[p.PubKey]; ok {
+		return
+	}
+	c.entries[p.PubKey] = cacheEntry{profile: p, found: true, fetchedAt: c.now().Add(-c.ttl)}
+}
+
+// MarkMissing records that relays have no metadata for pubkey, keeping any
+// previously known profile.
+func (c *Cache) MarkMissing(pubkey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.entries[pubkey]
+	entry.fetchedAt = c.now()
+	c.entries[pubkey] = entry
+}
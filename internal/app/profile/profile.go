@@ -0,0 +1,88 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"noscli/internal/nostr"
+)
+
+// KindMetadata is the NIP-01 kind 0 user metadata event.
+const KindMetadata = 0
+
+// VerifiedBadge marks names whose NIP-05 identifier resolves to the author.
+const VerifiedBadge = "✓"
+
+// Profile is the parsed content of a kind 0 event.
+type Profile struct {
+	PubKey      string `json:"-"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	About       string `json:"about"`
+	Picture     string `json:"picture"`
+	Website     string `json:"website"`
+	NIP05       string `json:"nip05"`
+	LUD16       string `json:"lud16"`
+	// CreatedAt is the created_at of the metadata event, used to keep the newest one.
+	CreatedAt time.Time `json:"-"`
+	// Verified is set once NIP05 has been confirmed to resolve to PubKey.
+	Verified bool `json:"-"`
+}
+
+// Parse decodes a kind 0 event.
+func Parse(evt nostr.Event) (Profile, error) {
+	if evt.Kind != KindMetadata {
+		return Profile{}, fmt.Errorf("unexpected kind: %d", evt.Kind)
+	}
+	var p Profile
+	if err := json.Unmarshal([]byte(evt.Content), &p); err != nil {
+		return Profile{}, fmt.Errorf("decode metadata: %w", err)
+	}
+	// 古いクライアントは displayName を使っていることがある
+	if p.DisplayName == "" {
+		var legacy struct {
+			DisplayName string `json:"displayName"`
+		}
+		if err := json.Unmarshal([]byte(evt.Content), &legacy); err == nil {
+			p.DisplayName = legacy.DisplayName
+		}
+	}
+	p.PubKey = evt.PubKey
+	p.CreatedAt = evt.CreatedAtTime()
+	return p, nil
+}
+
+// Label renders the profile as "display_name (@name)", falling back to
+// whichever of the two is set.
+func (p Profile) Label() string {
+	display := singleLine(p.DisplayName)
+	name := singleLine(p.Name)
+	switch {
+	case display != "" && name != "" && display != name:
+		return fmt.Sprintf("%s (@%s)", display, name)
+	case display != "":
+		return display
+	case name != "":
+		return "@" + name
+	default:
+		return ""
+	}
+}
+
+// singleLine collapses whitespace and drops control characters, so that
+// names from relays cannot inject terminal escape sequences.
+func singleLine(in string) string {
+	in = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, in)
+	return strings.Join(strings.Fields(in), " ")
+}
@@ -0,0 +1,81 @@
+package profile
+
+import (
+	"testing"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		evt     nostr.Event
+		want    Profile
+		wantErr bool
+	}{
+		{
+			name: "standard fields",
+			evt:  nostr.Event{PubKey: "abc", Kind: 0, CreatedAt: 10, Content: `{"name":"alice","display_name":"Alice","nip05":"alice@example.com"}`},
+			want: Profile{PubKey: "abc", Name: "alice", DisplayName: "Alice", NIP05: "alice@example.com", CreatedAt: time.Unix(10, 0)},
+		},
+		{
+			name: "legacy displayName",
+			evt:  nostr.Event{PubKey: "abc", Kind: 0, CreatedAt: 10, Content: `{"displayName":"Alice"}`},
+			want: Profile{PubKey: "abc", DisplayName: "Alice", CreatedAt: time.Unix(10, 0)},
+		},
+		{
+			name:    "wrong kind",
+			evt:     nostr.Event{Kind: 1, Content: `{}`},
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			evt:     nostr.Event{Kind: 0, Content: `not json`},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.evt)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if got.PubKey != tt.want.PubKey || got.Name != tt.want.Name || got.DisplayName != tt.want.DisplayName ||
+				got.NIP05 != tt.want.NIP05 || !got.CreatedAt.Equal(tt.want.CreatedAt) {
+				t.Fatalf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileLabel(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Profile
+		want string
+	}{
+		{name: "both", p: Profile{Name: "alice", DisplayName: "Alice ✨"}, want: "Alice ✨ (@alice)"},
+		{name: "same", p: Profile{Name: "alice", DisplayName: "alice"}, want: "alice"},
+		{name: "name only", p: Profile{Name: "alice"}, want: "@alice"},
+		{name: "display only", p: Profile{DisplayName: "Alice"}, want: "Alice"},
+		{name: "multiline", p: Profile{DisplayName: "Alice\nin\twonderland"}, want: "Alice in wonderland"},
+		{name: "escape", p: Profile{Name: "mallory\x1b[2J\x1b]0;pwned\a", DisplayName: "\u009b31mMallory"}, want: "31mMallory (@mallory[2J]0;pwned)"},
+		{name: "empty", p: Profile{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.Label(); got != tt.want {
+				t.Fatalf("Label() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package profile
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+const (
+	// DefaultTTL is how long fetched metadata is trusted before refreshing.
+	DefaultTTL = 30 * time.Minute
+
+	defaultBatchSize  = 50
+	defaultBatchDelay = 300 * time.Millisecond
+	fetchTimeout      = 10 * time.Second
+	queueSize         = 256
+)
+
+// Client exposes the subset of nostr client functionality needed to fetch metadata.
+type Client interface {
+	FetchLatest(ctx context.Context, relays []string, filter nostr.Filter) ([]nostr.Event, error)
+}
+
+// Store persists metadata events so names are available in later runs and offline.
+type Store interface {
+	Save(evt nostr.Event) error
+	Query(filter nostr.Filter) ([]nostr.Event, error)
+}
+
+// Verifier checks NIP-05 identifiers claimed in metadata.
+type Verifier interface {
+	Verify(ctx context.Context, identifier, pubkey string) (bool, error)
+}
+
+// Resolver looks up profiles without blocking the caller. Unknown authors are
+// queued and fetched in batches with a single REQ per batch.
+type Resolver struct {
+	client   Client
+	store    Store
+	verifier Verifier
+	relays   []string
+	cache    *Cache
+	logger   *slog.Logger
+
+	batchSize  int
+	batchDelay time.Duration
+
+	queue   chan string
+	mu      sync.Mutex
+	pending map[string]struct{}
+}
+
+// NewResolver creates a Resolver that fetches from relays. store and verifier
+// may be nil; without a verifier no profile is marked as NIP-05 verified.
+// Run must be started for queued lookups to be fetched.
+func NewResolver(client Client, store Store, verifier Verifier, relays []string, cache *Cache, logger *slog.Logger) *Resolver {
+	return &Resolver{
+		client:     client,
+		store:      store,
+		verifier:   verifier,
+		relays:     relays,
+		cache:      cache,
+		logger:     logger,
+		batchSize:  defaultBatchSize,
+		batchDelay: defaultBatchDelay,
+		queue:      make(chan string, queueSize),
+		pending:    make(map[string]struct{}),
+	}
+}
+
+// Lookup returns the best known profile for pubkey. When the cache has no
+// fresh entry a background fetch is scheduled; it never blocks on the network.
+func (r *Resolver) Lookup(pubkey string) (Profile, bool) {
+	p, found, fresh := r.cache.Get(pubkey)
+	if !found && !fresh {
+		if seeded, ok := r.loadStored(pubkey); ok {
+			p, found = seeded, true
+		}
+	}
+	if !fresh {
+		r.enqueue(pubkey)
+	}
+	return p, found
+}
+
+// Resolve returns the profile for pubkey like Lookup, but fetches it before
+// returning when the cache has no fresh entry. It suits one-shot commands
+// that print a single author and do not start Run.
+func (r *Resolver) Resolve(ctx context.Context, pubkey string) (Profile, bool) {
+	if p, found, fresh := r.cache.Get(pubkey); fresh {
+		return p, found
+	}
+	r.fetch(ctx, []string{pubkey})
+	if p, found, _ := r.cache.Get(pubkey); found {
+		return p, true
+	}
+	return r.loadStored(pubkey)
+}
+
+// Run fetches queued authors until ctx is cancelled.
+func (r *Resolver) Run(ctx context.Context) {
+	var (
+		batch []string
+		timer *time.Timer
+		fire  <-chan time.Time
+	)
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, fire = nil, nil
+		}
+		if len(batch) == 0 {
+			return
+		}
+		r.fetch(ctx, batch)
+		batch = nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case pubkey := <-r.queue:
+			batch = append(batch, pubkey)
+			if len(batch) >= r.batchSize {
+				flush()
+				continue
+			}
+			if timer == nil {
+				timer = time.NewTimer(r.batchDelay)
+				fire = timer.C
+			}
+		case <-fire:
+			flush()
+		}
+	}
+}
+
+func (r *Resolver) enqueue(pubkey string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.pending[pubkey]; ok {
+		return
+	}
+	select {
+	case r.queue <- pubkey:
+		r.pending[pubkey] = struct{}{}
+	default:
+		// キューが埋まっている場合は次回の Lookup で再試行する
+	}
+}
+
+func (r *Resolver) fetch(ctx context.Context, authors []string) {
+	defer func() {
+		r.mu.Lock()
+		for _, pubkey := range authors {
+			delete(r.pending, pubkey)
+		}
+		r.mu.Unlock()
+	}()
+
+	fetchCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	filter := nostr.Filter{Authors: authors, Kinds: []int{KindMetadata}}
+	events, err := r.client.FetchLatest(fetchCtx, r.relays, filter)
+	if err != nil {
+		r.logger.Debug("fetch profiles failed", "authors", len(authors), "error", err)
+		return
+	}
+
+	resolved := make(map[string]struct{}, len(events))
+	var claims []Profile
+	for _, evt := range events {
+		p, err := Parse(evt)
+		if err != nil {
+			r.logger.Debug("skip invalid metadata", "id", evt.ID, "error", err)
+			continue
+		}
+		r.cache.Put(p)
+		if p.NIP05 != "" {
+			claims = append(claims, p)
+		}
+		resolved[evt.PubKey] = struct{}{}
+		if r.store != nil {
+			if err := r.store.Save(evt); err != nil {
+				r.logger.Debug("cache metadata failed", "id", evt.ID, "error", err)
+			}
+		}
+	}
+	for _, pubkey := range authors {
+		if _, ok := resolved[pubkey]; !ok {
+			r.cache.MarkMissing(pubkey)
+		}
+	}
+
+	if r.verifier != nil && len(claims) > 0 {
+		// HTTP 問い合わせでバッチ処理を止めないよう別 goroutine で検証する
+		go r.verify(ctx, claims)
+	}
+}
+
+// verify checks NIP-05 claims one by one and records the results in the cache.
+func (r *Resolver) verify(ctx context.Context, claims []Profile) {
+	for _, p := range claims {
+		if ctx.Err() != nil {
+			return
+		}
+		verified, err := r.verifier.Verify(ctx, p.NIP05, p.PubKey)
+		if err != nil {
+			r.logger.Debug("nip05 verification failed", "nip05", p.NIP05, "pubkey", p.PubKey, "error", err)
+		}
+		r.cache.SetVerified(p.PubKey, p.NIP05, verified)
+	}
+}
+
+// loadStored seeds the cache from the local store, if any.
+func (r *Resolver) loadStored(pubkey string) (Profile, bool) {
+	if r.store == nil {
+		return Profile{}, false
+	}
+	events, err := r.store.Query(nostr.Filter{Authors: []string{pubkey}, Kinds: []int{KindMetadata}, Limit: 1})
+	if err != nil || len(events) == 0 {
+		return Profile{}, false
+	}
+	p, err := Parse(events[0])
+	if err != nil {
+		return Profile{}, false
+	}
+	r.cache.PutStale(p)
+	return p, true
+}
@@ -0,0 +1,176 @@
+package profile
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+type recordingClient struct {
+	mu      sync.Mutex
+	filters []nostr.Filter
+	events  []nostr.Event
+	done    chan struct{}
+}
+
+func (c *recordingClient) FetchLatest(_ context.Context, _ []string, filter nostr.Filter) ([]nostr.Event, error) {
+	c.mu.Lock()
+	c.filters = append(c.filters, filter)
+	c.mu.Unlock()
+	defer func() { c.done <- struct{}{} }()
+
+	var out []nostr.Event
+	for _, evt := range c.events {
+		if filter.Matches(evt) {
+			out = append(out, evt)
+		}
+	}
+	return out, nil
+}
+
+func TestResolverBatchesLookups(t *testing.T) {
+	client := &recordingClient{
+		events: []nostr.Event{
+			{ID: "1", PubKey: "alice", Kind: KindMetadata, CreatedAt: 1, Content: `{"name":"old"}`},
+			{ID: "2", PubKey: "alice", Kind: KindMetadata, CreatedAt: 2, Content: `{"name":"alice"}`},
+		},
+		done: make(chan struct{}, 1),
+	}
+	cache := NewCache(time.Hour)
+	r := NewResolver(client, nil, nil, []string{"wss://relay.example"}, cache, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	r.batchDelay = 50 * time.Millisecond
+
+	for _, pubkey := range []string{"alice", "bob", "alice", "carol"} {
+		if _, ok := r.Lookup(pubkey); ok {
+			t.Fatalf("Lookup(%q) resolved before fetch", pubkey)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+
+	select {
+	case <-client.done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for fetch")
+	}
+
+	client.mu.Lock()
+	if len(client.filters) != 1 {
+		t.Fatalf("expected one batched REQ, got %d", len(client.filters))
+	}
+	authors := append([]string(nil), client.filters[0].Authors...)
+	client.mu.Unlock()
+	sort.Strings(authors)
+	if len(authors) != 3 || authors[0] != "alice" || authors[1] != "bob" || authors[2] != "carol" {
+		t.Fatalf("unexpected authors in REQ: %v", authors)
+	}
+
+	// done fires when FetchLatest returns, before the resolver caches the result.
+	deadline := time.Now().Add(2 * time.Second)
+	p, ok := r.Lookup("alice")
+	for !ok && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		p, ok = r.Lookup("alice")
+	}
+	if !ok || p.Name != "alice" {
+		t.Fatalf("Lookup(alice) = %+v, %v; want newest metadata", p, ok)
+	}
+	if _, ok := r.Lookup("bob"); ok {
+		t.Fatalf("Lookup(bob) should not resolve without metadata")
+	}
+
+	// Fresh entries, including missing ones, must not be fetched again.
+	select {
+	case <-client.done:
+		t.Fatalf("unexpected second fetch")
+	case <-time.After(150 * time.Millisecond):
+	}
+}
+
+func TestResolverResolve(t *testing.T) {
+	client := &recordingClient{
+		events: []nostr.Event{{ID: "1", PubKey: "alice", Kind: KindMetadata, CreatedAt: 1, Content: `{"name":"alice"}`}},
+		done:   make(chan struct{}, 2),
+	}
+	r := NewResolver(client, nil, nil, []string{"wss://relay.example"}, NewCache(time.Hour), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// Resolve fetches synchronously without Run; a fresh entry is not fetched again.
+	for i := 0; i < 2; i++ {
+		p, ok := r.Resolve(context.Background(), "alice")
+		if !ok || p.Name != "alice" {
+			t.Fatalf("Resolve(alice) = %+v, %v; want the fetched profile", p, ok)
+		}
+	}
+	if _, ok := r.Resolve(context.Background(), "bob"); ok {
+		t.Fatalf("Resolve(bob) should not resolve without metadata")
+	}
+	if len(client.filters) != 2 {
+		t.Fatalf("fetched %d times, want once per author", len(client.filters))
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := NewCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.Put(Profile{PubKey: "alice", Name: "new", CreatedAt: time.Unix(20, 0)})
+	cache.Put(Profile{PubKey: "alice", Name: "old", CreatedAt: time.Unix(10, 0)})
+
+	p, found, fresh := cache.Get("alice")
+	if !found || !fresh || p.Name != "new" {
+		t.Fatalf("Get() = %+v, %v, %v; want fresh newest profile", p, found, fresh)
+	}
+
+	now = now.Add(2 * time.Minute)
+	p, found, fresh = cache.Get("alice")
+	if !found || fresh || p.Name != "new" {
+		t.Fatalf("Get() after ttl = %+v, %v, %v; want stale profile", p, found, fresh)
+	}
+}
+
+type fakeVerifier struct {
+	valid map[string]string
+}
+
+func (v fakeVerifier) Verify(_ context.Context, identifier, pubkey string) (bool, error) {
+	return v.valid[identifier] == pubkey, nil
+}
+
+func TestResolverVerifiesNIP05(t *testing.T) {
+	client := &recordingClient{
+		events: []nostr.Event{
+			{ID: "1", PubKey: "alice", Kind: KindMetadata, CreatedAt: 1, Content: `{"name":"alice","nip05":"alice@example.com"}`},
+			{ID: "2", PubKey: "mallory", Kind: KindMetadata, CreatedAt: 1, Content: `{"name":"mallory","nip05":"alice@example.com"}`},
+		},
+		done: make(chan struct{}, 1),
+	}
+	verifier := fakeVerifier{valid: map[string]string{"alice@example.com": "alice"}}
+	r := NewResolver(client, nil, verifier, nil, NewCache(time.Hour), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	r.fetch(context.Background(), []string{"alice", "mallory"})
+	<-client.done
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		p, _ := r.Lookup("alice")
+		if p.Verified {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("alice was never verified")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if p, _ := r.Lookup("mallory"); p.Verified {
+		t.Fatalf("mallory must not be verified for someone else's identifier")
+	}
+}
@@ -0,0 +1,123 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"noscli/internal/nostr"
+)
+
+// ShowRequest selects the profile to display.
+type ShowRequest struct {
+	Relays []string
+	// PubKey is the hex public key of the profile.
+	PubKey string
+}
+
+// Service fetches and renders a single profile.
+type Service struct {
+	client   Client
+	store    Store
+	verifier Verifier
+	logger   *slog.Logger
+}
+
+// NewService creates a Service. store may be nil to skip caching and verifier
+// may be nil to skip NIP-05 checks.
+func NewService(client Client, store Store, verifier Verifier, logger *slog.Logger) *Service {
+	return &Service{client: client, store: store, verifier: verifier, logger: logger}
+}
+
+// Show fetches the newest kind 0 of req.PubKey, caches the verified events in
+// the store and writes the profile to w.
+func (s *Service) Show(ctx context.Context, req ShowRequest, w io.Writer) error {
+	if len(req.Relays) == 0 {
+		return errors.New("relay is required")
+	}
+	if req.PubKey == "" {
+		return errors.New("pubkey is required")
+	}
+
+	filter := nostr.Filter{Authors: []string{req.PubKey}, Kinds: []int{KindMetadata}}
+	events, err := s.client.FetchLatest(ctx, req.Relays, filter)
+	if err != nil {
+		return err
+	}
+
+	var (
+		latest Profile
+		found  bool
+	)
+	for _, evt := range events {
+		p, err := Parse(evt)
+		if err != nil {
+			s.logger.Debug("skip invalid metadata", "id", evt.ID, "error", err)
+			continue
+		}
+		if s.store != nil {
+			if err := s.store.Save(evt); err != nil {
+				s.logger.Debug("cache metadata failed", "id", evt.ID, "error", err)
+			}
+		}
+		if !found || p.CreatedAt.After(latest.CreatedAt) {
+			latest, found = p, true
+		}
+	}
+
+	npub, err := nostr.EncodeNpub(req.PubKey)
+	if err != nil {
+		return err
+	}
+	if !found {
+		_, err := fmt.Fprintf(w, "pubkey:  %s\nnpub:    %s\n(no metadata found)\n", req.PubKey, npub)
+		return err
+	}
+
+	nip05 := latest.NIP05
+	if nip05 != "" {
+		nip05 += " " + s.verificationStatus(ctx, latest)
+	}
+
+	fields := []struct{ label, value string }{
+		{"pubkey", req.PubKey},
+		{"npub", npub},
+		{"name", latest.Name},
+		{"display", latest.DisplayName},
+		{"nip05", nip05},
+		{"about", latest.About},
+		{"picture", latest.Picture},
+		{"website", latest.Website},
+		{"lud16", latest.LUD16},
+		{"updated", latest.CreatedAt.Local().Format("2006-01-02 15:04:05")},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		value := strings.ReplaceAll(strings.TrimSpace(f.value), "\n", "\n         ")
+		if _, err := fmt.Fprintf(w, "%-8s %s\n", f.label+":", value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) verificationStatus(ctx context.Context, p Profile) string {
+	if s.verifier == nil {
+		return "(unverified)"
+	}
+	ok, err := s.verifier.Verify(ctx, p.NIP05, p.PubKey)
+	switch {
+	case err != nil:
+		s.logger.Debug("nip05 verification failed", "nip05", p.NIP05, "error", err)
+		return "(verification failed)"
+	case ok:
+		return VerifiedBadge + " verified"
+	default:
+		return "(does not match)"
+	}
+}
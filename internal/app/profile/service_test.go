@@ -0,0 +1,60 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"noscli/internal/nostr"
+)
+
+func TestServiceShow(t *testing.T) {
+	const pubkey = "b0635d6a9851d3aed0cd6c495b282167acf761729078d975fc341b22650b07b9"
+	client := &recordingClient{
+		events: []nostr.Event{
+			{ID: "1", PubKey: pubkey, Kind: KindMetadata, CreatedAt: 1, Content: `{"name":"old"}`},
+			{ID: "2", PubKey: pubkey, Kind: KindMetadata, CreatedAt: 2, Content: `{"name":"alice","about":"line1\nline2","nip05":"alice@example.com"}`},
+		},
+		done: make(chan struct{}, 1),
+	}
+	verifier := fakeVerifier{valid: map[string]string{"alice@example.com": pubkey}}
+	store := &memoryStore{}
+	svc := NewService(client, store, verifier, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var buf bytes.Buffer
+	if err := svc.Show(context.Background(), ShowRequest{Relays: []string{"wss://relay.example"}, PubKey: pubkey}, &buf); err != nil {
+		t.Fatalf("Show() unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"name:    alice\n",
+		"nip05:   alice@example.com ✓ verified\n",
+		"about:   line1\n         line2\n",
+		"npub:    npub1",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "old") {
+		t.Fatalf("output shows outdated metadata:\n%s", out)
+	}
+	if len(store.events) != 2 {
+		t.Fatalf("stored %d metadata events, want 2", len(store.events))
+	}
+}
+
+type memoryStore struct {
+	events []nostr.Event
+}
+
+func (m *memoryStore) Save(evt nostr.Event) error {
+	m.events = append(m.events, evt)
+	return nil
+}
+
+func (m *memoryStore) Query(nostr.Filter) ([]nostr.Event, error) { return m.events, nil }
@@ -0,0 +1,477 @@
+package relay
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"noscli/internal/nostr"
+)
+
+const (
+	// maxMessageSize bounds a single client message.
+	maxMessageSize = 512 * 1024
+	// maxSubscriptions bounds the open subscriptions per connection.
+	maxSubscriptions = 20
+	// maxFilters bounds the filters in a single REQ.
+	maxFilters = 10
+	// writeTimeout bounds sending one message to a slow client.
+	writeTimeout = 10 * time.Second
+	// maxNegentropyRecords bounds the events a NEG-OPEN may reconcile.
+	maxNegentropyRecords = 500_000
+)
+
+// Store persists accepted events; it is satisfied by *storage.EventStore.
+type Store interface {
+	Save(evt nostr.Event) error
+	Get(id string) (nostr.Event, bool)
+	Query(filter nostr.Filter) ([]nostr.Event, error)
+}
+
+// Info is the NIP-11 relay information document.
+type Info struct {
+	Name          string     `json:"name,omitempty"`
+	Description   string     `json:"description,omitempty"`
+	PubKey        string     `json:"pubkey,omitempty"`
+	Contact       string     `json:"contact,omitempty"`
+	SupportedNIPs []int      `json:"supported_nips"`
+	Software      string     `json:"software,omitempty"`
+	Version       string     `json:"version,omitempty"`
+	Limitation    Limitation `json:"limitation"`
+}
+
+// Limitation is the limitation object of the NIP-11 document.
+type Limitation struct {
+	MaxMessageLength int  `json:"max_message_length"`
+	MaxSubscriptions int  `json:"max_subscriptions"`
+	MaxFilters       int  `json:"max_filters"`
+	RestrictedWrites bool `json:"restricted_writes"`
+}
+
+// ServerOptions configures a Server.
+type ServerOptions struct {
+	// Allow lists the hex pubkeys allowed to publish; empty accepts every author.
+	Allow []string
+	// Info fills the descriptive fields of the NIP-11 document.
+	Info Info
+}
+
+// Server is a small NIP-01 relay backed by a Store. It serves the NIP-11
+// document to HTTP requests asking for application/nostr+json and WebSocket
+// clients otherwise.
+type Server struct {
+	store    Store
+	allow    []string
+	info     Info
+	logger   *slog.Logger
+	upgrader websocket.Upgrader
+
+	mu    sync.Mutex
+	conns map[*serverConn]struct{}
+}
+
+// NewServer creates a Server storing events in store.
+func NewServer(store Store, opts ServerOptions, logger *slog.Logger) *Server {
+	info := opts.Info
+	info.SupportedNIPs = []int{1, 11, 77}
+	info.Limitation = Limitation{
+		MaxMessageLength: maxMessageSize,
+		MaxSubscriptions: maxSubscriptions,
+		MaxFilters:       maxFilters,
+		RestrictedWrites: len(opts.Allow) > 0,
+	}
+	return &Server{
+		store:  store,
+		allow:  opts.Allow,
+		info:   info,
+		logger: logger,
+		upgrader: websocket.Upgrader{
+			// ブラウザのクライアントからも接続できるよう Origin は制限しない
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+		conns: make(map[*serverConn]struct{}),
+	}
+}
+
+// ListenAndServe listens on addr and serves until ctx is done.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve accepts connections on ln until ctx is done. ln is closed when Serve returns.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+
+	errs := make(chan error, 1)
+	go func() { errs <- srv.Serve(ln) }()
+	s.logger.Info("relay listening", "addr", ln.Addr().String())
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// Shutdown は WebSocket のような乗っ取られた接続を待たないため個別に閉じる
+	err := srv.Shutdown(shutdownCtx)
+	s.closeAll()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		if strings.Contains(r.Header.Get("Accept"), "application/nostr+json") {
+			s.serveInfo(w)
+			return
+		}
+		http.Error(w, "this is a nostr relay; connect with a WebSocket client", http.StatusUpgradeRequired)
+		return
+	}
+
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.Debug("websocket upgrade failed", "error", err)
+		return
+	}
+	ws.SetReadLimit(maxMessageSize)
+
+	c := &serverConn{server: s, ws: ws, subs: make(map[string][]nostr.Filter), negs: make(map[string]*nostr.Negentropy)}
+	s.mu.Lock()
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+	s.logger.Debug("client connected", "remote", r.RemoteAddr)
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		ws.Close()
+		s.logger.Debug("client disconnected", "remote", r.RemoteAddr)
+	}()
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.handle(data)
+	}
+}
+
+func (s *Server) serveInfo(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/nostr+json")
+	// NIP-11 はブラウザからの取得のため CORS ヘッダーを求めている
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if err := json.NewEncoder(w).Encode(s.info); err != nil {
+		s.logger.Debug("write relay info failed", "error", err)
+	}
+}
+
+func (s *Server) connections() []*serverConn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*serverConn, 0, len(s.conns))
+	for c := range s.conns {
+		out = append(out, c)
+	}
+	return out
+}
+
+func (s *Server) closeAll() {
+	for _, c := range s.connections() {
+		c.ws.Close()
+	}
+}
+
+// accept validates evt and stores it, returning the OK flag and message.
+func (s *Server) accept(evt nostr.Event) (bool, string) {
+	if problems := evt.Validate(time.Now()); len(problems) > 0 {
+		return false, "invalid: " + problems[0].Error()
+	}
+	if err := evt.Verify(); err != nil {
+		return false, "invalid: " + err.Error()
+	}
+	if len(s.allow) > 0 && !slices.Contains(s.allow, evt.PubKey) {
+		return false, "restricted: pubkey is not allowed to publish"
+	}
+
+	if nostr.IsEphemeralKind(evt.Kind) {
+		s.broadcast(evt)
+		return true, ""
+	}
+	if _, ok := s.store.Get(evt.ID); ok {
+		return true, "duplicate: already have this event"
+	}
+	if err := s.store.Save(evt); err != nil {
+		s.logger.Warn("save event failed", "id", evt.ID, "error", err)
+		return false, "error: could not save event"
+	}
+	s.broadcast(evt)
+	return true, ""
+}
+
+// query returns the stored events for filters, newest first and without
+// superseded replaceable versions.
+func (s *Server) query(filters []nostr.Filter) ([]nostr.Event, error) {
+	seen := make(map[string]struct{})
+	var out []nostr.Event
+	for _, f := range filters {
+		if f.LimitZero {
+			continue
+		}
+		// ストアは置き換え可能イベントの旧版も保持しているため、limit は旧版を除いてから適用する
+		limit := f.Limit
+		f.Limit = 0
+		events, err := s.store.Query(f)
+		if err != nil {
+			return nil, err
+		}
+		events = nostr.LatestPerAddress(events)
+		if limit > 0 && len(events) > limit {
+			events = events[:limit]
+		}
+		for _, evt := range events {
+			if _, ok := seen[evt.ID]; ok {
+				continue
+			}
+			seen[evt.ID] = struct{}{}
+			out = append(out, evt)
+		}
+	}
+	return out, nil
+}
+
+func (s *Server) broadcast(evt nostr.Event) {
+	for _, c := range s.connections() {
+		for _, id := range c.matching(evt) {
+			c.send("EVENT", id, evt)
+		}
+	}
+}
+
+// serverConn is one client connection.
+type serverConn struct {
+	server *Server
+	ws     *websocket.Conn
+
+	writeMu sync.Mutex
+
+	mu   sync.Mutex
+	subs map[string][]nostr.Filter
+	negs map[string]*nostr.Negentropy
+}
+
+func (c *serverConn) handle(data []byte) {
+	var msg []json.RawMessage
+	if err := json.Unmarshal(data, &msg); err != nil || len(msg) == 0 {
+		c.send("NOTICE", "invalid: message is not a JSON array")
+		return
+	}
+	var msgType string
+	if err := json.Unmarshal(msg[0], &msgType); err != nil {
+		c.send("NOTICE", "invalid: message type is not a string")
+		return
+	}
+
+	switch msgType {
+	case "EVENT":
+		c.handleEvent(msg)
+	case "REQ":
+		c.handleReq(msg)
+	case "CLOSE":
+		var id string
+		if len(msg) > 1 && json.Unmarshal(msg[1], &id) == nil {
+			c.mu.Lock()
+			delete(c.subs, id)
+			c.mu.Unlock()
+		}
+	case "NEG-OPEN", "NEG-MSG", "NEG-CLOSE":
+		c.handleNegentropy(msgType, msg)
+	default:
+		c.send("NOTICE", fmt.Sprintf("unsupported message type %q", msgType))
+	}
+}
+
+func (c *serverConn) handleEvent(msg []json.RawMessage) {
+	if len(msg) < 2 {
+		c.send("NOTICE", "invalid: EVENT without event")
+		return
+	}
+	evt, err := nostr.ParseEventStrict(msg[1])
+	if err != nil {
+		// ID が読めない場合は OK を返せないため NOTICE で知らせる
+		c.send("NOTICE", "invalid: "+err.Error())
+		return
+	}
+	ok, message := c.server.accept(evt)
+	c.send("OK", evt.ID, ok, message)
+}
+
+func (c *serverConn) handleReq(msg []json.RawMessage) {
+	var id string
+	if len(msg) < 3 || json.Unmarshal(msg[1], &id) != nil || id == "" {
+		c.send("NOTICE", "invalid: REQ needs a subscription id and filters")
+		return
+	}
+	if len(msg)-2 > maxFilters {
+		c.send("CLOSED", id, fmt.Sprintf("invalid: at most %d filters", maxFilters))
+		return
+	}
+
+	filters := make([]nostr.Filter, 0, len(msg)-2)
+	for _, raw := range msg[2:] {
+		var f nostr.Filter
+		if err := json.Unmarshal(raw, &f); err != nil {
+			c.send("CLOSED", id, "invalid: "+err.Error())
+			return
+		}
+		// search (NIP-50) などの未対応フィールドは拒否せず無視する
+		f.Extra = nil
+		filters = append(filters, f)
+	}
+
+	c.mu.Lock()
+	_, replacing := c.subs[id]
+	if !replacing && len(c.subs) >= maxSubscriptions {
+		c.mu.Unlock()
+		c.send("CLOSED", id, fmt.Sprintf("rate-limited: at most %d subscriptions", maxSubscriptions))
+		return
+	}
+	c.subs[id] = filters
+	c.mu.Unlock()
+
+	events, err := c.server.query(filters)
+	if err != nil {
+		c.server.logger.Warn("query failed", "error", err)
+		c.send("CLOSED", id, "error: could not query events")
+		return
+	}
+	for _, evt := range events {
+		c.send("EVENT", id, evt)
+	}
+	c.send("EOSE", id)
+}
+
+// handleNegentropy serves NIP-77 set reconciliation over the stored events.
+func (c *serverConn) handleNegentropy(msgType string, msg []json.RawMessage) {
+	var id string
+	if len(msg) < 2 || json.Unmarshal(msg[1], &id) != nil || id == "" {
+		c.send("NOTICE", "invalid: "+msgType+" needs a subscription id")
+		return
+	}
+
+	var encoded string
+	switch msgType {
+	case "NEG-CLOSE":
+		c.mu.Lock()
+		delete(c.negs, id)
+		c.mu.Unlock()
+		return
+	case "NEG-OPEN":
+		var f nostr.Filter
+		if len(msg) < 4 || json.Unmarshal(msg[2], &f) != nil || json.Unmarshal(msg[3], &encoded) != nil {
+			c.send("NEG-ERR", id, "invalid: NEG-OPEN needs a filter and a message")
+			return
+		}
+		// 照合は件数制限なしの集合に対して行う
+		f.Limit = 0
+		events, err := c.server.query([]nostr.Filter{f})
+		if err != nil {
+			c.server.logger.Warn("query failed", "error", err)
+			c.send("NEG-ERR", id, "error: could not query events")
+			return
+		}
+		if len(events) > maxNegentropyRecords {
+			c.send("NEG-ERR", id, fmt.Sprintf("blocked: more than %d events match", maxNegentropyRecords))
+			return
+		}
+		neg, err := nostr.NewNegentropy(events, nostr.NegentropyFrameLimit)
+		if err != nil {
+			c.send("NEG-ERR", id, "error: "+err.Error())
+			return
+		}
+		c.mu.Lock()
+		_, replacing := c.negs[id]
+		if !replacing && len(c.negs) >= maxSubscriptions {
+			c.mu.Unlock()
+			c.send("NEG-ERR", id, fmt.Sprintf("blocked: at most %d reconciliations", maxSubscriptions))
+			return
+		}
+		c.negs[id] = neg
+		c.mu.Unlock()
+	case "NEG-MSG":
+		if len(msg) < 3 || json.Unmarshal(msg[2], &encoded) != nil {
+			c.send("NEG-ERR", id, "invalid: NEG-MSG needs a message")
+			return
+		}
+	}
+
+	c.mu.Lock()
+	neg, ok := c.negs[id]
+	c.mu.Unlock()
+	if !ok {
+		c.send("NEG-ERR", id, "closed: unknown subscription")
+		return
+	}
+	query, err := hex.DecodeString(encoded)
+	if err != nil {
+		c.send("NEG-ERR", id, "invalid: message is not hex")
+		return
+	}
+	// 同じ接続のメッセージは 1 つのゴルーチンで順に処理されるため neg への並行アクセスはない
+	reply, err := neg.Respond(query)
+	if err != nil {
+		c.mu.Lock()
+		delete(c.negs, id)
+		c.mu.Unlock()
+		c.send("NEG-ERR", id, "invalid: "+err.Error())
+		return
+	}
+	c.send("NEG-MSG", id, hex.EncodeToString(reply))
+}
+
+// matching returns the subscriptions whose filters match evt.
+func (c *serverConn) matching(evt nostr.Event) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ids []string
+	for id, filters := range c.subs {
+		for _, f := range filters {
+			if f.Matches(evt) {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// send writes a relay message. Write errors are ignored; the read loop
+// notices the broken connection.
+func (c *serverConn) send(msgType string, args ...any) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_ = c.ws.WriteJSON(append([]any{msgType}, args...))
+}
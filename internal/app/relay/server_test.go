@@ -0,0 +1,287 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"noscli/internal/nostr"
+	"noscli/internal/storage"
+)
+
+const kindMetadata = 0
+
+var (
+	ownerKey    = bytes.Repeat([]byte{0x03}, 32)
+	strangerKey = bytes.Repeat([]byte{0x04}, 32)
+)
+
+func startServer(t *testing.T, opts ServerOptions) (*Server, string) {
+	t.Helper()
+	store, err := storage.OpenEventStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenEventStore() error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	server := NewServer(store, opts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ts := httptest.NewServer(server)
+	t.Cleanup(func() {
+		server.closeAll()
+		ts.Close()
+	})
+	return server, "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+func pubKey(t *testing.T, key []byte) string {
+	t.Helper()
+	pub, err := nostr.PublicKey(key)
+	if err != nil {
+		t.Fatalf("PublicKey() error: %v", err)
+	}
+	return pub
+}
+
+func signed(t *testing.T, key []byte, kind int, content string, createdAt int64) nostr.Event {
+	t.Helper()
+	evt := nostr.Event{PubKey: pubKey(t, key), CreatedAt: createdAt, Kind: kind, Tags: [][]string{}, Content: content}
+	if err := nostr.SignEvent(&evt, key); err != nil {
+		t.Fatalf("SignEvent() error: %v", err)
+	}
+	return evt
+}
+
+func TestServerPublishAndQuery(t *testing.T) {
+	_, url := startServer(t, ServerOptions{})
+	client := nostr.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+	now := time.Now().Unix()
+
+	note := signed(t, ownerKey, nostr.KindTextNote, "hello", now-10)
+	oldMeta := signed(t, ownerKey, kindMetadata, `{"name":"old"}`, now-5)
+	newMeta := signed(t, ownerKey, kindMetadata, `{"name":"new"}`, now)
+	for _, evt := range []nostr.Event{note, oldMeta, newMeta, note} {
+		if err := client.Publish(ctx, url, evt); err != nil {
+			t.Fatalf("Publish(%q) error: %v", evt.Content, err)
+		}
+	}
+
+	forged := signed(t, ownerKey, nostr.KindTextNote, "original", now)
+	forged.Content = "tampered"
+	err := client.Publish(ctx, url, forged)
+	var rejected *nostr.RejectedError
+	if !errors.As(err, &rejected) || rejected.Reason() != "invalid" {
+		t.Fatalf("Publish(forged) error = %v, want invalid rejection", err)
+	}
+
+	events, err := client.Fetch(ctx, url, nostr.Filter{Authors: []string{note.PubKey}})
+	if err != nil {
+		t.Fatalf("Fetch() error: %v", err)
+	}
+	var ids []string
+	for _, evt := range events {
+		ids = append(ids, evt.ID)
+	}
+	// The older metadata is superseded and the duplicate note stored once.
+	if len(ids) != 2 || ids[0] != newMeta.ID || ids[1] != note.ID {
+		t.Fatalf("Fetch() = %v, want [%s %s]", ids, newMeta.ID, note.ID)
+	}
+}
+
+func TestServerLimitCountsCurrentVersions(t *testing.T) {
+	_, url := startServer(t, ServerOptions{})
+	client := nostr.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+	now := time.Now().Unix()
+
+	var owner nostr.Event
+	for i := range 3 {
+		owner = signed(t, ownerKey, kindMetadata, fmt.Sprintf(`{"name":"v%d"}`, i), now-3+int64(i))
+		if err := client.Publish(ctx, url, owner); err != nil {
+			t.Fatalf("Publish(%q) error: %v", owner.Content, err)
+		}
+	}
+	stranger := signed(t, strangerKey, kindMetadata, `{"name":"stranger"}`, now-10)
+	if err := client.Publish(ctx, url, stranger); err != nil {
+		t.Fatalf("Publish(stranger) error: %v", err)
+	}
+
+	events, err := client.Fetch(ctx, url, nostr.Filter{Kinds: []int{kindMetadata}, Limit: 2})
+	if err != nil {
+		t.Fatalf("Fetch() error: %v", err)
+	}
+	// Older versions of the owner's profile must not crowd out the stranger's.
+	if len(events) != 2 || events[0].ID != owner.ID || events[1].ID != stranger.ID {
+		t.Fatalf("Fetch() = %+v, want the current profile of both authors", events)
+	}
+}
+
+func TestServerIgnoresUnknownFilterFields(t *testing.T) {
+	_, url := startServer(t, ServerOptions{})
+	client := nostr.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	note := signed(t, ownerKey, nostr.KindTextNote, "hello", time.Now().Unix())
+	if err := client.Publish(ctx, url, note); err != nil {
+		t.Fatalf("Publish() error: %v", err)
+	}
+
+	search := nostr.Filter{Kinds: []int{nostr.KindTextNote}, Extra: map[string]json.RawMessage{"search": json.RawMessage(`"hello"`)}}
+	events, err := client.Fetch(ctx, url, search)
+	if err != nil {
+		t.Fatalf("Fetch(search) error: %v", err)
+	}
+	if len(events) != 1 || events[0].ID != note.ID {
+		t.Fatalf("Fetch(search) = %+v, want the note", events)
+	}
+
+	events, err = client.Fetch(ctx, url, nostr.Filter{Kinds: []int{nostr.KindTextNote}, LimitZero: true})
+	if err != nil {
+		t.Fatalf("Fetch(limit 0) error: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("Fetch(limit 0) = %+v, want no stored events", events)
+	}
+}
+
+func TestServerAllowlist(t *testing.T) {
+	_, url := startServer(t, ServerOptions{Allow: []string{pubKey(t, ownerKey)}})
+	client := nostr.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+	now := time.Now().Unix()
+
+	if err := client.Publish(ctx, url, signed(t, ownerKey, nostr.KindTextNote, "mine", now)); err != nil {
+		t.Fatalf("Publish(owner) error: %v", err)
+	}
+	err := client.Publish(ctx, url, signed(t, strangerKey, nostr.KindTextNote, "spam", now))
+	var rejected *nostr.RejectedError
+	if !errors.As(err, &rejected) || rejected.Reason() != "restricted" {
+		t.Fatalf("Publish(stranger) error = %v, want restricted rejection", err)
+	}
+
+	events, err := client.Fetch(ctx, url, nostr.Filter{})
+	if err != nil {
+		t.Fatalf("Fetch() error: %v", err)
+	}
+	if len(events) != 1 || events[0].Content != "mine" {
+		t.Fatalf("Fetch() = %+v, want only the owner's note", events)
+	}
+}
+
+func TestServerBroadcastsToSubscriptions(t *testing.T) {
+	_, url := startServer(t, ServerOptions{})
+	client := nostr.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eose := make(chan struct{}, 1)
+	events, _ := client.Subscribe(ctx, url, nostr.Filter{Kinds: []int{nostr.KindTextNote, 20001}}, func(msg nostr.RelayMessage) {
+		if msg.Type == nostr.MessageEOSE {
+			eose <- struct{}{}
+		}
+	})
+	select {
+	case <-eose:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for EOSE")
+	}
+
+	now := time.Now().Unix()
+	for _, evt := range []nostr.Event{
+		signed(t, ownerKey, kindMetadata, "{}", now),
+		signed(t, ownerKey, 20001, "ephemeral", now),
+		signed(t, ownerKey, nostr.KindTextNote, "live", now),
+	} {
+		if err := client.Publish(context.Background(), url, evt); err != nil {
+			t.Fatalf("Publish(%q) error: %v", evt.Content, err)
+		}
+	}
+
+	for _, want := range []string{"ephemeral", "live"} {
+		select {
+		case evt := <-events:
+			if evt.Content != want {
+				t.Fatalf("received %q, want %q", evt.Content, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+
+	// Ephemeral events are delivered but not stored.
+	stored, err := client.Fetch(context.Background(), url, nostr.Filter{Kinds: []int{20001}})
+	if err != nil {
+		t.Fatalf("Fetch() error: %v", err)
+	}
+	if len(stored) != 0 {
+		t.Fatalf("ephemeral event was stored: %+v", stored)
+	}
+}
+
+func TestServerNegentropy(t *testing.T) {
+	_, url := startServer(t, ServerOptions{})
+	client := nostr.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+	now := time.Now().Unix()
+
+	shared := signed(t, ownerKey, nostr.KindTextNote, "shared", now-2)
+	remote := signed(t, ownerKey, nostr.KindTextNote, "remote", now-1)
+	local := signed(t, ownerKey, nostr.KindTextNote, "local", now)
+	for _, evt := range []nostr.Event{shared, remote} {
+		if err := client.Publish(ctx, url, evt); err != nil {
+			t.Fatalf("Publish(%q) error: %v", evt.Content, err)
+		}
+	}
+
+	missing, err := client.FetchMissing(ctx, url, nostr.Filter{Kinds: []int{nostr.KindTextNote}}, []nostr.Event{shared, local})
+	if err != nil {
+		t.Fatalf("FetchMissing() error: %v", err)
+	}
+	if len(missing) != 1 || missing[0].ID != remote.ID {
+		t.Fatalf("FetchMissing() = %+v, want only the remote note", missing)
+	}
+
+	diff, err := client.Reconcile(ctx, url, nostr.Filter{Kinds: []int{nostr.KindTextNote}}, []nostr.Event{shared, local})
+	if err != nil {
+		t.Fatalf("Reconcile() error: %v", err)
+	}
+	if len(diff.Have) != 1 || diff.Have[0] != local.ID {
+		t.Fatalf("Reconcile().Have = %v, want the local note", diff.Have)
+	}
+}
+
+func TestServerInfoDocument(t *testing.T) {
+	server, _ := startServer(t, ServerOptions{Allow: []string{"pk"}, Info: Info{Name: "local"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/nostr+json")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/nostr+json" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	var info Info
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatalf("decode info: %v", err)
+	}
+	if info.Name != "local" || len(info.SupportedNIPs) != 3 || !info.Limitation.RestrictedWrites {
+		t.Fatalf("info = %+v", info)
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUpgradeRequired {
+		t.Fatalf("plain GET status = %d, want %d", rec.Code, http.StatusUpgradeRequired)
+	}
+}
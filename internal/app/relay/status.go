@@ -0,0 +1,100 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+// ErrUnreachable is returned by Status when at least one relay failed its probe.
+var ErrUnreachable = errors.New("some relays are unreachable")
+
+// defaultProbeTimeout bounds a single probe when the request sets no Timeout.
+const defaultProbeTimeout = 10 * time.Second
+
+// StatusRequest lists the relays to probe.
+type StatusRequest struct {
+	Relays []string
+	// Timeout bounds each probe; zero uses defaultProbeTimeout.
+	Timeout time.Duration
+}
+
+// Client exposes the subset of nostr client functionality needed by the relay service.
+type Client interface {
+	Probe(ctx context.Context, relay string) (nostr.RelayStats, error)
+}
+
+// Service reports relay health.
+type Service struct {
+	client Client
+	logger *slog.Logger
+}
+
+// NewService creates a Service that relies on the given nostr client.
+func NewService(client Client, logger *slog.Logger) *Service {
+	return &Service{client: client, logger: logger}
+}
+
+type probeResult struct {
+	stats nostr.RelayStats
+	err   error
+}
+
+// Status probes every relay in parallel and writes one line per relay, in
+// request order, to w.
+func (s *Service) Status(ctx context.Context, req StatusRequest, w io.Writer) error {
+	if len(req.Relays) == 0 {
+		return errors.New("relay is required")
+	}
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+
+	results := make([]probeResult, len(req.Relays))
+	done := make(chan struct{})
+	for i, relay := range req.Relays {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			probeCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			stats, err := s.client.Probe(probeCtx, relay)
+			stats.Relay = relay
+			results[i] = probeResult{stats: stats, err: err}
+		}()
+	}
+	for range req.Relays {
+		<-done
+	}
+
+	unreachable := 0
+	for _, res := range results {
+		if res.err != nil {
+			unreachable++
+			s.logger.Debug("relay probe failed", "relay", res.stats.Relay, "error", res.err)
+		}
+		if err := writeStatus(w, res); err != nil {
+			return err
+		}
+	}
+	if unreachable > 0 {
+		return ErrUnreachable
+	}
+	return nil
+}
+
+func writeStatus(w io.Writer, res probeResult) error {
+	st := res.stats
+	if res.err != nil {
+		_, err := fmt.Fprintf(w, "%-6s %s state:%s error:%v\n", "failed", st.Relay, st.State, res.err)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%-6s %s state:%s latency:%s events:%d invalid:%d reconnects:%d\n",
+		"ok", st.Relay, st.State, st.Latency.Round(time.Millisecond), st.Events, st.Invalid, st.Reconnects)
+	return err
+}
@@ -0,0 +1,55 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+type fakeProber map[string]error
+
+func (f fakeProber) Probe(ctx context.Context, relay string) (nostr.RelayStats, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return nostr.RelayStats{}, errors.New("probe without timeout")
+	}
+	if err := f[relay]; err != nil {
+		return nostr.RelayStats{Relay: relay, State: nostr.StateFailed}, err
+	}
+	return nostr.RelayStats{Relay: relay, State: nostr.StateClosed, Latency: 42 * time.Millisecond, Events: 1}, nil
+}
+
+func TestServiceStatus(t *testing.T) {
+	client := fakeProber{"wss://down": errors.New("dial wss://down: refused")}
+	svc := NewService(client, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var buf bytes.Buffer
+	err := svc.Status(context.Background(), StatusRequest{Relays: []string{"wss://up", "wss://down"}}, &buf)
+	if !errors.Is(err, ErrUnreachable) {
+		t.Fatalf("Status() error = %v, want ErrUnreachable", err)
+	}
+
+	want := "ok     wss://up state:closed latency:42ms events:1 invalid:0 reconnects:0\n" +
+		"failed wss://down state:failed error:dial wss://down: refused\n"
+	if buf.String() != want {
+		t.Fatalf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestServiceStatusAllReachable(t *testing.T) {
+	svc := NewService(fakeProber{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var buf bytes.Buffer
+	if err := svc.Status(context.Background(), StatusRequest{Relays: []string{"wss://a", "wss://b"}}, &buf); err != nil {
+		t.Fatalf("Status() unexpected error: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+}
@@ -0,0 +1,99 @@
+package req
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"noscli/internal/nostr"
+)
+
+// Request describes an arbitrary REQ to send to a relay.
+type Request struct {
+	Relay  string
+	Filter nostr.Filter
+	// Stream keeps the subscription open after EOSE until interrupted.
+	Stream bool
+}
+
+// Client exposes the subset of nostr client functionality needed by the req service.
+type Client interface {
+	Req(ctx context.Context, relay string, filter nostr.Filter, closeOnEOSE bool, onMessage func(nostr.RelayMessage)) (<-chan nostr.Event, <-chan error)
+}
+
+// Service sends raw subscriptions for debugging relays.
+type Service struct {
+	client Client
+	logger *slog.Logger
+}
+
+// NewService creates a Service that relies on the given nostr client.
+func NewService(client Client, logger *slog.Logger) *Service {
+	return &Service{client: client, logger: logger}
+}
+
+// Run sends req.Filter and writes each received event as one JSON line to w.
+// EOSE, CLOSED and NOTICE messages are written to status.
+func (s *Service) Run(ctx context.Context, req Request, w, status io.Writer) error {
+	if req.Relay == "" {
+		return errors.New("relay is required")
+	}
+
+	if b, err := json.Marshal(req.Filter); err == nil {
+		s.logger.Debug("sending REQ", "relay", req.Relay, "filter", string(b))
+	}
+
+	onMessage := func(msg nostr.RelayMessage) {
+		line := fmt.Sprintf("%s %s", msg.Type, msg.Relay)
+		if msg.Message != "" {
+			line += ": " + msg.Message
+		}
+		fmt.Fprintln(status, line)
+	}
+
+	events, errs := s.client.Req(ctx, req.Relay, req.Filter, !req.Stream, onMessage)
+
+	// エラー受信後も、バッファ済みのイベントを出力し切ってから返す
+	var runErr error
+	for events != nil || errs != nil {
+		select {
+		case <-ctx.Done():
+			return nil
+		case evt, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if err := writeRaw(w, evt); err != nil {
+				return err
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			runErr = err
+		}
+	}
+	return runErr
+}
+
+// writeRaw writes the event exactly as the relay sent it.
+func writeRaw(w io.Writer, evt nostr.Event) error {
+	raw := []byte(evt.Raw)
+	if len(raw) == 0 {
+		b, err := json.Marshal(evt)
+		if err != nil {
+			return err
+		}
+		raw = b
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
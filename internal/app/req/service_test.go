@@ -0,0 +1,103 @@
+package req
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"noscli/internal/nostr"
+)
+
+type scriptedClient struct {
+	events      []nostr.Event
+	messages    []nostr.RelayMessage
+	err         error
+	closeOnEOSE bool
+}
+
+func (c *scriptedClient) Req(_ context.Context, _ string, _ nostr.Filter, closeOnEOSE bool, onMessage func(nostr.RelayMessage)) (<-chan nostr.Event, <-chan error) {
+	c.closeOnEOSE = closeOnEOSE
+	events := make(chan nostr.Event, len(c.events))
+	errs := make(chan error, 1)
+	for _, evt := range c.events {
+		events <- evt
+	}
+	for _, msg := range c.messages {
+		onMessage(msg)
+	}
+	if c.err != nil {
+		errs <- c.err
+	}
+	close(events)
+	close(errs)
+	return events, errs
+}
+
+func TestServiceRun(t *testing.T) {
+	raw := `{"id":"abc","pubkey":"def","created_at":1,"kind":1,"tags":[],"content":"hi","sig":"00"}`
+	closedErr := errors.New("relay wss://relay.example: subscription closed by relay: auth-required: sign in")
+
+	tests := []struct {
+		name       string
+		client     *scriptedClient
+		stream     bool
+		wantOut    string
+		wantStatus []string
+		wantErr    bool
+	}{
+		{
+			name: "events then eose",
+			client: &scriptedClient{
+				events:   []nostr.Event{{ID: "abc", Raw: json.RawMessage(raw)}},
+				messages: []nostr.RelayMessage{{Relay: "wss://relay.example", Type: nostr.MessageEOSE}},
+			},
+			wantOut:    raw + "\n",
+			wantStatus: []string{"EOSE wss://relay.example\n"},
+		},
+		{
+			name: "closed is reported and returned after buffered events",
+			client: &scriptedClient{
+				events: []nostr.Event{{ID: "abc", Raw: json.RawMessage(raw)}},
+				messages: []nostr.RelayMessage{
+					{Relay: "wss://relay.example", Type: nostr.MessageNotice, Message: "slow down"},
+					{Relay: "wss://relay.example", Type: nostr.MessageClosed, Message: "auth-required: sign in"},
+				},
+				err: closedErr,
+			},
+			stream:  true,
+			wantOut: raw + "\n",
+			wantStatus: []string{
+				"NOTICE wss://relay.example: slow down\n",
+				"CLOSED wss://relay.example: auth-required: sign in\n",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(tt.client, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			var out, status bytes.Buffer
+			err := svc.Run(context.Background(), Request{Relay: "wss://relay.example", Stream: tt.stream}, &out, &status)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.client.closeOnEOSE == tt.stream {
+				t.Fatalf("closeOnEOSE = %v for stream = %v", tt.client.closeOnEOSE, tt.stream)
+			}
+			if out.String() != tt.wantOut {
+				t.Fatalf("stdout = %q, want %q", out.String(), tt.wantOut)
+			}
+			for _, want := range tt.wantStatus {
+				if !strings.Contains(status.String(), want) {
+					t.Fatalf("stderr %q does not contain %q", status.String(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package timeline
+
+// ItemType identifies what an Item carries.
+type ItemType int
+
+const (
+	// ItemEvent carries a visible note in Entry.
+	ItemEvent ItemType = iota + 1
+	// ItemRelayStatus reports a connection change of Relay in Status; Err holds
+	// the cause when the relay was disconnected by an error.
+	ItemRelayStatus
+	// ItemError carries a stream error in Err. The stream keeps running.
+	ItemError
+	// ItemEOSE marks that Relay has sent all stored events. Offline streams
+	// send it once after the cached events.
+	ItemEOSE
+)
+
+// RelayStatus is the connection state reported by ItemRelayStatus.
+type RelayStatus string
+
+const (
+	RelayConnected    RelayStatus = "connected"
+	RelayDisconnected RelayStatus = "disconnected"
+)
+
+// Item is a single update from Service.Stream.
+type Item struct {
+	Type   ItemType
+	Relay  string
+	Entry  Entry
+	Status RelayStatus
+	Err    error
+}
@@ -0,0 +1,174 @@
+package timeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+// Output formats accepted by NewRenderer.
+const (
+	OutputPlain = "plain"
+	OutputJSONL = "jsonl"
+	OutputRaw   = "raw"
+)
+
+// Entry is a timeline event together with what is known about its author.
+// Templates can use the event fields directly, e.g. {{.Content}}.
+type Entry struct {
+	nostr.Event
+	// Author is the author's profile label, or truncated hex when unknown.
+	Author string
+}
+
+// Renderer writes timeline entries to an output stream.
+type Renderer interface {
+	Render(w io.Writer, entry Entry) error
+}
+
+// NewRenderer returns the renderer for output, or a template renderer when
+// format is set. showSensitive only affects the plain renderer; machine
+// readable outputs always carry the full content.
+func NewRenderer(output, format string, showSensitive bool) (Renderer, error) {
+	if format != "" {
+		if output != "" && output != OutputPlain {
+			return nil, fmt.Errorf("format cannot be combined with output %q", output)
+		}
+		return NewTemplateRenderer(format)
+	}
+	switch output {
+	case "", OutputPlain:
+		return PlainRenderer{ShowSensitive: showSensitive}, nil
+	case OutputJSONL:
+		return JSONLRenderer{}, nil
+	case OutputRaw:
+		return RawRenderer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown output %q (want %s, %s or %s)", output, OutputPlain, OutputJSONL, OutputRaw)
+	}
+}
+
+// PlainRenderer writes one human readable line per event.
+type PlainRenderer struct {
+	// ShowSensitive renders content of NIP-36 content-warning events instead of a placeholder.
+	ShowSensitive bool
+}
+
+// Render implements Renderer.
+func (r PlainRenderer) Render(w io.Writer, entry Entry) error {
+	evt := entry.Event
+	ts := time.Unix(evt.CreatedAt, 0).Local().Format("2006-01-02 15:04:05")
+	author := entry.Author
+	if author == "" {
+		author = truncateHex(evt.PubKey)
+	}
+	summary := sanitizeContent(evt.Content)
+	if reason, ok := evt.ContentWarning(); ok && !r.ShowSensitive {
+		summary = contentWarningPlaceholder(reason)
+	}
+	prefixForPreview := evt.ID
+	if len(prefixForPreview) > 8 {
+		prefixForPreview = prefixForPreview[:8]
+	}
+	_, err := fmt.Fprintf(w, "[%s] %s: %s (id:%s relay:%s)\n", ts, author, summary, prefixForPreview, evt.Relay)
+	return err
+}
+
+// JSONLRenderer writes the full signed event plus the relay it came from as
+// one JSON object per line.
+type JSONLRenderer struct{}
+
+type jsonlEvent struct {
+	nostr.Event
+	Relay string `json:"relay,omitempty"`
+}
+
+// Render implements Renderer.
+func (JSONLRenderer) Render(w io.Writer, entry Entry) error {
+	b, err := json.Marshal(jsonlEvent{Event: entry.Event, Relay: entry.Relay})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// RawRenderer writes the NIP-01 event JSON as received from the relay. Events
+// without the original bytes, such as cached ones, are re-encoded.
+type RawRenderer struct{}
+
+// Render implements Renderer.
+func (RawRenderer) Render(w io.Writer, entry Entry) error {
+	raw := []byte(entry.Raw)
+	if len(raw) == 0 {
+		b, err := json.Marshal(entry.Event)
+		if err != nil {
+			return err
+		}
+		raw = b
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// TemplateRenderer executes a text/template for each entry.
+type TemplateRenderer struct {
+	tmpl *template.Template
+}
+
+// NewTemplateRenderer parses format. A trailing newline is added when the
+// template does not end with one, so each event stays on its own line.
+func NewTemplateRenderer(format string) (*TemplateRenderer, error) {
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	tmpl, err := template.New("timeline").Funcs(template.FuncMap{
+		"time": func(unix int64) string {
+			return time.Unix(unix, 0).Local().Format("2006-01-02 15:04:05")
+		},
+		"short":   truncateHex,
+		"oneline": sanitizeContent,
+	}).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("parse format: %w", err)
+	}
+	return &TemplateRenderer{tmpl: tmpl}, nil
+}
+
+// Render implements Renderer.
+func (r *TemplateRenderer) Render(w io.Writer, entry Entry) error {
+	return r.tmpl.Execute(w, entry)
+}
+
+func contentWarningPlaceholder(reason string) string {
+	reason = sanitizeContent(reason)
+	if reason == "(no content)" {
+		return "[content warning] (hidden, use --show-sensitive)"
+	}
+	return fmt.Sprintf("[content warning: %s] (hidden, use --show-sensitive)", reason)
+}
+
+func truncateHex(in string) string {
+	if len(in) <= 12 {
+		return in
+	}
+	return fmt.Sprintf("%s...%s", in[:6], in[len(in)-4:])
+}
+
+func sanitizeContent(in string) string {
+	trimmed := strings.TrimSpace(in)
+	if trimmed == "" {
+		return "(no content)"
+	}
+	trimmed = strings.ReplaceAll(trimmed, "\n", " ")
+	trimmed = strings.ReplaceAll(trimmed, "\r", " ")
+	return trimmed
+}
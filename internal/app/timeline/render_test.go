@@ -0,0 +1,102 @@
+package timeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"noscli/internal/nostr"
+)
+
+func TestRenderers(t *testing.T) {
+	raw := json.RawMessage(`{"id":"abc","pubkey":"def","created_at":1700000000,"kind":1,"tags":[["content-warning","spoiler"]],"content":"hello\nworld","sig":"00",  "extra":true}`)
+	evt := nostr.Event{
+		ID:        "abc",
+		PubKey:    "def",
+		CreatedAt: 1700000000,
+		Kind:      1,
+		Tags:      [][]string{{"content-warning", "spoiler"}},
+		Content:   "hello\nworld",
+		Sig:       "00",
+		Relay:     "wss://relay.example",
+		Raw:       raw,
+	}
+	entry := Entry{Event: evt, Author: "Alice (@alice)"}
+
+	tests := []struct {
+		name   string
+		output string
+		format string
+		want   []string
+	}{
+		{
+			name:   "plain hides sensitive content",
+			output: OutputPlain,
+			want:   []string{"Alice (@alice): [content warning: spoiler]", "relay:wss://relay.example"},
+		},
+		{
+			name:   "jsonl",
+			output: OutputJSONL,
+			want:   []string{`"sig":"00"`, `"relay":"wss://relay.example"`, `"content":"hello\nworld"`},
+		},
+		{
+			name:   "raw",
+			output: OutputRaw,
+			want:   []string{string(raw) + "\n"},
+		},
+		{
+			name:   "template",
+			format: "{{.CreatedAt}} {{.Author}} {{oneline .Content}}",
+			want:   []string{"1700000000 Alice (@alice) hello world\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRenderer(tt.output, tt.format, false)
+			if err != nil {
+				t.Fatalf("NewRenderer() unexpected error: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := r.Render(&buf, entry); err != nil {
+				t.Fatalf("Render() unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Fatalf("output %q does not contain %q", buf.String(), want)
+				}
+			}
+			if strings.Count(buf.String(), "\n") != 1 {
+				t.Fatalf("expected a single line, got %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestRawRendererWithoutOriginalBytes(t *testing.T) {
+	evt := nostr.Event{ID: "abc", PubKey: "def", CreatedAt: 1, Kind: 1, Tags: [][]string{}, Sig: "00", Relay: "wss://relay.example"}
+	var buf bytes.Buffer
+	if err := (RawRenderer{}).Render(&buf, Entry{Event: evt}); err != nil {
+		t.Fatalf("Render() unexpected error: %v", err)
+	}
+	var decoded nostr.Event
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not an event: %v", err)
+	}
+	if decoded.ID != "abc" || strings.Contains(buf.String(), "relay") {
+		t.Fatalf("unexpected raw output %q", buf.String())
+	}
+}
+
+func TestNewRendererErrors(t *testing.T) {
+	if _, err := NewRenderer("xml", "", false); err == nil {
+		t.Fatalf("NewRenderer() expected error for unknown output")
+	}
+	if _, err := NewRenderer(OutputJSONL, "{{.ID}}", false); err == nil {
+		t.Fatalf("NewRenderer() expected error when combining format and jsonl")
+	}
+	if _, err := NewRenderer("", "{{.ID", false); err == nil {
+		t.Fatalf("NewRenderer() expected error for invalid template")
+	}
+}
@@ -0,0 +1,79 @@
+package timeline
+
+import (
+	"sort"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+// reorderBuffer holds streamed events for up to a window so that events
+// arriving slightly out of order, e.g. from relays with different latency,
+// are emitted by created_at.
+type reorderBuffer struct {
+	window  time.Duration
+	pending []pendingEvent
+}
+
+type pendingEvent struct {
+	evt     nostr.Event
+	arrived time.Time
+}
+
+func newReorderBuffer(window time.Duration) *reorderBuffer {
+	return &reorderBuffer{window: window}
+}
+
+// add buffers evt, keeping pending sorted by created_at.
+func (b *reorderBuffer) add(evt nostr.Event, now time.Time) {
+	i := sort.Search(len(b.pending), func(i int) bool {
+		return createdBefore(evt, b.pending[i].evt)
+	})
+	b.pending = append(b.pending, pendingEvent{})
+	copy(b.pending[i+1:], b.pending[i:])
+	b.pending[i] = pendingEvent{evt: evt, arrived: now}
+}
+
+// due removes and returns, oldest first, every event held for the full
+// window together with any older event, so no event is held longer than the
+// window and output stays ordered.
+func (b *reorderBuffer) due(now time.Time) []nostr.Event {
+	last := -1
+	for i, p := range b.pending {
+		if !p.arrived.Add(b.window).After(now) {
+			last = i
+		}
+	}
+	return b.take(last + 1)
+}
+
+// flush removes and returns all buffered events, oldest first.
+func (b *reorderBuffer) flush() []nostr.Event {
+	return b.take(len(b.pending))
+}
+
+func (b *reorderBuffer) take(n int) []nostr.Event {
+	if n == 0 {
+		return nil
+	}
+	out := make([]nostr.Event, n)
+	for i := range out {
+		out[i] = b.pending[i].evt
+	}
+	b.pending = append(b.pending[:0], b.pending[n:]...)
+	return out
+}
+
+// sortEvents orders events oldest first, breaking ties by ID like the store.
+func sortEvents(events []nostr.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return createdBefore(events[i], events[j])
+	})
+}
+
+func createdBefore(a, b nostr.Event) bool {
+	if a.CreatedAt != b.CreatedAt {
+		return a.CreatedAt < b.CreatedAt
+	}
+	return a.ID < b.ID
+}
@@ -0,0 +1,45 @@
+package timeline
+
+import (
+	"testing"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+func ids(events []nostr.Event) []string {
+	out := make([]string, len(events))
+	for i, evt := range events {
+		out[i] = evt.ID
+	}
+	return out
+}
+
+func TestReorderBuffer(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	b := newReorderBuffer(2 * time.Second)
+
+	b.add(nostr.Event{ID: "c", CreatedAt: 30}, start)
+	b.add(nostr.Event{ID: "a", CreatedAt: 10}, start.Add(time.Second))
+	b.add(nostr.Event{ID: "d", CreatedAt: 40}, start.Add(1500*time.Millisecond))
+
+	if got := b.due(start.Add(time.Second)); len(got) != 0 {
+		t.Fatalf("due before window = %v, want none", ids(got))
+	}
+
+	// c has waited the full window; a is older, so it goes first even though
+	// it arrived later. d is newer and keeps waiting.
+	got := ids(b.due(start.Add(2 * time.Second)))
+	if len(got) != 2 || got[0] != "a" || got[1] != "c" {
+		t.Fatalf("due() = %v, want [a c]", got)
+	}
+
+	b.add(nostr.Event{ID: "b", CreatedAt: 40}, start.Add(3*time.Second))
+	got = ids(b.flush())
+	if len(got) != 2 || got[0] != "b" || got[1] != "d" {
+		t.Fatalf("flush() = %v, want [b d] ordered by created_at then id", got)
+	}
+	if got := b.flush(); len(got) != 0 {
+		t.Fatalf("flush() after flush = %v", ids(got))
+	}
+}
@@ -3,107 +3,419 @@ package timeline
 import (
 	"context"
 	"errors"
-	"fmt"
 	"io"
 	"log/slog"
-	"strings"
+	"sync"
 	"time"
 
+	"noscli/internal/app/profile"
 	"noscli/internal/nostr"
 )
 
 // Request represents timeline filters and rendering options.
 type Request struct {
 	Relays []string
+	// ShowSensitive renders content of NIP-36 content-warning events instead of a placeholder.
+	ShowSensitive bool
+	// MinPoW drops events whose NIP-13 difficulty is below this many bits.
+	MinPoW int
+	// Offline renders cached events from the store instead of connecting to relays.
+	Offline bool
+	// Limit bounds the number of cached events shown in offline mode.
+	Limit int
+	// Refresh, in offline mode, first fetches the text notes of this long ago
+	// onwards that the store lacks from Relays. Zero stays off the network.
+	Refresh time.Duration
+	// Output selects the renderer: plain (default), jsonl or raw.
+	Output string
+	// Format is a Go text/template executed for each event instead of the plain output.
+	Format string
+	// ReorderWindow holds live events for up to this long to emit them by
+	// created_at. Zero emits them as they arrive. Stored events sent before
+	// EOSE are always sorted.
+	ReorderWindow time.Duration
+	// MaxFutureSkew drops events dated further than this into the future. Zero disables the check.
+	MaxFutureSkew time.Duration
 }
 
+// defaultOfflineLimit is used when an offline request does not set Limit.
+const defaultOfflineLimit = 50
+
+// seenCapacity bounds how many event IDs are remembered to drop an event
+// delivered by more than one relay.
+const seenCapacity = 4096
+
 // Client exposes the subset of nostr client functionality needed by the timeline service.
 type Client interface {
-	Stream(ctx context.Context, relay string, filter nostr.Filter) (<-chan nostr.Event, <-chan error)
+	Subscribe(ctx context.Context, relay string, filter nostr.Filter, onMessage func(nostr.RelayMessage)) (<-chan nostr.Event, <-chan error)
+	FetchMissing(ctx context.Context, relay string, filter nostr.Filter, local []nostr.Event) ([]nostr.Event, error)
+}
+
+// Store caches received events for offline reading.
+type Store interface {
+	Save(evt nostr.Event) error
+	Query(filter nostr.Filter) ([]nostr.Event, error)
+}
+
+// Profiles resolves author metadata for display. Lookup must not block.
+type Profiles interface {
+	Lookup(pubkey string) (profile.Profile, bool)
 }
 
 // Service fetches and renders timeline events.
 type Service struct {
-	client Client
-	logger *slog.Logger
+	client   Client
+	store    Store
+	profiles Profiles
+	logger   *slog.Logger
 }
 
 // NewService creates a Service that relies on the given nostr client.
-func NewService(client Client, logger *slog.Logger) *Service {
-	return &Service{client: client, logger: logger}
+// store may be nil, in which case nothing is cached and offline mode is unavailable.
+// profiles may be nil, in which case authors are shown as truncated hex.
+func NewService(client Client, store Store, profiles Profiles, logger *slog.Logger) *Service {
+	return &Service{client: client, store: store, profiles: profiles, logger: logger}
 }
 
 // Run executes the timeline request and writes results to w.
 func (s *Service) Run(ctx context.Context, req Request, w io.Writer) error {
-	if len(req.Relays) == 0 {
-		return errors.New("relay is required")
+	renderer, err := NewRenderer(req.Output, req.Format, req.ShowSensitive)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	items, err := s.Stream(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case item, ok := <-items:
+			if !ok {
+				return nil
+			}
+			switch item.Type {
+			case ItemEvent:
+				if err := renderer.Render(w, item.Entry); err != nil {
+					return err
+				}
+			case ItemError:
+				if errors.Is(item.Err, nostr.ErrMaxAttempts) {
+					return item.Err
+				}
+				s.logger.Warn("timeline stream error", "error", item.Err)
+			}
+		}
 	}
+}
 
-	// 単一リレーのみを処理する。将来的に複数リレー対応時はここでルーティングを追加する。
-	relay := req.Relays[0]
+// Stream executes the timeline request and returns its updates. Every relay
+// in req.Relays is subscribed and an event delivered by several of them is
+// emitted once. The channel is closed once every subscription has ended
+// after ctx is done, or after the cached events in offline mode.
+func (s *Service) Stream(ctx context.Context, req Request) (<-chan Item, error) {
+	if req.Offline {
+		return s.streamOffline(ctx, req)
+	}
+	if len(req.Relays) == 0 {
+		return nil, errors.New("relay is required")
+	}
 
 	filter := nostr.Filter{
 		Kinds: []int{nostr.KindTextNote},
 	}
 
-	events, errs := s.client.Stream(ctx, relay, filter)
+	updates := make(chan update)
+	var wg sync.WaitGroup
+	for _, relay := range req.Relays {
+		// onMessage は購読側のゴルーチンから呼ばれるため、イベントとの順序を保つよう同じループで転送する
+		msgs := make(chan nostr.RelayMessage)
+		events, errs := s.client.Subscribe(ctx, relay, filter, func(msg nostr.RelayMessage) {
+			select {
+			case msgs <- msg:
+			case <-ctx.Done():
+			}
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			forward(ctx, relay, events, errs, msgs, updates)
+		}()
+	}
+	go func() {
+		// onMessage は events が閉じられるまで呼ばれ得るため、全リレーの転送が終わるまで updates を閉じない
+		wg.Wait()
+		close(updates)
+	}()
+
+	items := make(chan Item, 16)
+	go func() {
+		defer close(items)
+		s.pump(ctx, req, updates, items)
+	}()
 
-	for {
+	return items, nil
+}
+
+// update is an event, relay message or error from one relay's subscription.
+type update struct {
+	relay string
+	evt   *nostr.Event
+	msg   *nostr.RelayMessage
+	err   error
+}
+
+// forward passes one relay's subscription output to updates in the order it
+// was received, until both events and errs are closed.
+func forward(ctx context.Context, relay string, events <-chan nostr.Event, errs <-chan error, msgs <-chan nostr.RelayMessage, updates chan<- update) {
+	send := func(u update) {
 		select {
+		case updates <- u:
 		case <-ctx.Done():
-			return nil
+		}
+	}
+	for events != nil || errs != nil {
+		select {
 		case evt, ok := <-events:
 			if !ok {
 				events = nil
-				if errs == nil {
-					return nil
-				}
 				continue
 			}
-			if err := renderPlainEvent(w, evt); err != nil {
-				return err
-			}
+			send(update{relay: relay, evt: &evt})
+		case msg := <-msgs:
+			send(update{relay: relay, msg: &msg})
 		case err, ok := <-errs:
 			if !ok {
 				errs = nil
-				if events == nil {
-					return nil
-				}
 				continue
 			}
-			if err == nil || errors.Is(err, context.Canceled) {
+			send(update{relay: relay, err: err})
+		}
+	}
+}
+
+// pump turns subscription updates into items. Events received while a relay
+// has not yet sent EOSE are stored events and are emitted sorted once every
+// connected relay has sent it; later events go through the reorder buffer
+// when req.ReorderWindow is set.
+func (s *Service) pump(ctx context.Context, req Request, updates <-chan update, items chan<- Item) {
+	send := func(item Item) {
+		select {
+		case items <- item:
+		case <-ctx.Done():
+		}
+	}
+	emit := func(events []nostr.Event) {
+		for _, evt := range events {
+			send(Item{Type: ItemEvent, Relay: evt.Relay, Entry: s.entry(evt)})
+		}
+	}
+
+	var (
+		stored  []nostr.Event
+		live    bool
+		reorder *reorderBuffer
+		tick    <-chan time.Time
+		// pending は EOSE を待っているリレー
+		pending = make(map[string]struct{})
+		seen    = nostr.NewSeenSet(seenCapacity)
+	)
+	if req.ReorderWindow > 0 {
+		reorder = newReorderBuffer(req.ReorderWindow)
+		ticker := time.NewTicker(max(req.ReorderWindow/4, 10*time.Millisecond))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	endStored := func(relay string) {
+		delete(pending, relay)
+		if len(pending) > 0 {
+			return
+		}
+		sortEvents(stored)
+		emit(stored)
+		stored = nil
+		live = true
+	}
+
+	for updates != nil {
+		select {
+		case u, ok := <-updates:
+			if !ok {
+				updates = nil
 				continue
 			}
-			s.logger.Warn("timeline stream error", "error", err)
+			switch {
+			case u.evt != nil:
+				evt := *u.evt
+				if !seen.Add(evt.ID) {
+					continue
+				}
+				s.cache(evt)
+				if !s.visible(evt, req) {
+					continue
+				}
+				switch {
+				case !live:
+					stored = append(stored, evt)
+				case reorder != nil:
+					reorder.add(evt, time.Now())
+				default:
+					emit([]nostr.Event{evt})
+				}
+			case u.msg != nil:
+				msg := *u.msg
+				switch msg.Type {
+				case nostr.MessageConnected:
+					// 再接続後は再び保存済みイベントから届く
+					pending[u.relay] = struct{}{}
+					live = false
+					send(Item{Type: ItemRelayStatus, Relay: msg.Relay, Status: RelayConnected})
+				case nostr.MessageDisconnected:
+					endStored(u.relay)
+					item := Item{Type: ItemRelayStatus, Relay: msg.Relay, Status: RelayDisconnected}
+					if msg.Message != "" {
+						item.Err = errors.New(msg.Message)
+					}
+					send(item)
+				case nostr.MessageEOSE:
+					endStored(u.relay)
+					send(Item{Type: ItemEOSE, Relay: msg.Relay})
+				}
+			case u.err != nil && !errors.Is(u.err, context.Canceled):
+				send(Item{Type: ItemError, Relay: u.relay, Err: u.err})
+			}
+		case <-tick:
+			emit(reorder.due(time.Now()))
 		}
 	}
+
+	clear(pending)
+	endStored("")
+	if reorder != nil {
+		emit(reorder.flush())
+	}
 }
 
-func renderPlainEvent(w io.Writer, evt nostr.Event) error {
-	ts := time.Unix(evt.CreatedAt, 0).Local().Format("2006-01-02 15:04:05")
-	author := truncateHex(evt.PubKey)
-	summary := sanitizeContent(evt.Content)
-	prefixForPreview := evt.ID
-	if len(prefixForPreview) > 8 {
-		prefixForPreview = prefixForPreview[:8]
+// streamOffline emits cached text notes, oldest first, without touching the
+// network unless req.Refresh is set.
+func (s *Service) streamOffline(ctx context.Context, req Request) (<-chan Item, error) {
+	if s.store == nil {
+		return nil, errors.New("offline mode requires the local event store")
+	}
+	if req.Refresh > 0 {
+		if err := s.refresh(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultOfflineLimit
+	}
+	events, err := s.store.Query(nostr.Filter{Kinds: []int{nostr.KindTextNote}, Limit: limit})
+	if err != nil {
+		return nil, err
 	}
-	_, err := fmt.Fprintf(w, "[%s] %s: %s (id:%s relay:%s)\n", ts, author, summary, prefixForPreview, evt.Relay)
-	return err
+	sortEvents(events)
+
+	items := make(chan Item, 16)
+	go func() {
+		defer close(items)
+		for _, evt := range events {
+			if !s.visible(evt, req) {
+				continue
+			}
+			select {
+			case items <- Item{Type: ItemEvent, Relay: evt.Relay, Entry: s.entry(evt)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case items <- Item{Type: ItemEOSE}:
+		case <-ctx.Done():
+		}
+	}()
+	return items, nil
 }
 
-func truncateHex(in string) string {
-	if len(in) <= 12 {
-		return in
+// refresh caches the recent text notes on req.Relays that the store lacks.
+// NIP-77 relays send only those; others fall back to a plain REQ. An
+// unreachable relay is logged so the cache can still be read.
+func (s *Service) refresh(ctx context.Context, req Request) error {
+	since := time.Now().Add(-req.Refresh)
+	filter := nostr.Filter{Kinds: []int{nostr.KindTextNote}, Since: &since}
+	local, err := s.store.Query(filter)
+	if err != nil {
+		return err
+	}
+	for _, relay := range req.Relays {
+		missing, err := s.client.FetchMissing(ctx, relay, filter, local)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.logger.Warn("refresh cache failed", "relay", relay, "error", err)
+			continue
+		}
+		for _, evt := range missing {
+			s.cache(evt)
+		}
+		local = append(local, missing...)
+		s.logger.Info("refreshed cache", "relay", relay, "events", len(missing))
 	}
-	return fmt.Sprintf("%s...%s", in[:6], in[len(in)-4:])
+	return nil
 }
 
-func sanitizeContent(in string) string {
-	trimmed := strings.TrimSpace(in)
-	if trimmed == "" {
-		return "(no content)"
+// visible applies the request's expiration, future-skew and proof-of-work rules.
+func (s *Service) visible(evt nostr.Event, req Request) bool {
+	now := time.Now()
+	if evt.IsExpired(now) {
+		s.logger.Debug("drop expired event", "relay", evt.Relay, "id", evt.ID)
+		return false
+	}
+	if req.MaxFutureSkew > 0 && evt.CreatedAt > now.Add(req.MaxFutureSkew).Unix() {
+		s.logger.Debug("drop event from the future", "relay", evt.Relay, "id", evt.ID, "created_at", evt.CreatedAt)
+		return false
+	}
+	if req.MinPoW > 0 && evt.PoWDifficulty() < req.MinPoW {
+		s.logger.Debug("drop event below pow threshold", "relay", evt.Relay, "id", evt.ID)
+		return false
+	}
+	return true
+}
+
+// cache stores a verified event, logging instead of failing the stream on errors.
+func (s *Service) cache(evt nostr.Event) {
+	if s.store == nil {
+		return
+	}
+	if err := s.store.Save(evt); err != nil {
+		s.logger.Debug("cache event failed", "id", evt.ID, "error", err)
+	}
+}
+
+// entry prepares evt for rendering.
+func (s *Service) entry(evt nostr.Event) Entry {
+	return Entry{Event: evt, Author: s.authorLabel(evt.PubKey)}
+}
+
+// authorLabel renders the author's profile name when known, otherwise truncated hex.
+func (s *Service) authorLabel(pubkey string) string {
+	if s.profiles != nil {
+		if p, ok := s.profiles.Lookup(pubkey); ok {
+			if label := p.Label(); label != "" {
+				if p.Verified {
+					label += " " + profile.VerifiedBadge
+				}
+				return label
+			}
+		}
 	}
-	trimmed = strings.ReplaceAll(trimmed, "\n", " ")
-	trimmed = strings.ReplaceAll(trimmed, "\r", " ")
-	return trimmed
+	return truncateHex(pubkey)
 }
@@ -0,0 +1,349 @@
+package timeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"noscli/internal/app/profile"
+	"noscli/internal/nostr"
+)
+
+type unusedClient struct{}
+
+func (unusedClient) Subscribe(context.Context, string, nostr.Filter, func(nostr.RelayMessage)) (<-chan nostr.Event, <-chan error) {
+	panic("offline mode must not touch the network")
+}
+
+func (unusedClient) FetchMissing(context.Context, string, nostr.Filter, []nostr.Event) ([]nostr.Event, error) {
+	panic("offline mode must not touch the network")
+}
+
+type memoryStore struct {
+	events []nostr.Event
+	filter nostr.Filter
+}
+
+func (m *memoryStore) Save(evt nostr.Event) error {
+	m.events = append(m.events, evt)
+	return nil
+}
+
+func (m *memoryStore) Query(filter nostr.Filter) ([]nostr.Event, error) {
+	m.filter = filter
+	return m.events, nil
+}
+
+type staticProfiles map[string]profile.Profile
+
+func (p staticProfiles) Lookup(pubkey string) (profile.Profile, bool) {
+	prof, ok := p[pubkey]
+	return prof, ok
+}
+
+func TestServiceRunOffline(t *testing.T) {
+	past := time.Now().Add(-time.Hour).Unix()
+	store := &memoryStore{
+		// Query returns newest first.
+		events: []nostr.Event{
+			{ID: "newer", PubKey: "alice", CreatedAt: past + 2, Kind: 1, Content: "second"},
+			{ID: "expired", PubKey: "alice", CreatedAt: past + 1, Kind: 1, Content: "gone", Tags: [][]string{{"expiration", "1"}}},
+			{ID: "older", PubKey: "bob", CreatedAt: past, Kind: 1, Content: "first", Tags: [][]string{{"content-warning", "spoiler"}}},
+		},
+	}
+	profiles := staticProfiles{"bob": {PubKey: "bob", Name: "bob", DisplayName: "Bob", Verified: true}}
+	svc := NewService(unusedClient{}, store, profiles, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var buf bytes.Buffer
+	if err := svc.Run(context.Background(), Request{Offline: true, Limit: 10}, &buf); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	if store.filter.Limit != 10 || len(store.filter.Kinds) != 1 || store.filter.Kinds[0] != nostr.KindTextNote {
+		t.Fatalf("unexpected store filter: %+v", store.filter)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	if !strings.Contains(lines[0], "Bob (@bob) ✓: ") {
+		t.Fatalf("first line = %q, want profile label", lines[0])
+	}
+	if !strings.Contains(lines[0], "[content warning: spoiler]") || !strings.Contains(lines[0], "id:older") {
+		t.Fatalf("first line = %q, want hidden older note", lines[0])
+	}
+	if !strings.Contains(lines[1], "alice: second") {
+		t.Fatalf("second line = %q, want newer note", lines[1])
+	}
+}
+
+// refreshClient serves relay as the text notes a relay holds.
+type refreshClient struct {
+	unusedClient
+	relay  []nostr.Event
+	filter nostr.Filter
+}
+
+func (c *refreshClient) FetchMissing(_ context.Context, _ string, filter nostr.Filter, local []nostr.Event) ([]nostr.Event, error) {
+	c.filter = filter
+	known := make(map[string]bool)
+	for _, evt := range local {
+		known[evt.ID] = true
+	}
+	var out []nostr.Event
+	for _, evt := range c.relay {
+		if !known[evt.ID] {
+			out = append(out, evt)
+		}
+	}
+	return out, nil
+}
+
+func TestServiceRunOfflineRefresh(t *testing.T) {
+	past := time.Now().Add(-time.Minute).Unix()
+	cached := nostr.Event{ID: "cached", PubKey: "alice", CreatedAt: past, Kind: 1, Content: "cached"}
+	fresh := nostr.Event{ID: "fresh", PubKey: "bob", CreatedAt: past + 1, Kind: 1, Content: "fresh"}
+	store := &memoryStore{events: []nostr.Event{cached}}
+	client := &refreshClient{relay: []nostr.Event{cached, fresh}}
+	svc := NewService(client, store, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var buf bytes.Buffer
+	req := Request{Relays: []string{"wss://relay"}, Offline: true, Refresh: time.Hour}
+	if err := svc.Run(context.Background(), req, &buf); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	if client.filter.Since == nil || time.Since(*client.filter.Since) < time.Hour-time.Minute {
+		t.Fatalf("refresh filter since = %v, want an hour ago", client.filter.Since)
+	}
+	if len(store.events) != 2 || store.events[1].ID != "fresh" {
+		t.Fatalf("store events = %+v, want the fresh note cached", store.events)
+	}
+	if !strings.Contains(buf.String(), "bob: fresh") {
+		t.Fatalf("output = %q, want the fresh note", buf.String())
+	}
+}
+
+func TestServiceRunOfflineWithoutStore(t *testing.T) {
+	svc := NewService(unusedClient{}, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := svc.Run(context.Background(), Request{Offline: true}, io.Discard); err == nil {
+		t.Fatalf("Run() expected error without store")
+	}
+}
+
+type scriptedClient struct {
+	events []nostr.Event
+	err    error
+}
+
+func (scriptedClient) FetchMissing(context.Context, string, nostr.Filter, []nostr.Event) ([]nostr.Event, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c scriptedClient) Subscribe(_ context.Context, relay string, _ nostr.Filter, onMessage func(nostr.RelayMessage)) (<-chan nostr.Event, <-chan error) {
+	events := make(chan nostr.Event)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(events)
+		defer close(errs)
+		onMessage(nostr.RelayMessage{Relay: relay, Type: nostr.MessageConnected})
+		onMessage(nostr.RelayMessage{Relay: relay, Type: nostr.MessageEOSE})
+		for _, evt := range c.events {
+			evt.Relay = relay
+			events <- evt
+		}
+		errs <- c.err
+		onMessage(nostr.RelayMessage{Relay: relay, Type: nostr.MessageDisconnected, Message: c.err.Error()})
+	}()
+	return events, errs
+}
+
+func TestServiceStreamItems(t *testing.T) {
+	past := time.Now().Add(-time.Minute).Unix()
+	client := scriptedClient{
+		events: []nostr.Event{
+			{ID: "a", PubKey: "alice", CreatedAt: past, Kind: 1, Content: "hello"},
+			{ID: "b", PubKey: "alice", CreatedAt: past, Kind: 1, Tags: [][]string{{"expiration", "1"}}},
+		},
+		err: errors.New("read: connection reset"),
+	}
+	store := &memoryStore{}
+	svc := NewService(client, store, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	items, err := svc.Stream(context.Background(), Request{Relays: []string{"wss://relay"}})
+	if err != nil {
+		t.Fatalf("Stream() unexpected error: %v", err)
+	}
+
+	got := make(map[ItemType][]Item)
+	for item := range items {
+		got[item.Type] = append(got[item.Type], item)
+	}
+
+	if len(got[ItemEvent]) != 1 || got[ItemEvent][0].Entry.ID != "a" || got[ItemEvent][0].Relay != "wss://relay" {
+		t.Fatalf("event items = %+v, want only the unexpired note", got[ItemEvent])
+	}
+	if len(store.events) != 2 {
+		t.Fatalf("expected both events cached, got %d", len(store.events))
+	}
+	if len(got[ItemEOSE]) != 1 {
+		t.Fatalf("expected one EOSE item, got %+v", got[ItemEOSE])
+	}
+	if len(got[ItemError]) != 1 || got[ItemError][0].Err != client.err {
+		t.Fatalf("error items = %+v", got[ItemError])
+	}
+	status := got[ItemRelayStatus]
+	if len(status) != 2 || status[0].Status != RelayConnected || status[1].Status != RelayDisconnected || status[1].Err == nil {
+		t.Fatalf("relay status items = %+v", status)
+	}
+}
+
+type storedThenLiveClient struct {
+	stored []nostr.Event
+	live   []nostr.Event
+}
+
+func (storedThenLiveClient) FetchMissing(context.Context, string, nostr.Filter, []nostr.Event) ([]nostr.Event, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c storedThenLiveClient) Subscribe(ctx context.Context, relay string, _ nostr.Filter, onMessage func(nostr.RelayMessage)) (<-chan nostr.Event, <-chan error) {
+	events := make(chan nostr.Event)
+	errs := make(chan error)
+	go func() {
+		defer close(events)
+		defer close(errs)
+		onMessage(nostr.RelayMessage{Relay: relay, Type: nostr.MessageConnected})
+		for _, evt := range c.stored {
+			events <- evt
+		}
+		onMessage(nostr.RelayMessage{Relay: relay, Type: nostr.MessageEOSE})
+		for _, evt := range c.live {
+			events <- evt
+		}
+		<-ctx.Done()
+	}()
+	return events, errs
+}
+
+func TestServiceStreamOrdersEvents(t *testing.T) {
+	now := time.Now().Unix()
+	client := storedThenLiveClient{
+		stored: []nostr.Event{
+			{ID: "s2", CreatedAt: now - 20, Kind: 1},
+			{ID: "s1", CreatedAt: now - 30, Kind: 1},
+			{ID: "future", CreatedAt: now + 3600, Kind: 1},
+		},
+		live: []nostr.Event{
+			{ID: "l2", CreatedAt: now - 1, Kind: 1},
+			{ID: "l1", CreatedAt: now - 2, Kind: 1},
+		},
+	}
+	svc := NewService(client, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	items, err := svc.Stream(ctx, Request{
+		Relays:        []string{"wss://relay"},
+		ReorderWindow: 50 * time.Millisecond,
+		MaxFutureSkew: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Stream() unexpected error: %v", err)
+	}
+
+	var got []string
+	for item := range items {
+		switch item.Type {
+		case ItemEvent:
+			got = append(got, item.Entry.ID)
+		case ItemEOSE:
+			got = append(got, "EOSE")
+		}
+		if len(got) == 5 {
+			cancel()
+		}
+	}
+
+	want := []string{"s1", "s2", "EOSE", "l1", "l2"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("items = %v, want %v", got, want)
+	}
+}
+
+// relayClient serves each relay's stored events followed by EOSE, then stays
+// connected until ctx is done.
+type relayClient map[string][]nostr.Event
+
+func (relayClient) FetchMissing(context.Context, string, nostr.Filter, []nostr.Event) ([]nostr.Event, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c relayClient) Subscribe(ctx context.Context, relay string, _ nostr.Filter, onMessage func(nostr.RelayMessage)) (<-chan nostr.Event, <-chan error) {
+	events := make(chan nostr.Event)
+	errs := make(chan error)
+	go func() {
+		defer close(events)
+		defer close(errs)
+		onMessage(nostr.RelayMessage{Relay: relay, Type: nostr.MessageConnected})
+		for _, evt := range c[relay] {
+			evt.Relay = relay
+			events <- evt
+		}
+		onMessage(nostr.RelayMessage{Relay: relay, Type: nostr.MessageEOSE})
+		<-ctx.Done()
+	}()
+	return events, errs
+}
+
+func TestServiceStreamMergesRelays(t *testing.T) {
+	now := time.Now().Unix()
+	shared := nostr.Event{ID: "shared", CreatedAt: now - 20, Kind: 1}
+	client := relayClient{
+		"wss://a": {{ID: "a1", CreatedAt: now - 10, Kind: 1}, shared},
+		"wss://b": {shared, {ID: "b1", CreatedAt: now - 30, Kind: 1}},
+	}
+	svc := NewService(client, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	items, err := svc.Stream(ctx, Request{Relays: []string{"wss://a", "wss://b"}})
+	if err != nil {
+		t.Fatalf("Stream() unexpected error: %v", err)
+	}
+
+	var (
+		got       []string
+		connected = make(map[string]bool)
+		eose      int
+	)
+	for item := range items {
+		switch item.Type {
+		case ItemEvent:
+			got = append(got, item.Entry.ID)
+		case ItemRelayStatus:
+			connected[item.Relay] = item.Status == RelayConnected
+		case ItemEOSE:
+			eose++
+		}
+		if eose == 2 && len(got) == 3 {
+			cancel()
+		}
+	}
+
+	// The note stored on both relays is emitted once.
+	sort.Strings(got)
+	if strings.Join(got, ",") != "a1,b1,shared" {
+		t.Fatalf("events = %v, want a1, b1 and shared once", got)
+	}
+	if !connected["wss://a"] || !connected["wss://b"] {
+		t.Fatalf("relay status = %v, want both relays reported", connected)
+	}
+}
@@ -0,0 +1,132 @@
+package verify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+// ErrInvalidEvents is returned by Run when at least one event failed validation.
+var ErrInvalidEvents = errors.New("invalid events found")
+
+// Request describes an event dump to validate.
+type Request struct {
+	// Input holds one JSON event per line. Blank lines are skipped.
+	Input io.Reader
+	// OnlyInvalid suppresses the per-event lines of valid events.
+	OnlyInvalid bool
+}
+
+// Result is the outcome of validating one input line.
+type Result struct {
+	Line     int
+	ID       string
+	Problems []string
+}
+
+// Valid reports whether the event passed every check.
+func (r Result) Valid() bool {
+	return len(r.Problems) == 0
+}
+
+// Service validates events without contacting relays.
+type Service struct {
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// NewService creates a Service.
+func NewService(logger *slog.Logger) *Service {
+	return &Service{logger: logger, now: time.Now}
+}
+
+// Run checks each event read from req.Input, writes one result line per event
+// and a summary to w, and returns ErrInvalidEvents if any event was invalid.
+func (s *Service) Run(ctx context.Context, req Request, w io.Writer) error {
+	if req.Input == nil {
+		return errors.New("input is required")
+	}
+
+	reader := bufio.NewReader(req.Input)
+	var total, invalid int
+	for lineNo := 1; ; lineNo++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// 大きなイベント (kind 3 など) もあるため Scanner の行長制限を避ける
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return readErr
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			res := s.check(lineNo, line)
+			total++
+			if !res.Valid() {
+				invalid++
+			}
+			if !res.Valid() || !req.OnlyInvalid {
+				if err := renderResult(w, res); err != nil {
+					return err
+				}
+			}
+		}
+		if readErr != nil {
+			break
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "checked %d events: %d valid, %d invalid\n", total, total-invalid, invalid); err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return ErrInvalidEvents
+	}
+	return nil
+}
+
+// check runs the structural checks and, when the event could be decoded,
+// the ID and signature verification.
+func (s *Service) check(lineNo int, line []byte) Result {
+	res := Result{Line: lineNo}
+
+	evt, err := nostr.ParseEventStrict(line)
+	if err != nil {
+		res.Problems = append(res.Problems, err.Error())
+		return res
+	}
+	res.ID = evt.ID
+
+	for _, problem := range evt.Validate(s.now()) {
+		res.Problems = append(res.Problems, problem.Error())
+	}
+	if err := evt.Verify(); err != nil {
+		res.Problems = append(res.Problems, err.Error())
+	}
+	return res
+}
+
+func renderResult(w io.Writer, res Result) error {
+	id := res.ID
+	if len(id) > 16 {
+		id = id[:16]
+	}
+	if id == "" {
+		id = "-"
+	}
+	if res.Valid() {
+		_, err := fmt.Fprintf(w, "ok      line:%d id:%s\n", res.Line, id)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "invalid line:%d id:%s: %s\n", res.Line, id, strings.Join(res.Problems, "; "))
+	return err
+}
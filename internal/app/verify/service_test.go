@@ -0,0 +1,81 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"noscli/internal/nostr"
+)
+
+func signedLine(t *testing.T, content string) (string, nostr.Event) {
+	t.Helper()
+	priv := bytes.Repeat([]byte{0x05}, 32)
+	pub, err := nostr.PublicKey(priv)
+	if err != nil {
+		t.Fatalf("PublicKey: %v", err)
+	}
+	evt := nostr.Event{PubKey: pub, CreatedAt: 1_700_000_000, Kind: 1, Tags: [][]string{}, Content: content}
+	if err := nostr.SignEvent(&evt, priv); err != nil {
+		t.Fatalf("SignEvent: %v", err)
+	}
+	b, err := json.Marshal(evt)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return string(b), evt
+}
+
+func TestServiceRun(t *testing.T) {
+	good, _ := signedLine(t, "a <b> & c")
+	_, tampered := signedLine(t, "original")
+	tampered.Content = "changed"
+	bad, err := json.Marshal(tampered)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	input := strings.Join([]string{good, "", string(bad), `{"id":"x"}`}, "\n")
+	svc := NewService(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var buf bytes.Buffer
+	err = svc.Run(context.Background(), Request{Input: strings.NewReader(input)}, &buf)
+	if !errors.Is(err, ErrInvalidEvents) {
+		t.Fatalf("Run() error = %v, want ErrInvalidEvents", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 3 results and a summary, got:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "ok      line:1 ") {
+		t.Fatalf("line 1 = %q, want ok", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "invalid line:3 ") || !strings.Contains(lines[1], "event id mismatch") {
+		t.Fatalf("line 3 = %q, want id mismatch", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "invalid line:4 id:-: missing field") {
+		t.Fatalf("line 4 = %q, want missing field", lines[2])
+	}
+	if lines[3] != "checked 3 events: 1 valid, 2 invalid" {
+		t.Fatalf("summary = %q", lines[3])
+	}
+}
+
+func TestServiceRunOnlyInvalid(t *testing.T) {
+	good, _ := signedLine(t, "fine")
+	svc := NewService(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var buf bytes.Buffer
+	if err := svc.Run(context.Background(), Request{Input: strings.NewReader(good + "\n"), OnlyInvalid: true}, &buf); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if buf.String() != "checked 1 events: 1 valid, 0 invalid\n" {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"noscli/internal/app/article"
+	"noscli/internal/app/profile"
+	"noscli/internal/nostr"
+	"noscli/internal/storage"
+)
+
+type articleOptions struct {
+	relays     []string
+	identifier string
+	dryRun     bool
+	noColor    bool
+}
+
+func newArticleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "article",
+		Short: "長文記事 (NIP-23) を投稿・表示する",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		newArticlePublishCommand(),
+		newArticleShowCommand(),
+	)
+
+	return cmd
+}
+
+func newArticlePublishCommand() *cobra.Command {
+	opts := &articleOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "publish <file.md>",
+		Short: "Markdown ファイルを長文記事として投稿する",
+		Long: `フロントマター (title, summary, image, published_at, tags) 付きの Markdown を kind 30023 のイベントとして投稿します。
+d タグはファイル名から生成されるため、同じファイルを再投稿すると既存の記事が更新されます。`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := getLogger()
+
+			src, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("記事ファイルを読み込めません: %w", err)
+			}
+
+			identifier := opts.identifier
+			if identifier == "" {
+				identifier = article.IdentifierFromPath(args[0])
+			}
+			if identifier == "" {
+				return errors.New("ファイル名から識別子を決められません (--identifier で指定してください)")
+			}
+
+			relays := opts.relays
+			if len(relays) == 0 {
+				relays = loadConfig().Relays
+			}
+			if len(relays) == 0 && !opts.dryRun {
+				return errors.New("リレーが指定されていません (--relay または NOSCLI_RELAYS)")
+			}
+
+			req := article.PublishRequest{
+				Relays:     relays,
+				Identifier: identifier,
+				Markdown:   string(src),
+				DryRun:     opts.dryRun,
+			}
+
+			svc := article.NewService(nostr.NewClient(logger), nil, logger)
+			return svc.Publish(commandContext(cmd), req, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&opts.relays, "relay", nil, "リレー URL (複数指定可、未指定時は NOSCLI_RELAYS)")
+	cmd.Flags().StringVar(&opts.identifier, "identifier", "", "d タグ (未指定時はファイル名から生成)")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "送信せず署名済みイベントを出力する")
+
+	return cmd
+}
+
+func newArticleShowCommand() *cobra.Command {
+	opts := &articleOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "show <naddr>",
+		Short: "長文記事を取得して Markdown を整形表示する",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := getLogger()
+
+			pointer, err := nostr.DecodeNaddr(args[0])
+			if err != nil {
+				return fmt.Errorf("naddr を解析できません: %w", err)
+			}
+
+			relays := opts.relays
+			if len(relays) == 0 {
+				relays = loadConfig().Relays
+			}
+
+			req := article.ShowRequest{
+				Relays:  relays,
+				Pointer: pointer,
+				Color:   !opts.noColor && isTerminal(cmd.OutOrStdout()),
+			}
+
+			var store profile.Store
+			eventStore, err := storage.OpenEventStore(loadConfig().DataDir)
+			if err != nil {
+				logger.Warn("event store unavailable, caching disabled", "error", err)
+			} else {
+				defer eventStore.Close()
+				store = eventStore
+			}
+
+			// 著者のプロフィールは記事と同じく naddr のリレーヒントからも探す
+			profileRelays := slices.Clone(pointer.Relays)
+			for _, relay := range relays {
+				if !slices.Contains(profileRelays, relay) {
+					profileRelays = append(profileRelays, relay)
+				}
+			}
+			client := nostr.NewClient(logger)
+			resolver := profile.NewResolver(client, store, nil, profileRelays, profile.NewCache(profile.DefaultTTL), logger)
+			svc := article.NewService(client, resolver, logger)
+			return svc.Show(commandContext(cmd), req, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&opts.relays, "relay", nil, "リレー URL (複数指定可、naddr のリレーヒントに追加)")
+	cmd.Flags().BoolVar(&opts.noColor, "no-color", false, "装飾を付けずに表示する")
+
+	return cmd
+}
+
+// isTerminal reports whether w is a character device and NO_COLOR is unset.
+func isTerminal(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
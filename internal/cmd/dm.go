@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"noscli/internal/app/dm"
+	"noscli/internal/nostr"
+)
+
+type dmOptions struct {
+	relay   string
+	message string
+	with    string
+	limit   int
+	nip04   bool
+}
+
+func newDMCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dm",
+		Short: "暗号化ダイレクトメッセージを送受信する",
+		Long:  "NIP-17 (NIP-44 暗号化 + NIP-59 gift wrap) によるダイレクトメッセージを扱います。",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		newDMSendCommand(),
+		newDMListCommand(),
+		newDMWatchCommand(),
+	)
+
+	return cmd
+}
+
+func newDMSendCommand() *cobra.Command {
+	opts := &dmOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "send <npub|nip05>",
+		Short: "ダイレクトメッセージを送信する",
+		Long:  "kind 14 のメッセージを kind 13 で封印し、kind 1059 の gift wrap として宛先と自分宛てに送信します。メッセージは -m または標準入力から指定します。",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := getLogger()
+
+			relay, err := dmRelay(opts)
+			if err != nil {
+				return err
+			}
+
+			recipient, hints, err := resolvePubKey(commandContext(cmd), args[0])
+			if err != nil {
+				return err
+			}
+
+			content := strings.TrimSpace(opts.message)
+			if content == "" {
+				b, err := io.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					return err
+				}
+				content = strings.TrimSpace(string(b))
+			}
+			if content == "" {
+				return errors.New("メッセージが空です (-m または標準入力で指定してください)")
+			}
+
+			req := dm.SendRequest{
+				Relay:      relay,
+				Recipient:  recipient,
+				RelayHints: hints,
+				Content:    content,
+				Legacy:     opts.nip04,
+			}
+
+			svc := dm.NewService(nostr.NewClient(logger), logger)
+			return svc.Send(commandContext(cmd), req, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.relay, "relay", "", "リレー URL")
+	cmd.Flags().StringVarP(&opts.message, "message", "m", "", "送信するメッセージ本文")
+	cmd.Flags().BoolVar(&opts.nip04, "nip04", false, "NIP-17 非対応の相手向けに旧形式 (NIP-04, kind 4) で送信する")
+
+	return cmd
+}
+
+func newDMListCommand() *cobra.Command {
+	opts := &dmOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "受信済みのダイレクトメッセージを一覧表示する",
+		Long:  "NIP-17 のメッセージに加え、旧形式 (NIP-04) のメッセージも復号して [legacy NIP-04] ラベル付きで表示します。",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := getLogger()
+
+			relay, err := dmRelay(opts)
+			if err != nil {
+				return err
+			}
+
+			req := dm.ListRequest{
+				Relay: relay,
+				Limit: opts.limit,
+			}
+			if opts.with != "" {
+				with, _, err := resolvePubKey(commandContext(cmd), opts.with)
+				if err != nil {
+					return err
+				}
+				req.With = with
+			}
+
+			svc := dm.NewService(nostr.NewClient(logger), logger)
+			return svc.List(commandContext(cmd), req, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.relay, "relay", "", "リレー URL")
+	cmd.Flags().StringVar(&opts.with, "with", "", "指定した相手 (npub, hex または NIP-05 識別子) との会話のみ表示する")
+	cmd.Flags().IntVar(&opts.limit, "limit", 100, "取得する gift wrap の最大件数")
+
+	return cmd
+}
+
+func newDMWatchCommand() *cobra.Command {
+	opts := &dmOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "ダイレクトメッセージをストリーム表示する",
+		Long:  "Ctrl+C などで中断するまで、新着のダイレクトメッセージを受信し続けます。",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := getLogger()
+
+			relay, err := dmRelay(opts)
+			if err != nil {
+				return err
+			}
+
+			svc := dm.NewService(nostr.NewClient(logger), logger)
+			return svc.Watch(commandContext(cmd), dm.WatchRequest{Relay: relay}, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.relay, "relay", "", "リレー URL")
+
+	return cmd
+}
+
+func dmRelay(opts *dmOptions) (string, error) {
+	relay := opts.relay
+	if relay == "" {
+		relay = loadConfig().Timeline.Relay
+	}
+	if strings.TrimSpace(relay) == "" {
+		return "", errors.New("リレーが指定されていません (--relay または NOSCLI_RELAY)")
+	}
+	return relay, nil
+}
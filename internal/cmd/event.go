@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"noscli/internal/app/event"
+	"noscli/internal/nostr"
+)
+
+type eventOptions struct {
+	relays  []string
+	kind    int
+	tags    []string
+	content string
+	dryRun  bool
+}
+
+func newEventCommand() *cobra.Command {
+	opts := &eventOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "event",
+		Short: "任意の kind のイベントを作成・署名して投稿する",
+		Long: `--kind, --tag, --content で指定したイベントを NOSTR_NSEC の鍵で署名し、すべての書き込みリレーに送信します。
+--content @file.md のように @ を付けるとファイルの内容を本文にします。--dry-run では送信せず署名済みイベントを出力します。`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("kind") {
+				return errors.New("--kind を指定してください")
+			}
+			if opts.kind < 0 || opts.kind > 65535 {
+				return errors.New("--kind には 0 から 65535 の値を指定してください")
+			}
+
+			content, err := readContentFlag(opts.content)
+			if err != nil {
+				return err
+			}
+			tags, err := parseTagFlags(opts.tags)
+			if err != nil {
+				return err
+			}
+
+			evt := nostr.Event{
+				Kind:    opts.kind,
+				Tags:    tags,
+				Content: content,
+			}
+			return runEvent(cmd, opts, evt)
+		},
+	}
+
+	addEventFlags(cmd, opts)
+	cmd.Flags().IntVar(&opts.kind, "kind", 0, "イベントの kind")
+	cmd.Flags().StringArrayVar(&opts.tags, "tag", nil, "タグ (key=value 形式、複数指定可)")
+	cmd.Flags().StringVar(&opts.content, "content", "", "本文 (@path でファイルから読み込む)")
+
+	cmd.AddCommand(newEventPublishCommand())
+
+	return cmd
+}
+
+func newEventPublishCommand() *cobra.Command {
+	opts := &eventOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "publish",
+		Short: "標準入力のイベント JSON を署名または検証して投稿する",
+		Long:  "標準入力からイベント JSON を 1 件読み込みます。sig がなければ NOSTR_NSEC の鍵で署名し、署名済みであれば検証してからそのまま送信します。",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var evt nostr.Event
+			dec := json.NewDecoder(cmd.InOrStdin())
+			if err := dec.Decode(&evt); err != nil {
+				return fmt.Errorf("イベント JSON を読み込めません: %w", err)
+			}
+			return runEvent(cmd, opts, evt)
+		},
+	}
+
+	addEventFlags(cmd, opts)
+
+	return cmd
+}
+
+func addEventFlags(cmd *cobra.Command, opts *eventOptions) {
+	cmd.Flags().StringArrayVar(&opts.relays, "relay", nil, "リレー URL (複数指定可、未指定時は NOSCLI_RELAYS)")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "送信せず署名済みイベントを出力する")
+}
+
+func runEvent(cmd *cobra.Command, opts *eventOptions, evt nostr.Event) error {
+	logger := getLogger()
+
+	relays := opts.relays
+	if len(relays) == 0 {
+		relays = loadConfig().Relays
+	}
+	if len(relays) == 0 && !opts.dryRun {
+		return errors.New("リレーが指定されていません (--relay または NOSCLI_RELAYS)")
+	}
+
+	req := event.Request{
+		Relays: relays,
+		Event:  evt,
+		DryRun: opts.dryRun,
+	}
+
+	svc := event.NewService(nostr.NewClient(logger), logger)
+	return svc.Run(commandContext(cmd), req, cmd.OutOrStdout())
+}
+
+// readContentFlag returns v, or the contents of the file when v is "@path".
+func readContentFlag(v string) (string, error) {
+	path, ok := strings.CutPrefix(v, "@")
+	if !ok {
+		return v, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("本文ファイルを読み込めません: %w", err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"noscli/internal/app/notifications"
+	"noscli/internal/nostr"
+	"noscli/internal/storage"
+)
+
+type notificationsOptions struct {
+	relays []string
+	follow bool
+}
+
+func newNotificationsCommand() *cobra.Command {
+	opts := &notificationsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "notifications",
+		Short: "自分宛ての返信・メンション・リアクション・リポスト・Zap を表示する",
+		Long:  "読み込みリレーから #p が自分の公開鍵であるイベントを取得して分類表示します。最後に表示した時刻をローカルに記録し、次回以降は新着のみを表示します。",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := loadConfig()
+			logger := getLogger()
+
+			relays := opts.relays
+			if len(relays) == 0 {
+				relays = cfg.Relays
+			}
+
+			req := notifications.Request{
+				Relays: relays,
+				Follow: opts.follow,
+			}
+
+			cursor := storage.NewCursor(filepath.Join(cfg.DataDir, "notifications.json"))
+			svc := notifications.NewService(nostr.NewClient(logger), cursor, logger)
+			return svc.Run(commandContext(cmd), req, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&opts.relays, "relay", nil, "リレー URL (複数指定可、未指定時は NOSCLI_RELAYS)")
+	cmd.Flags().BoolVar(&opts.follow, "follow", false, "新着通知を待ち受けて表示し続ける")
+
+	return cmd
+}
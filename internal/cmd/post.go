@@ -1,10 +1,11 @@
 package cmd
 
 import (
-	"context"
 	"errors"
+	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -16,6 +17,10 @@ type postOptions struct {
 	relay   string
 	message string
 	replyTo string
+	tags    []string
+	warning string
+	expire  time.Duration
+	pow     int
 }
 
 func newPostCommand() *cobra.Command {
@@ -24,7 +29,7 @@ func newPostCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "post",
 		Short: "Nostr テキストノートを投稿する",
-		Long:  "kind 1 のテキストノートイベントを単一リレーに送信します。メッセージは -m または標準入力から指定します。",
+		Long:  "kind 1 のテキストノートイベントを単一リレーに送信します。メッセージは -m または標準入力から指定します。本文中のハッシュタグは t タグとして自動付与されます。",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := loadConfig()
 			logger := getLogger()
@@ -49,15 +54,27 @@ func newPostCommand() *cobra.Command {
 				return errors.New("投稿内容が空です (-m または標準入力で指定してください)")
 			}
 
-			ctx := cmd.Context()
-			if ctx == nil {
-				ctx = context.Background()
+			tags, err := parseTagFlags(opts.tags)
+			if err != nil {
+				return err
 			}
+			if opts.expire < 0 {
+				return errors.New("--expire には正の期間を指定してください")
+			}
+			if opts.pow < 0 || opts.pow > 256 {
+				return errors.New("--pow には 0 から 256 の値を指定してください")
+			}
+
+			ctx := commandContext(cmd)
 
 			req := post.Request{
-				Relay:   relay,
-				Content: content,
-				ReplyTo: strings.TrimSpace(opts.replyTo),
+				Relay:          relay,
+				Content:        content,
+				ReplyTo:        strings.TrimSpace(opts.replyTo),
+				Tags:           tags,
+				ContentWarning: strings.TrimSpace(opts.warning),
+				ExpireAfter:    opts.expire,
+				PoW:            opts.pow,
 			}
 
 			svc := post.NewService(nostr.NewClient(logger), logger)
@@ -68,6 +85,24 @@ func newPostCommand() *cobra.Command {
 	cmd.Flags().StringVar(&opts.relay, "relay", "", "リレー URL")
 	cmd.Flags().StringVarP(&opts.message, "message", "m", "", "投稿するテキスト本文")
 	cmd.Flags().StringVar(&opts.replyTo, "reply-to", "", "返信先イベント ID")
+	cmd.Flags().StringArrayVar(&opts.tags, "tag", nil, "追加するタグ (key=value 形式、複数指定可)")
+	cmd.Flags().StringVar(&opts.warning, "content-warning", "", "コンテンツ警告の理由 (NIP-36)")
+	cmd.Flags().DurationVar(&opts.expire, "expire", 0, "投稿の有効期限 (例: 24h, NIP-40)")
+	cmd.Flags().IntVar(&opts.pow, "pow", 0, "投稿前に採掘する PoW 難易度 (先頭ゼロビット数, NIP-13)")
 
 	return cmd
 }
+
+// parseTagFlags converts repeated "key=value" flags into event tags.
+func parseTagFlags(values []string) ([][]string, error) {
+	tags := make([][]string, 0, len(values))
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("タグの形式が不正です (key=value で指定してください): %q", v)
+		}
+		tags = append(tags, []string{key, value})
+	}
+	return tags, nil
+}
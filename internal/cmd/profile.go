@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"noscli/internal/app/profile"
+	"noscli/internal/nip05"
+	"noscli/internal/nostr"
+	"noscli/internal/storage"
+)
+
+type profileOptions struct {
+	relays []string
+}
+
+func newProfileCommand() *cobra.Command {
+	opts := &profileOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "profile <npub|hex|nip05>",
+		Short: "ユーザーのプロフィール (kind 0) を表示する",
+		Long:  "公開鍵または NIP-05 識別子 (user@domain) で指定したユーザーのプロフィールを取得して表示します。nip05 が公開鍵と一致する場合は検証済みマークを付けます。",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := loadConfig()
+			logger := getLogger()
+			ctx := commandContext(cmd)
+
+			pubkey, hints, err := resolvePubKey(ctx, args[0])
+			if err != nil {
+				return err
+			}
+
+			relays := opts.relays
+			if len(relays) == 0 {
+				relays = cfg.Relays
+			}
+			for _, hint := range hints {
+				if !slices.Contains(relays, hint) {
+					relays = append(relays, hint)
+				}
+			}
+
+			req := profile.ShowRequest{
+				Relays: relays,
+				PubKey: pubkey,
+			}
+
+			var store profile.Store
+			eventStore, err := storage.OpenEventStore(cfg.DataDir)
+			if err != nil {
+				logger.Warn("event store unavailable, caching disabled", "error", err)
+			} else {
+				defer eventStore.Close()
+				store = eventStore
+			}
+
+			svc := profile.NewService(nostr.NewClient(logger), store, nip05.NewResolver(nil), logger)
+			return svc.Show(ctx, req, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&opts.relays, "relay", nil, "リレー URL (複数指定可、未指定時は NOSCLI_RELAYS)")
+
+	return cmd
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"noscli/internal/nip05"
+	"noscli/internal/nostr"
+)
+
+// resolvePubKey accepts a hex key, an npub or a NIP-05 identifier
+// (user@domain) and returns the hex pubkey together with any relay hints
+// published for it.
+func resolvePubKey(ctx context.Context, s string) (string, []string, error) {
+	if nip05.IsIdentifier(s) {
+		ptr, err := nip05.NewResolver(nil).Resolve(ctx, s)
+		if err != nil {
+			return "", nil, fmt.Errorf("NIP-05 識別子 %s を解決できません: %w", s, err)
+		}
+		return ptr.PubKey, ptr.Relays, nil
+	}
+	pubkey, err := nostr.ParsePubKey(s)
+	if err != nil {
+		return "", nil, err
+	}
+	return pubkey, nil, nil
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"noscli/internal/app/relay"
+	"noscli/internal/nostr"
+	"noscli/internal/storage"
+)
+
+func newRelayCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "relay",
+		Short: "リレーの状態確認とローカルリレーの起動",
+	}
+
+	cmd.AddCommand(newRelayStatusCommand())
+	cmd.AddCommand(newRelayServeCommand())
+
+	return cmd
+}
+
+type relayStatusOptions struct {
+	relays  []string
+	timeout time.Duration
+}
+
+func newRelayStatusCommand() *cobra.Command {
+	opts := &relayStatusOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "設定済みのリレーに並列で接続して状態を表示する",
+		Long:  "各リレーに接続して保存済みイベントを 1 件要求し、EOSE までの結果から接続状態・ハンドシェイクの遅延・受信件数・再接続回数を表示します。接続できないリレーが 1 つでもあれば終了コードは 0 以外になります。",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := loadConfig()
+			logger := getLogger()
+
+			relays := opts.relays
+			if len(relays) == 0 {
+				relays = cfg.Relays
+			}
+			if len(relays) == 0 || relays[0] == "" {
+				return errors.New("リレーが指定されていません (--relay または NOSCLI_RELAYS)")
+			}
+
+			req := relay.StatusRequest{
+				Relays:  relays,
+				Timeout: opts.timeout,
+			}
+
+			svc := relay.NewService(nostr.NewClient(logger), logger)
+			err := svc.Status(commandContext(cmd), req, cmd.OutOrStdout())
+			if errors.Is(err, relay.ErrUnreachable) {
+				return errors.New("接続できないリレーがあります")
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&opts.relays, "relay", nil, "リレー URL (複数指定可、未指定時は NOSCLI_RELAYS)")
+	cmd.Flags().DurationVar(&opts.timeout, "timeout", 10*time.Second, "リレーごとのタイムアウト")
+
+	return cmd
+}
+
+type relayServeOptions struct {
+	listen      string
+	dataDir     string
+	allow       []string
+	name        string
+	description string
+}
+
+func newRelayServeCommand() *cobra.Command {
+	opts := &relayServeOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "ローカル用の小さなリレーを起動する",
+		Long:  "NIP-01 (REQ/EVENT/CLOSE)、NIP-11 の情報ドキュメントと NIP-77 (negentropy) の差分同期に対応したリレーを起動します。受け取ったイベントは署名を検証してからデータディレクトリのイベントストアに保存します。--allow を指定すると、その公開鍵からの書き込みだけを受け付けます。",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := loadConfig()
+			logger := getLogger()
+			// Ctrl+C で接続を閉じ、イベントストアを閉じてから終了する
+			ctx, stop := signal.NotifyContext(commandContext(cmd), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			dataDir := opts.dataDir
+			if dataDir == "" {
+				// クライアントのキャッシュとファイルを共有しないよう専用のディレクトリを使う
+				dataDir = filepath.Join(cfg.DataDir, "relay")
+			}
+
+			allow := make([]string, 0, len(opts.allow))
+			for _, s := range opts.allow {
+				pubkey, _, err := resolvePubKey(ctx, s)
+				if err != nil {
+					return fmt.Errorf("--allow %s: %w", s, err)
+				}
+				allow = append(allow, pubkey)
+			}
+
+			store, err := storage.OpenEventStore(dataDir)
+			if err != nil {
+				return fmt.Errorf("イベントストアを開けません: %w", err)
+			}
+			defer store.Close()
+
+			server := relay.NewServer(store, relay.ServerOptions{
+				Allow: allow,
+				Info: relay.Info{
+					Name:        opts.name,
+					Description: opts.description,
+					Software:    "noscli",
+				},
+			}, logger)
+
+			// 待ち受けに成功してから起動を報告する
+			ln, err := net.Listen("tcp", opts.listen)
+			if err != nil {
+				return fmt.Errorf("%s で待ち受けできません: %w", opts.listen, err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "リレーを起動しました: %s (データ: %s)\n", ln.Addr(), dataDir)
+			return server.Serve(ctx, ln)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.listen, "listen", ":7777", "待ち受けるアドレス")
+	cmd.Flags().StringVar(&opts.dataDir, "data", "", "イベントを保存するディレクトリ (未指定時はデータディレクトリの relay)")
+	cmd.Flags().StringArrayVar(&opts.allow, "allow", nil, "書き込みを許可する公開鍵 (npub、hex、NIP-05。複数指定可、未指定時は全員)")
+	cmd.Flags().StringVar(&opts.name, "name", "noscli relay", "NIP-11 に載せるリレー名")
+	cmd.Flags().StringVar(&opts.description, "description", "", "NIP-11 に載せる説明")
+
+	return cmd
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"noscli/internal/app/req"
+	"noscli/internal/nostr"
+)
+
+type reqOptions struct {
+	relay   string
+	stream  bool
+	ids     []string
+	authors []string
+	kinds   []int
+	tags    []string
+	since   string
+	until   string
+	limit   int
+}
+
+func newReqCommand() *cobra.Command {
+	opts := &reqOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "req [filter-json]",
+		Short: "任意のフィルタで REQ を送り、受信したイベントをそのまま出力する",
+		Long: `リレーのデバッグ用に、NIP-01 のフィルタ JSON (例: '{"kinds":[1],"#t":["nostr"],"limit":10}') またはフラグで指定した REQ を送信します。
+search (NIP-50) や複数文字のタグなど NIP-01 以外のフィールドもそのまま送信します。
+受信したイベントは 1 行 1 JSON で標準出力に、EOSE / CLOSED / NOTICE は標準エラー出力に表示します。
+--stream を指定しない場合は EOSE を受信した時点で終了します。`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := getLogger()
+			ctx := commandContext(cmd)
+
+			relay := opts.relay
+			if relay == "" {
+				relay = loadConfig().Timeline.Relay
+			}
+			if strings.TrimSpace(relay) == "" {
+				return errors.New("リレーが指定されていません (--relay または NOSCLI_RELAY)")
+			}
+
+			var filter nostr.Filter
+			if len(args) == 1 {
+				if err := json.Unmarshal([]byte(args[0]), &filter); err != nil {
+					return fmt.Errorf("フィルタ JSON を解析できません: %w", err)
+				}
+			}
+
+			filter.IDs = append(filter.IDs, opts.ids...)
+			filter.Kinds = append(filter.Kinds, opts.kinds...)
+			for _, author := range opts.authors {
+				pubkey, _, err := resolvePubKey(ctx, author)
+				if err != nil {
+					return err
+				}
+				filter.Authors = append(filter.Authors, pubkey)
+			}
+			tags, err := parseTagFlags(opts.tags)
+			if err != nil {
+				return err
+			}
+			for _, tag := range tags {
+				if len(tag[0]) != 1 {
+					return fmt.Errorf("タグ名は 1 文字で指定してください: %q", tag[0])
+				}
+				if filter.Tags == nil {
+					filter.Tags = make(map[string][]string)
+				}
+				filter.Tags[tag[0]] = append(filter.Tags[tag[0]], tag[1])
+			}
+			if opts.since != "" {
+				if filter.Since, err = parseTimeFlag(opts.since); err != nil {
+					return err
+				}
+			}
+			if opts.until != "" {
+				if filter.Until, err = parseTimeFlag(opts.until); err != nil {
+					return err
+				}
+			}
+			if cmd.Flags().Changed("limit") {
+				if opts.limit < 0 {
+					return errors.New("--limit には 0 以上の値を指定してください")
+				}
+				filter.Limit = opts.limit
+				filter.LimitZero = opts.limit == 0
+			}
+
+			r := req.Request{
+				Relay:  relay,
+				Filter: filter,
+				Stream: opts.stream,
+			}
+
+			svc := req.NewService(nostr.NewClient(logger), logger)
+			return svc.Run(ctx, r, cmd.OutOrStdout(), cmd.ErrOrStderr())
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.relay, "relay", "", "リレー URL")
+	cmd.Flags().BoolVar(&opts.stream, "stream", false, "EOSE 後も購読を続ける")
+	cmd.Flags().StringArrayVar(&opts.ids, "id", nil, "イベント ID (複数指定可)")
+	cmd.Flags().StringArrayVar(&opts.authors, "author", nil, "作成者 (npub, hex または NIP-05 識別子、複数指定可)")
+	cmd.Flags().IntSliceVar(&opts.kinds, "kind", nil, "kind (複数指定可)")
+	cmd.Flags().StringArrayVar(&opts.tags, "tag", nil, "タグ条件 (t=nostr のように 1 文字のタグ名=値、複数指定可)")
+	cmd.Flags().StringVar(&opts.since, "since", "", "この時刻以降 (UNIX 秒, RFC3339 または 24h のような相対時間)")
+	cmd.Flags().StringVar(&opts.until, "until", "", "この時刻以前 (UNIX 秒, RFC3339 または 24h のような相対時間)")
+	cmd.Flags().IntVar(&opts.limit, "limit", 0, "取得する最大件数")
+
+	return cmd
+}
+
+// parseTimeFlag accepts UNIX seconds, RFC3339 or a duration meaning "this long ago".
+func parseTimeFlag(v string) (*time.Time, error) {
+	if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+		t := time.Unix(unix, 0)
+		return &t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		t := time.Now().Add(-d)
+		return &t, nil
+	}
+	return nil, fmt.Errorf("時刻の形式が不正です (UNIX 秒, RFC3339 または 24h のような相対時間): %q", v)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"sync"
@@ -36,6 +37,16 @@ func init() {
 	rootCmd.AddCommand(
 		newTimelineCommand(),
 		newPostCommand(),
+		newDMCommand(),
+		newNotificationsCommand(),
+		newProfileCommand(),
+		newReqCommand(),
+		newEventCommand(),
+		newVerifyCommand(),
+		newArticleCommand(),
+		newRelayCommand(),
+		newSyncCommand(),
+		newTUICommand(),
 	)
 }
 
@@ -59,3 +70,12 @@ func getLogger() *slog.Logger {
 	})
 	return logger
 }
+
+// commandContext returns the command's context, or a background one when the
+// command runs without it.
+func commandContext(cmd *cobra.Command) context.Context {
+	if ctx := cmd.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
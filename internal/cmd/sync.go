@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"noscli/internal/app/mirror"
+	"noscli/internal/nostr"
+	"noscli/internal/storage"
+)
+
+type syncOptions struct {
+	from        string
+	to          string
+	authors     []string
+	kinds       []int
+	pageSize    int
+	concurrency int
+	restart     bool
+}
+
+func newSyncCommand() *cobra.Command {
+	opts := &syncOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "あるリレーのイベントを別のリレーへコピーする",
+		Long: `--from のリレーから until を遡りながら過去のイベントを取得し、署名を検証して --to のリレーへ再送信します。
+ページごとに進捗をデータディレクトリに記録するため、中断しても同じ引数で再実行すれば続きから再開します。
+両方のリレーが NIP-77 (negentropy) に対応していれば、ローカルキャッシュに無いイベントだけを取得し、コピー先に無いイベントだけを送信します。
+最後にコピー・重複・拒否・不正の件数を表示し、拒否されたイベントがあれば終了コードは 0 以外になります。`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := loadConfig()
+			logger := getLogger()
+			ctx := commandContext(cmd)
+
+			if opts.from == "" || opts.to == "" {
+				return errors.New("--from と --to を指定してください")
+			}
+			if opts.from == opts.to {
+				return errors.New("--from と --to に同じリレーは指定できません")
+			}
+
+			filter := nostr.Filter{Kinds: opts.kinds}
+			authors := opts.authors
+			if len(authors) == 0 {
+				authors = []string{"me"}
+			}
+			for _, author := range authors {
+				if author == "me" {
+					keys, err := nostr.LoadKeysFromEnv()
+					if err != nil {
+						return fmt.Errorf("自分の公開鍵を取得できません: %w", err)
+					}
+					filter.Authors = append(filter.Authors, keys.Public)
+					continue
+				}
+				pubkey, _, err := resolvePubKey(ctx, author)
+				if err != nil {
+					return err
+				}
+				filter.Authors = append(filter.Authors, pubkey)
+			}
+
+			req := mirror.Request{
+				From:        opts.from,
+				To:          opts.to,
+				Filter:      filter,
+				PageSize:    opts.pageSize,
+				Concurrency: opts.concurrency,
+			}
+
+			checkpoint := storage.NewCursor(filepath.Join(cfg.DataDir, "sync", checkpointName(req)))
+			if opts.restart {
+				if err := checkpoint.Clear(); err != nil {
+					return err
+				}
+			}
+
+			var store mirror.Store
+			eventStore, err := storage.OpenEventStore(cfg.DataDir)
+			if err != nil {
+				logger.Warn("event store unavailable, negentropy disabled", "error", err)
+			} else {
+				defer eventStore.Close()
+				store = eventStore
+			}
+
+			svc := mirror.NewService(nostr.NewClient(logger), store, checkpoint, logger)
+			err = svc.Run(ctx, req, cmd.OutOrStdout())
+			if errors.Is(err, mirror.ErrRejected) {
+				return errors.New("コピー先に拒否されたイベントがあります")
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.from, "from", "", "コピー元のリレー URL")
+	cmd.Flags().StringVar(&opts.to, "to", "", "コピー先のリレー URL")
+	cmd.Flags().StringArrayVar(&opts.authors, "author", nil, "作成者 (npub, hex, NIP-05 識別子または自分を表す me、複数指定可、未指定時は me)")
+	cmd.Flags().IntSliceVar(&opts.kinds, "kinds", nil, "コピーする kind (0,1,3 のようにカンマ区切り、未指定時はすべて)")
+	cmd.Flags().IntVar(&opts.pageSize, "page-size", 500, "1 回の REQ で取得する件数")
+	cmd.Flags().IntVar(&opts.concurrency, "concurrency", 4, "同時に送信するイベント数の上限")
+	cmd.Flags().BoolVar(&opts.restart, "restart", false, "記録済みの進捗を破棄して最新から始める")
+
+	return cmd
+}
+
+// checkpointName derives the checkpoint file from the relays and filter, so
+// different copies do not share progress.
+func checkpointName(req mirror.Request) string {
+	b, _ := json.Marshal(req.Filter)
+	sum := sha256.Sum256([]byte(req.From + "\n" + req.To + "\n" + string(b)))
+	return hex.EncodeToString(sum[:8]) + ".json"
+}
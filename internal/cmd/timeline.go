@@ -1,17 +1,31 @@
 package cmd
 
 import (
-	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"noscli/internal/app/profile"
 	"noscli/internal/app/timeline"
+	"noscli/internal/nip05"
 	"noscli/internal/nostr"
+	"noscli/internal/storage"
 )
 
 type timelineOptions struct {
-	relay string
+	relays        []string
+	showSensitive bool
+	minPoW        int
+	offline       bool
+	limit         int
+	refresh       time.Duration
+	output        string
+	format        string
+	maxAttempts   int
+	reorder       time.Duration
+	maxSkew       time.Duration
 }
 
 func newTimelineCommand() *cobra.Command {
@@ -20,34 +34,78 @@ func newTimelineCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "timeline",
 		Short: "Nostr テキストノートをストリーム表示する",
-		Long:  "WebSocket でリレーに接続し、Ctrl+C などで中断するまでイベントを受信し続けます。",
+		Long:  "WebSocket でリレーに接続し、Ctrl+C などで中断するまでイベントを受信し続けます。--relay を複数指定すると全リレーを購読し、複数のリレーから届いた同じ投稿は一度だけ表示します。受信した検証済みイベントはローカルにキャッシュされ、--offline で参照できます。",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := loadConfig()
 			logger := getLogger()
 
-			relay := opts.relay
-			if relay == "" {
-				relay = cfg.Timeline.Relay
+			relays := opts.relays
+			if len(relays) == 0 {
+				relays = []string{cfg.Timeline.Relay}
 			}
-			if relay == "" {
+			if relays[0] == "" && (!opts.offline || opts.refresh > 0) {
 				return errors.New("リレーが指定されていません (--relay または NOSCLI_RELAY)")
 			}
+			if opts.refresh > 0 && !opts.offline {
+				return errors.New("--refresh は --offline と一緒に指定してください")
+			}
 
 			req := timeline.Request{
-				Relays: []string{relay},
+				Relays:        relays,
+				ShowSensitive: opts.showSensitive,
+				MinPoW:        opts.minPoW,
+				Offline:       opts.offline,
+				Limit:         opts.limit,
+				Refresh:       opts.refresh,
+				Output:        opts.output,
+				Format:        opts.format,
+				ReorderWindow: opts.reorder,
+				MaxFutureSkew: opts.maxSkew,
 			}
 
-			ctx := cmd.Context()
-			if ctx == nil {
-				ctx = context.Background()
+			ctx := commandContext(cmd)
+
+			var (
+				store        timeline.Store
+				profileStore profile.Store
+			)
+			eventStore, err := storage.OpenEventStore(cfg.DataDir)
+			if err != nil {
+				if opts.offline {
+					return fmt.Errorf("ローカルキャッシュを開けません: %w", err)
+				}
+				logger.Warn("event store unavailable, caching disabled", "error", err)
+			} else {
+				defer eventStore.Close()
+				store = eventStore
+				profileStore = eventStore
+			}
+
+			if opts.maxAttempts < 0 {
+				return errors.New("--max-attempts には 0 以上の値を指定してください")
+			}
+			client := nostr.NewClient(logger, nostr.WithMaxAttempts(opts.maxAttempts))
+			resolver := profile.NewResolver(client, profileStore, nip05.NewResolver(nil), req.Relays, profile.NewCache(profile.DefaultTTL), logger)
+			if !opts.offline {
+				go resolver.Run(ctx)
 			}
 
-			svc := timeline.NewService(nostr.NewClient(logger), logger)
+			svc := timeline.NewService(client, store, resolver, logger)
 			return svc.Run(ctx, req, cmd.OutOrStdout())
 		},
 	}
 
-	cmd.Flags().StringVar(&opts.relay, "relay", "", "リレー URL")
+	cmd.Flags().StringArrayVar(&opts.relays, "relay", nil, "リレー URL (複数指定可、同じ投稿は一度だけ表示)")
+	cmd.Flags().IntVar(&opts.minPoW, "min-pow", 0, "指定した PoW 難易度未満の投稿を表示しない (NIP-13)")
+	cmd.Flags().BoolVar(&opts.offline, "offline", false, "ネットワークに接続せずローカルキャッシュの投稿を表示する")
+	cmd.Flags().IntVar(&opts.limit, "limit", 50, "--offline 時に表示する最大件数")
+	cmd.Flags().DurationVar(&opts.refresh, "refresh", 0, "--offline 時、表示前にこの期間の投稿のうちキャッシュに無いものをリレーから取得する (例: 24h、NIP-77 対応リレーでは差分のみ)")
+	cmd.Flags().StringVar(&opts.output, "output", timeline.OutputPlain, "出力形式 (plain, jsonl, raw)")
+	cmd.Flags().StringVar(&opts.format, "format", "", "各イベントを Go テンプレートで出力する (例: '{{.CreatedAt}} {{.Author}} {{.Content}}')")
+	cmd.Flags().IntVar(&opts.maxAttempts, "max-attempts", 0, "連続して接続に失敗したら終了する回数 (0 は無制限)")
+	cmd.Flags().DurationVar(&opts.reorder, "reorder-window", 0, "受信した投稿をこの時間だけ保持し作成日時順に表示する (例: 2s)")
+	cmd.Flags().DurationVar(&opts.maxSkew, "max-skew", 15*time.Minute, "作成日時がこれより未来の投稿を表示しない (0 で無効)")
+	cmd.Flags().BoolVar(&opts.showSensitive, "show-sensitive", false, "コンテンツ警告付きの投稿も本文を表示する")
 
 	return cmd
 }
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"noscli/internal/app/event"
+	"noscli/internal/app/post"
+	"noscli/internal/app/profile"
+	"noscli/internal/app/timeline"
+	"noscli/internal/nip05"
+	"noscli/internal/nostr"
+	"noscli/internal/storage"
+	"noscli/internal/tui"
+)
+
+type tuiOptions struct {
+	relays        []string
+	showSensitive bool
+	minPoW        int
+	maxSkew       time.Duration
+}
+
+func newTUICommand() *cobra.Command {
+	opts := &tuiOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "対話型のターミナル UI を起動する",
+		Long:  "タイムライン・投稿入力・ステータスバーからなる TUI を起動します。j/k で投稿を移動し、n で投稿、r で返信、l でリアクション、t でリポストします。指定した全リレーからタイムラインを受信し、投稿・返信・リアクション・リポストも全リレーに送信します。",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := loadConfig()
+
+			relays := opts.relays
+			if len(relays) == 0 {
+				relays = cfg.Relays
+			}
+			if len(relays) == 0 || relays[0] == "" {
+				return errors.New("リレーが指定されていません (--relay または NOSCLI_RELAYS)")
+			}
+
+			ctx := commandContext(cmd)
+
+			// 標準エラーへのログは画面を崩すため捨てる。接続エラーはステータスバーに表示される
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+			var (
+				store        timeline.Store
+				profileStore profile.Store
+			)
+			if eventStore, err := storage.OpenEventStore(cfg.DataDir); err == nil {
+				defer eventStore.Close()
+				store = eventStore
+				profileStore = eventStore
+			}
+
+			client := nostr.NewClient(logger)
+			resolver := profile.NewResolver(client, profileStore, nip05.NewResolver(nil), relays, profile.NewCache(profile.DefaultTTL), logger)
+			go resolver.Run(ctx)
+
+			return tui.Run(ctx, tui.Options{
+				Relays:        relays,
+				ShowSensitive: opts.showSensitive,
+				MinPoW:        opts.minPoW,
+				MaxFutureSkew: opts.maxSkew,
+			},
+				timeline.NewService(client, store, resolver, logger),
+				post.NewService(client, logger),
+				event.NewService(client, logger),
+			)
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&opts.relays, "relay", nil, "リレー URL (複数指定可、未指定時は NOSCLI_RELAYS)")
+	cmd.Flags().IntVar(&opts.minPoW, "min-pow", 0, "指定した PoW 難易度未満の投稿を表示しない (NIP-13)")
+	cmd.Flags().DurationVar(&opts.maxSkew, "max-skew", 15*time.Minute, "作成日時がこれより未来の投稿を表示しない (0 で無効)")
+	cmd.Flags().BoolVar(&opts.showSensitive, "show-sensitive", false, "コンテンツ警告付きの投稿も本文を表示する")
+
+	return cmd
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"noscli/internal/app/verify"
+)
+
+type verifyOptions struct {
+	onlyInvalid bool
+}
+
+func newVerifyCommand() *cobra.Command {
+	opts := &verifyOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "標準入力のイベント (JSONL) をオフラインで検証する",
+		Long:  "1 行 1 イベントの JSON を標準入力から読み込み、ID と署名に加えて NIP-01 の構造 (hex の長さ・小文字、タグの形式、created_at、kind の範囲) を検証します。不正なイベントが 1 件でもあれば終了コードは 0 以外になります。",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := verify.Request{
+				Input:       cmd.InOrStdin(),
+				OnlyInvalid: opts.onlyInvalid,
+			}
+
+			svc := verify.NewService(getLogger())
+			err := svc.Run(commandContext(cmd), req, cmd.OutOrStdout())
+			if errors.Is(err, verify.ErrInvalidEvents) {
+				return errors.New("検証に失敗したイベントがあります")
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.onlyInvalid, "only-invalid", false, "不正なイベントの結果のみ表示する")
+
+	return cmd
+}
@@ -2,12 +2,17 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 )
 
 // Config represents runtime configuration exposed to the CLI.
 type Config struct {
 	Timeline TimelineConfig
+	// Relays lists the read relays used by commands that query several relays.
+	Relays []string
+	// DataDir is where noscli keeps local state such as notification cursors.
+	DataDir string
 }
 
 // TimelineConfig holds defaults for the timeline command.
@@ -27,5 +32,39 @@ func Load() Config {
 		cfg.Timeline.Relay = relayEnv
 	}
 
+	// NOSCLI_RELAYS はカンマ区切り。未指定時は単一リレー設定をそのまま使う
+	cfg.Relays = splitList(os.Getenv("NOSCLI_RELAYS"))
+	if len(cfg.Relays) == 0 {
+		cfg.Relays = []string{cfg.Timeline.Relay}
+	}
+
+	cfg.DataDir = dataDir()
+
 	return cfg
 }
+
+// dataDir resolves $XDG_DATA_HOME/noscli, falling back to ~/.local/share/noscli.
+func dataDir() string {
+	if dir := strings.TrimSpace(os.Getenv("NOSCLI_DATA_DIR")); dir != "" {
+		return dir
+	}
+	base := strings.TrimSpace(os.Getenv("XDG_DATA_HOME"))
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(os.TempDir(), "noscli")
+		}
+		base = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(base, "noscli")
+}
+
+func splitList(in string) []string {
+	var out []string
+	for _, v := range strings.Split(in, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+// Package nip04 implements the legacy NIP-04 direct message encryption.
+//
+// NIP-04 is deprecated in favour of NIP-44/NIP-17: it leaks metadata and is
+// not authenticated. It is kept only to read and answer contacts whose clients
+// have not migrated yet.
+package nip04
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+)
+
+// KindEncryptedDirectMessage is the legacy NIP-04 direct message kind.
+const KindEncryptedDirectMessage = 4
+
+// SharedSecret returns the unhashed x coordinate of the ECDH point between a
+// raw 32-byte private key and a hex x-only public key, used as the AES key.
+func SharedSecret(priv []byte, pubHex string) ([]byte, error) {
+	if len(priv) != 32 {
+		return nil, fmt.Errorf("invalid private key length: %d", len(priv))
+	}
+	pubBytes, err := hex.DecodeString(pubHex)
+	if err != nil {
+		return nil, fmt.Errorf("pubkey decode: %w", err)
+	}
+	pub, err := schnorr.ParsePubKey(pubBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	sk, _ := btcec.PrivKeyFromBytes(priv)
+	return btcec.GenerateSharedSecret(sk, pub), nil
+}
+
+// Encrypt encrypts plaintext with AES-256-CBC and returns "<ciphertext>?iv=<iv>".
+func Encrypt(plaintext string, sharedSecret []byte) (string, error) {
+	block, err := aes.NewCipher(sharedSecret)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return "", fmt.Errorf("read iv: %w", err)
+	}
+
+	padded := pkcs7Pad([]byte(plaintext), aes.BlockSize)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+	return base64.StdEncoding.EncodeToString(ciphertext) + "?iv=" + base64.StdEncoding.EncodeToString(iv), nil
+}
+
+// Decrypt reverses Encrypt.
+func Decrypt(content string, sharedSecret []byte) (string, error) {
+	ctPart, ivPart, ok := strings.Cut(content, "?iv=")
+	if !ok {
+		return "", errors.New("missing iv")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(ctPart)
+	if err != nil {
+		return "", fmt.Errorf("decode ciphertext: %w", err)
+	}
+	iv, err := base64.StdEncoding.DecodeString(ivPart)
+	if err != nil {
+		return "", fmt.Errorf("decode iv: %w", err)
+	}
+	if len(iv) != aes.BlockSize {
+		return "", fmt.Errorf("invalid iv length: %d", len(iv))
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return "", fmt.Errorf("invalid ciphertext length: %d", len(ciphertext))
+	}
+
+	block, err := aes.NewCipher(sharedSecret)
+	if err != nil {
+		return "", err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	unpadded, err := pkcs7Unpad(plaintext, aes.BlockSize)
+	if err != nil {
+		return "", err
+	}
+	return string(unpadded), nil
+}
+
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	return append(data, bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("invalid padding")
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize || n > len(data) {
+		return nil, errors.New("invalid padding")
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, errors.New("invalid padding")
+		}
+	}
+	return data[:len(data)-n], nil
+}
@@ -0,0 +1,97 @@
+package nip04
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"noscli/internal/nostr"
+)
+
+func TestSharedSecretIsSymmetric(t *testing.T) {
+	priv1 := bytes.Repeat([]byte{0x01}, 32)
+	priv2 := bytes.Repeat([]byte{0x02}, 32)
+
+	ss1, err := SharedSecret(priv1, mustPublicKey(t, priv2))
+	if err != nil {
+		t.Fatalf("SharedSecret() unexpected error: %v", err)
+	}
+	ss2, err := SharedSecret(priv2, mustPublicKey(t, priv1))
+	if err != nil {
+		t.Fatalf("SharedSecret() unexpected error: %v", err)
+	}
+	if !bytes.Equal(ss1, ss2) {
+		t.Fatalf("shared secrets differ: %x != %x", ss1, ss2)
+	}
+}
+
+func TestDecryptNostrToolsPayload(t *testing.T) {
+	// Payload produced by nostr-tools for the same key pair.
+	sk1, _ := hex.DecodeString("92996316beebf94171065a714cbf164d1f56d7ad9b35b329d9fc97535bf25352")
+	sk2, _ := hex.DecodeString("591c0c249adfb9346f8d37dfeed65725e2eea1d7a6e99fa503342f367138de84")
+
+	shared, err := SharedSecret(sk1, mustPublicKey(t, sk2))
+	if err != nil {
+		t.Fatalf("SharedSecret() unexpected error: %v", err)
+	}
+	got, err := Decrypt("A+fRnU4aXS4kbTLfowqAww==?iv=QFYUrl5or/n/qamY79ze0A==", shared)
+	if err != nil {
+		t.Fatalf("Decrypt() unexpected error: %v", err)
+	}
+	if got != "hello" {
+		t.Fatalf("Decrypt() = %q, want %q", got, "hello")
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	secret := bytes.Repeat([]byte{0x07}, 32)
+
+	for _, msg := range []string{"", "a", "hello hello", strings.Repeat("あ", 40)} {
+		content, err := Encrypt(msg, secret)
+		if err != nil {
+			t.Fatalf("Encrypt(%q) unexpected error: %v", msg, err)
+		}
+		got, err := Decrypt(content, secret)
+		if err != nil {
+			t.Fatalf("Decrypt(%q) unexpected error: %v", content, err)
+		}
+		if got != msg {
+			t.Fatalf("round trip = %q, want %q", got, msg)
+		}
+	}
+}
+
+func TestDecryptInvalid(t *testing.T) {
+	secret := bytes.Repeat([]byte{0x07}, 32)
+
+	tests := []struct {
+		name     string
+		content  string
+		contains string
+	}{
+		{name: "missing iv", content: "A+fRnU4aXS4kbTLfowqAww==", contains: "missing iv"},
+		{name: "bad base64", content: "***?iv=QFYUrl5or/n/qamY79ze0A==", contains: "decode ciphertext"},
+		{name: "short iv", content: "A+fRnU4aXS4kbTLfowqAww==?iv=AAAA", contains: "invalid iv length"},
+		{name: "wrong key", content: "A+fRnU4aXS4kbTLfowqAww==?iv=QFYUrl5or/n/qamY79ze0A==", contains: "invalid padding"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decrypt(tt.content, secret)
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Fatalf("Decrypt() error = %v, want %q", err, tt.contains)
+			}
+		})
+	}
+}
+
+func mustPublicKey(t *testing.T, priv []byte) string {
+	t.Helper()
+
+	pub, err := nostr.PublicKey(priv)
+	if err != nil {
+		t.Fatalf("PublicKey() unexpected error: %v", err)
+	}
+	return pub
+}
@@ -0,0 +1,171 @@
+// Package nip05 resolves NIP-05 internet identifiers (user@domain) to pubkeys.
+package nip05
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+const (
+	wellKnownPath  = "/.well-known/nostr.json"
+	defaultTimeout = 10 * time.Second
+	// maxResponseSize bounds nostr.json bodies; real files are a few KB.
+	maxResponseSize = 1 << 20
+)
+
+// ErrNotFound is returned when the domain does not list the requested name.
+var ErrNotFound = errors.New("nip05 name not found")
+
+// Pointer is the result of resolving an identifier.
+type Pointer struct {
+	PubKey string
+	// Relays are the relay hints published alongside the name, if any.
+	Relays []string
+}
+
+// IsIdentifier reports whether s looks like a NIP-05 identifier rather than
+// a hex key or bech32 string.
+func IsIdentifier(s string) bool {
+	_, _, err := ParseIdentifier(s)
+	return err == nil
+}
+
+// ParseIdentifier splits "name@domain" into its lowercased parts. A bare
+// "@domain" or "_@domain" addresses the domain's root identifier "_".
+func ParseIdentifier(s string) (name, domain string, err error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	at := strings.LastIndex(s, "@")
+	if at < 0 {
+		return "", "", fmt.Errorf("invalid nip05 identifier %q", s)
+	}
+	name, domain = s[:at], s[at+1:]
+	if name == "" {
+		name = "_"
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return "", "", fmt.Errorf("invalid nip05 identifier %q", s)
+		}
+	}
+	if domain == "" || strings.ContainsAny(domain, "/?#@ ") {
+		return "", "", fmt.Errorf("invalid nip05 identifier %q", s)
+	}
+	return name, domain, nil
+}
+
+// Resolver queries well-known documents over HTTPS.
+type Resolver struct {
+	httpClient *http.Client
+}
+
+// NewResolver creates a Resolver. httpClient may be nil to use a default
+// client; redirects are never followed, as required by NIP-05.
+func NewResolver(httpClient *http.Client) *Resolver {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	c := *httpClient
+	c.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &Resolver{httpClient: &c}
+}
+
+type wellKnown struct {
+	Names  map[string]string   `json:"names"`
+	Relays map[string][]string `json:"relays"`
+}
+
+// Resolve looks up identifier at https://<domain>/.well-known/nostr.json.
+func (r *Resolver) Resolve(ctx context.Context, identifier string) (Pointer, error) {
+	name, domain, err := ParseIdentifier(identifier)
+	if err != nil {
+		return Pointer{}, err
+	}
+
+	u := url.URL{
+		Scheme:   "https",
+		Host:     domain,
+		Path:     wellKnownPath,
+		RawQuery: url.Values{"name": {name}}.Encode(),
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Pointer{}, err
+	}
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := r.httpClient.Do(httpReq)
+	if err != nil {
+		return Pointer{}, fmt.Errorf("fetch %s: %w", u.String(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Pointer{}, fmt.Errorf("fetch %s: unexpected status %s", u.String(), resp.Status)
+	}
+
+	var doc wellKnown
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&doc); err != nil {
+		return Pointer{}, fmt.Errorf("decode %s: %w", u.String(), err)
+	}
+
+	raw, ok := lookupName(doc.Names, name)
+	if !ok {
+		return Pointer{}, fmt.Errorf("%w: %s@%s", ErrNotFound, name, domain)
+	}
+	// NIP-05 requires hex keys in nostr.json; ParsePubKey alone would also accept npub.
+	if !isHex(raw) {
+		return Pointer{}, fmt.Errorf("%s@%s: pubkey must be hex", name, domain)
+	}
+	pubkey, err := nostr.ParsePubKey(raw)
+	if err != nil {
+		return Pointer{}, fmt.Errorf("%s@%s: %w", name, domain, err)
+	}
+
+	return Pointer{PubKey: pubkey, Relays: doc.Relays[pubkey]}, nil
+}
+
+// Verify reports whether identifier resolves to pubkey. A missing name is
+// reported as false without an error.
+func (r *Resolver) Verify(ctx context.Context, identifier, pubkey string) (bool, error) {
+	ptr, err := r.Resolve(ctx, identifier)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return ptr.PubKey == strings.ToLower(pubkey), nil
+}
+
+// lookupName finds name in names, tolerating servers that keep mixed-case keys.
+func lookupName(names map[string]string, name string) (string, bool) {
+	if pubkey, ok := names[name]; ok {
+		return pubkey, true
+	}
+	for key, pubkey := range names {
+		if strings.ToLower(key) == name {
+			return pubkey, true
+		}
+	}
+	return "", false
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,129 @@
+package nip05
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	alicePub = "b0635d6a9851d3aed0cd6c495b282167acf761729078d975fc341b22650b07b9"
+	bobPub   = "32e1827635450ebb3c5a7d12c1f8e7b2b514439ac10a67eef3d9fd9c5c68e245"
+)
+
+func newDomain(t *testing.T) (*Resolver, string) {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != wellKnownPath {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("name") {
+		case "redirect":
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+			return
+		case "npub":
+			w.Write([]byte(`{"names":{"npub":"npub1kp346656z8f6a5xd43y4k2ppv7k0wctjjpudja0uxsdjyeggq7usdvl7hp"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"names": {"alice": "` + alicePub + `", "_": "` + bobPub + `", "Carol": "` + strings.ToUpper(bobPub) + `"},
+			"relays": {"` + alicePub + `": ["wss://relay.one", "wss://relay.two"]}
+		}`))
+	}))
+	t.Cleanup(server.Close)
+	return NewResolver(server.Client()), strings.TrimPrefix(server.URL, "https://")
+}
+
+func TestResolve(t *testing.T) {
+	resolver, domain := newDomain(t)
+
+	tests := []struct {
+		name       string
+		identifier string
+		wantPub    string
+		wantRelays int
+		wantErr    error
+		anyErr     bool
+	}{
+		{name: "name with relays", identifier: "Alice@" + domain, wantPub: alicePub, wantRelays: 2},
+		{name: "root identifier", identifier: "_@" + domain, wantPub: bobPub},
+		{name: "bare domain", identifier: "@" + domain, wantPub: bobPub},
+		{name: "mixed-case document", identifier: "carol@" + domain, wantPub: bobPub},
+		{name: "unknown name", identifier: "dave@" + domain, wantErr: ErrNotFound},
+		{name: "redirect not followed", identifier: "redirect@" + domain, anyErr: true},
+		{name: "npub rejected", identifier: "npub@" + domain, anyErr: true},
+		{name: "invalid identifier", identifier: "alice", anyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ptr, err := resolver.Resolve(context.Background(), tt.identifier)
+			if tt.wantErr != nil || tt.anyErr {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Fatalf("Resolve() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() unexpected error: %v", err)
+			}
+			if ptr.PubKey != tt.wantPub || len(ptr.Relays) != tt.wantRelays {
+				t.Fatalf("Resolve() = %+v, want pubkey %s with %d relays", ptr, tt.wantPub, tt.wantRelays)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	resolver, domain := newDomain(t)
+
+	ok, err := resolver.Verify(context.Background(), "alice@"+domain, alicePub)
+	if err != nil || !ok {
+		t.Fatalf("Verify(alice) = %v, %v; want true", ok, err)
+	}
+	ok, err = resolver.Verify(context.Background(), "alice@"+domain, bobPub)
+	if err != nil || ok {
+		t.Fatalf("Verify(alice, bob) = %v, %v; want false", ok, err)
+	}
+	ok, err = resolver.Verify(context.Background(), "dave@"+domain, alicePub)
+	if err != nil || ok {
+		t.Fatalf("Verify(dave) = %v, %v; want false without error", ok, err)
+	}
+}
+
+func TestParseIdentifier(t *testing.T) {
+	tests := []struct {
+		in         string
+		wantName   string
+		wantDomain string
+		wantErr    bool
+	}{
+		{in: "Bob@Example.com", wantName: "bob", wantDomain: "example.com"},
+		{in: "_@example.com", wantName: "_", wantDomain: "example.com"},
+		{in: "@example.com", wantName: "_", wantDomain: "example.com"},
+		{in: "example.com", wantErr: true},
+		{in: "bob@", wantErr: true},
+		{in: "b o b@example.com", wantErr: true},
+		{in: "bob@example.com/path", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			name, domain, err := ParseIdentifier(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseIdentifier() expected error")
+				}
+				return
+			}
+			if err != nil || name != tt.wantName || domain != tt.wantDomain {
+				t.Fatalf("ParseIdentifier() = %q, %q, %v", name, domain, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+// Package nip44 implements NIP-44 version 2 payload encryption.
+//
+// A conversation key is derived once per key pair from the secp256k1 ECDH
+// shared point, then every message gets a random 32-byte nonce from which the
+// ChaCha20 key/nonce and the HMAC-SHA256 key are expanded with HKDF.
+package nip44
+
+import (
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	version = 2
+
+	minPlaintextSize = 1
+	maxPlaintextSize = 65535
+
+	// base64 payload and decoded payload bounds defined by the spec.
+	minPayloadSize = 132
+	maxPayloadSize = 87472
+	minDecodedSize = 99
+	maxDecodedSize = 65603
+)
+
+var salt = []byte("nip44-v2")
+
+// ConversationKey derives the NIP-44 conversation key between a raw 32-byte
+// private key and a hex x-only public key. The result is symmetric:
+// ConversationKey(a, B) == ConversationKey(b, A).
+func ConversationKey(priv []byte, pubHex string) ([32]byte, error) {
+	var key [32]byte
+
+	if len(priv) != 32 {
+		return key, fmt.Errorf("invalid private key length: %d", len(priv))
+	}
+	var scalar btcec.ModNScalar
+	if overflow := scalar.SetByteSlice(priv); overflow || scalar.IsZero() {
+		return key, errors.New("invalid private key")
+	}
+
+	pubBytes, err := hex.DecodeString(pubHex)
+	if err != nil {
+		return key, fmt.Errorf("pubkey decode: %w", err)
+	}
+	pub, err := schnorr.ParsePubKey(pubBytes)
+	if err != nil {
+		return key, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	sk, _ := btcec.PrivKeyFromBytes(priv)
+	shared := btcec.GenerateSharedSecret(sk, pub)
+
+	prk, err := hkdf.Extract(sha256.New, shared, salt)
+	if err != nil {
+		return key, err
+	}
+	copy(key[:], prk)
+	return key, nil
+}
+
+// Encrypt encrypts plaintext with the conversation key and a random nonce and
+// returns the base64 payload.
+func Encrypt(plaintext string, conversationKey [32]byte) (string, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("read nonce: %w", err)
+	}
+	return encrypt(plaintext, conversationKey, nonce)
+}
+
+func encrypt(plaintext string, conversationKey [32]byte, nonce []byte) (string, error) {
+	if len(nonce) != 32 {
+		return "", fmt.Errorf("invalid nonce length: %d", len(nonce))
+	}
+
+	chachaKey, chachaNonce, hmacKey, err := messageKeys(conversationKey, nonce)
+	if err != nil {
+		return "", err
+	}
+
+	padded, err := pad(plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext := make([]byte, len(padded))
+	cipher, err := chacha20.NewUnauthenticatedCipher(chachaKey, chachaNonce)
+	if err != nil {
+		return "", err
+	}
+	cipher.XORKeyStream(ciphertext, padded)
+
+	mac := hmacAAD(hmacKey, ciphertext, nonce)
+
+	payload := make([]byte, 0, 1+len(nonce)+len(ciphertext)+len(mac))
+	payload = append(payload, version)
+	payload = append(payload, nonce...)
+	payload = append(payload, ciphertext...)
+	payload = append(payload, mac...)
+
+	return base64.StdEncoding.EncodeToString(payload), nil
+}
+
+// Decrypt verifies and decrypts a base64 NIP-44 v2 payload.
+func Decrypt(payload string, conversationKey [32]byte) (string, error) {
+	if payload != "" && payload[0] == '#' {
+		return "", errors.New("unknown version")
+	}
+	if len(payload) < minPayloadSize || len(payload) > maxPayloadSize {
+		return "", fmt.Errorf("invalid payload length: %d", len(payload))
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64: %w", err)
+	}
+	if len(data) < minDecodedSize || len(data) > maxDecodedSize {
+		return "", fmt.Errorf("invalid data length: %d", len(data))
+	}
+	if data[0] != version {
+		return "", fmt.Errorf("unknown version %d", data[0])
+	}
+
+	nonce := data[1:33]
+	ciphertext := data[33 : len(data)-32]
+	mac := data[len(data)-32:]
+
+	chachaKey, chachaNonce, hmacKey, err := messageKeys(conversationKey, nonce)
+	if err != nil {
+		return "", err
+	}
+
+	if !hmac.Equal(hmacAAD(hmacKey, ciphertext, nonce), mac) {
+		return "", errors.New("invalid hmac")
+	}
+
+	padded := make([]byte, len(ciphertext))
+	cipher, err := chacha20.NewUnauthenticatedCipher(chachaKey, chachaNonce)
+	if err != nil {
+		return "", err
+	}
+	cipher.XORKeyStream(padded, ciphertext)
+
+	return unpad(padded)
+}
+
+// messageKeys expands the per-message ChaCha20 key, ChaCha20 nonce and HMAC key.
+func messageKeys(conversationKey [32]byte, nonce []byte) ([]byte, []byte, []byte, error) {
+	keys, err := hkdf.Expand(sha256.New, conversationKey[:], string(nonce), 76)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return keys[0:32], keys[32:44], keys[44:76], nil
+}
+
+func hmacAAD(key, message, aad []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(aad)
+	h.Write(message)
+	return h.Sum(nil)
+}
+
+// calcPaddedLen returns the padded plaintext length used to hide message sizes.
+func calcPaddedLen(unpaddedLen int) int {
+	if unpaddedLen <= 32 {
+		return 32
+	}
+	nextPower := 1 << bits.Len(uint(unpaddedLen-1))
+	chunk := 32
+	if nextPower > 256 {
+		chunk = nextPower / 8
+	}
+	return chunk * ((unpaddedLen-1)/chunk + 1)
+}
+
+func pad(plaintext string) ([]byte, error) {
+	n := len(plaintext)
+	if n < minPlaintextSize || n > maxPlaintextSize {
+		return nil, fmt.Errorf("invalid plaintext length: %d", n)
+	}
+	padded := make([]byte, 2+calcPaddedLen(n))
+	binary.BigEndian.PutUint16(padded, uint16(n))
+	copy(padded[2:], plaintext)
+	return padded, nil
+}
+
+func unpad(padded []byte) (string, error) {
+	if len(padded) < 2 {
+		return "", errors.New("invalid padding")
+	}
+	n := int(binary.BigEndian.Uint16(padded))
+	if n < minPlaintextSize || 2+n > len(padded) || len(padded) != 2+calcPaddedLen(n) {
+		return "", errors.New("invalid padding")
+	}
+	return string(padded[2 : 2+n]), nil
+}
@@ -0,0 +1,375 @@
+package nip44
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"noscli/internal/nostr"
+)
+
+// Test vectors are taken from the reference nip44.vectors.json published with NIP-44.
+
+func TestConversationKey(t *testing.T) {
+	tests := []struct {
+		name string
+		sec1 string
+		pub2 string
+		want string
+	}{
+		{
+			name: "vector 1",
+			sec1: "315e59ff51cb9209768cf7da80791ddcaae56ac9775eb25b6dee1234bc5d2268",
+			pub2: "c2f9d9948dc8c7c38321e4b85c8558872eafa0641cd269db76848a6073e69133",
+			want: "3dfef0ce2a4d80a25e7a328accf73448ef67096f65f79588e358d9a0eb9013f1",
+		},
+		{
+			name: "vector 2",
+			sec1: "a1e37752c9fdc1273be53f68c5f74be7c8905728e8de75800b94262f9497c86e",
+			pub2: "03bb7947065dde12ba991ea045132581d0954f042c84e06d8c00066e23c1a800",
+			want: "4d14f36e81b8452128da64fe6f1eae873baae2f444b02c950b90e43553f2178b",
+		},
+		{
+			name: "vector 3",
+			sec1: "98a5902fd67518a0c900f0fb62158f278f94a21d6f9d33d30cd3091195500311",
+			pub2: "aae65c15f98e5e677b5050de82e3aba47a6fe49b3dab7863cf35d9478ba9f7d1",
+			want: "9c00b769d5f54d02bf175b7284a1cbd28b6911b06cda6666b2243561ac96bad7",
+		},
+		{
+			name: "vector 4",
+			sec1: "86ae5ac8034eb2542ce23ec2f84375655dab7f836836bbd3c54cefe9fdc9c19f",
+			pub2: "59f90272378089d73f1339710c02e2be6db584e9cdbe86eed3578f0c67c23585",
+			want: "19f934aafd3324e8415299b64df42049afaa051c71c98d0aa10e1081f2e3e2ba",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConversationKey(mustHex(t, tt.sec1), tt.pub2)
+			if err != nil {
+				t.Fatalf("ConversationKey() unexpected error: %v", err)
+			}
+			if hex.EncodeToString(got[:]) != tt.want {
+				t.Fatalf("ConversationKey() = %x, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConversationKeyInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		sec1 string
+		pub2 string
+	}{
+		{
+			name: "sec1 is zero",
+			sec1: "0000000000000000000000000000000000000000000000000000000000000000",
+			pub2: "1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef",
+		},
+		{
+			name: "sec1 equals curve order",
+			sec1: "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141",
+			pub2: "1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef",
+		},
+		{
+			name: "pub2 is not on the curve",
+			sec1: "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364139",
+			pub2: "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ConversationKey(mustHex(t, tt.sec1), tt.pub2); err == nil {
+				t.Fatalf("ConversationKey() expected error")
+			}
+		})
+	}
+}
+
+func TestMessageKeys(t *testing.T) {
+	tests := []struct {
+		name        string
+		convKey     string
+		nonce       string
+		chachaKey   string
+		chachaNonce string
+		hmacKey     string
+	}{
+		{
+			name:        "vector 1",
+			convKey:     "a1a3d60f3470a8612633924e91febf96dc5366ce130f658b1f0fc652c20b3b54",
+			nonce:       "e1e6f880560d6d149ed83dcc7e5861ee62a5ee051f7fde9975fe5d25d2a02d72",
+			chachaKey:   "f145f3bed47cb70dbeaac07f3a3fe683e822b3715edb7c4fe310829014ce7d76",
+			chachaNonce: "c4ad129bb01180c0933a160c",
+			hmacKey:     "027c1db445f05e2eee864a0975b0ddef5b7110583c8c192de3732571ca5838c4",
+		},
+		{
+			name:        "vector 2",
+			convKey:     "a1a3d60f3470a8612633924e91febf96dc5366ce130f658b1f0fc652c20b3b54",
+			nonce:       "e1d6d28c46de60168b43d79dacc519698512ec35e8ccb12640fc8e9f26121101",
+			chachaKey:   "e35b88f8d4a8f1606c5082f7a64b100e5d85fcdb2e62aeafbec03fb9e860ad92",
+			chachaNonce: "22925e920cee4a50a478be90",
+			hmacKey:     "46a7c55d4283cb0df1d5e29540be67abfe709e3b2e14b7bf9976e6df994ded30",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chachaKey, chachaNonce, hmacKey, err := messageKeys(mustKey(t, tt.convKey), mustHex(t, tt.nonce))
+			if err != nil {
+				t.Fatalf("messageKeys() unexpected error: %v", err)
+			}
+			if hex.EncodeToString(chachaKey) != tt.chachaKey {
+				t.Fatalf("chacha key = %x, want %s", chachaKey, tt.chachaKey)
+			}
+			if hex.EncodeToString(chachaNonce) != tt.chachaNonce {
+				t.Fatalf("chacha nonce = %x, want %s", chachaNonce, tt.chachaNonce)
+			}
+			if hex.EncodeToString(hmacKey) != tt.hmacKey {
+				t.Fatalf("hmac key = %x, want %s", hmacKey, tt.hmacKey)
+			}
+		})
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	tests := []struct {
+		name      string
+		sec1      string
+		sec2      string
+		convKey   string
+		nonce     string
+		plaintext string
+		payload   string
+	}{
+		{
+			name:      "single character",
+			sec1:      "0000000000000000000000000000000000000000000000000000000000000001",
+			sec2:      "0000000000000000000000000000000000000000000000000000000000000002",
+			convKey:   "c41c775356fd92eadc63ff5a0dc1da211b268cbea22316767095b2871ea1412d",
+			nonce:     "0000000000000000000000000000000000000000000000000000000000000001",
+			plaintext: "a",
+			payload:   "AgAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABee0G5VSK0/9YypIObAtDKfYEAjD35uVkHyB0F4DwrcNaCXlCWZKaArsGrY6M9wnuTMxWfp1RTN9Xga8no+kF5Vsb",
+		},
+		{
+			name:      "emoji",
+			sec1:      "0000000000000000000000000000000000000000000000000000000000000002",
+			sec2:      "0000000000000000000000000000000000000000000000000000000000000001",
+			convKey:   "c41c775356fd92eadc63ff5a0dc1da211b268cbea22316767095b2871ea1412d",
+			nonce:     "f00000000000000000000000000000f00000000000000000000000000000000f",
+			plaintext: "🍕🫃",
+			payload:   "AvAAAAAAAAAAAAAAAAAAAPAAAAAAAAAAAAAAAAAAAAAPSKSK6is9ngkX2+cSq85Th16oRTISAOfhStnixqZziKMDvB0QQzgFZdjLTPicCJaV8nDITO+QfaQ61+KbWQIOO2Yj",
+		},
+		{
+			name:      "mixed scripts",
+			sec1:      "5c0c523f52a5b6fad39ed2403092df8cebc36318b39383bca6c00808626fab3a",
+			sec2:      "4b22aa260e4acb7021e32f38a6cdf4b673c6a277755bfce287e370c924dc936d",
+			convKey:   "3e2b52a63be47d34fe0a80e34e73d436d6963bc8f39827f327057a9986c20a45",
+			nonce:     "b635236c42db20f021bb8d1cdff5ca75dd1a0cc72ea742ad750f33010b24f73b",
+			plaintext: "表ポあA鷗ŒéＢ逍Üßªąñ丂㐀𠀀",
+			payload:   "ArY1I2xC2yDwIbuNHN/1ynXdGgzHLqdCrXUPMwELJPc7s7JqlCMJBAIIjfkpHReBPXeoMCyuClwgbT419jUWU1PwaNl4FEQYKCDKVJz+97Mp3K+Q2YGa77B6gpxB/lr1QgoqpDf7wDVrDmOqGoiPjWDqy8KzLueKDcm9BVP8xeTJIxs=",
+		},
+		{
+			name:      "ascii and emoji",
+			sec1:      "8f40e50a84a7462e2b8d24c28898ef1f23359fff50d8c509e6fb7ce06e142f9c",
+			sec2:      "b9b0a1e9cc20100c5faa3bbe2777303d25950616c4c6a3fa2e3e046f936ec2ba",
+			convKey:   "d5a2f879123145a4b291d767428870f5a8d9e5007193321795b40183d4ab8c2b",
+			nonce:     "b20989adc3ddc41cd2c435952c0d59a91315d8c5218d5040573fc3749543acaf",
+			plaintext: "ability🤝的 ȺȾ",
+			payload:   "ArIJia3D3cQc0sQ1lSwNWakTFdjFIY1QQFc/w3SVQ6yvbG2S0x4Yu86QGwPTy7mP3961I1XqB6SFFTzqDZZavhxoWMj7mEVGMQIsh2RLWI5EYQaQDIePSnXPlzf7CIt+voTD",
+		},
+		{
+			name:      "cjk",
+			sec1:      "d5633530f5bcfebceb5584cfbbf718a30df0751b729dd9a789b9f30c0587d74e",
+			sec2:      "b74e6a341fb134127272b795a08b59250e5fa45a82a2eb4095e4ce9ed5f5e214",
+			convKey:   "75fe686d21a035f0c7cd70da64ba307936e5ca0b20710496a6b6b5f573377bdd",
+			nonce:     "4f1a31909f3483a9e69c8549a55bbc9af25fa5bbecf7bd32d9896f83ef2e12e0",
+			plaintext: "𝖑𝖆𝖟𝖞 社會科學院語學研究所",
+			payload:   "Ak8aMZCfNIOp5pyFSaVbvJryX6W77Pe9MtmJb4PvLhLgh/TsxPLFSANcT67EC1t/qxjru5ZoADjKVEt2ejdx+xGvH49mcdfbc+l+L7gJtkH7GLKpE9pQNQWNHMAmj043PAXJZ++fiJObMRR2mye5VHEANzZWkZXMrXF7YjuG10S1pOU=",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub2, err := nostr.PublicKey(mustHex(t, tt.sec2))
+			if err != nil {
+				t.Fatalf("PublicKey() unexpected error: %v", err)
+			}
+			convKey, err := ConversationKey(mustHex(t, tt.sec1), pub2)
+			if err != nil {
+				t.Fatalf("ConversationKey() unexpected error: %v", err)
+			}
+			if hex.EncodeToString(convKey[:]) != tt.convKey {
+				t.Fatalf("ConversationKey() = %x, want %s", convKey, tt.convKey)
+			}
+
+			payload, err := encrypt(tt.plaintext, convKey, mustHex(t, tt.nonce))
+			if err != nil {
+				t.Fatalf("encrypt() unexpected error: %v", err)
+			}
+			if payload != tt.payload {
+				t.Fatalf("encrypt() = %s, want %s", payload, tt.payload)
+			}
+
+			plaintext, err := Decrypt(tt.payload, convKey)
+			if err != nil {
+				t.Fatalf("Decrypt() unexpected error: %v", err)
+			}
+			if plaintext != tt.plaintext {
+				t.Fatalf("Decrypt() = %q, want %q", plaintext, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestEncryptLong(t *testing.T) {
+	tests := []struct {
+		name          string
+		convKey       string
+		nonce         string
+		pattern       string
+		repeat        int
+		payloadSHA256 string
+	}{
+		{
+			name:          "max length x",
+			convKey:       "8fc262099ce0d0bb9b89bac05bb9e04f9bc0090acc181fef6840ccee470371ed",
+			nonce:         "326bcb2c943cd6bb717588c9e5a7e738edf6ed14ec5f5344caa6ef56f0b9cff7",
+			pattern:       "x",
+			repeat:        65535,
+			payloadSHA256: "90714492225faba06310bff2f249ebdc2a5e609d65a629f1c87f2d4ffc55330a",
+		},
+		{
+			name:          "max length exclamation",
+			convKey:       "56adbe3720339363ab9c3b8526ffce9fd77600927488bfc4b59f7a68ffe5eae0",
+			nonce:         "ad68da81833c2a8ff609c3d2c0335fd44fe5954f85bb580c6a8d467aa9fc5dd0",
+			pattern:       "!",
+			repeat:        65535,
+			payloadSHA256: "8013e45a109fad3362133132b460a2d5bce235fe71c8b8f4014793fb52a49844",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext := strings.Repeat(tt.pattern, tt.repeat)
+			payload, err := encrypt(plaintext, mustKey(t, tt.convKey), mustHex(t, tt.nonce))
+			if err != nil {
+				t.Fatalf("encrypt() unexpected error: %v", err)
+			}
+			sum := sha256.Sum256([]byte(payload))
+			if hex.EncodeToString(sum[:]) != tt.payloadSHA256 {
+				t.Fatalf("payload sha256 = %x, want %s", sum, tt.payloadSHA256)
+			}
+
+			decrypted, err := Decrypt(payload, mustKey(t, tt.convKey))
+			if err != nil {
+				t.Fatalf("Decrypt() unexpected error: %v", err)
+			}
+			if decrypted != plaintext {
+				t.Fatalf("Decrypt() did not round-trip")
+			}
+		})
+	}
+}
+
+func TestDecryptInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		convKey  string
+		payload  string
+		contains string
+	}{
+		{
+			name:     "unknown version marker",
+			convKey:  "ca2527a037347b91bea0c8a30fc8d9600ffd81ec00038671e3a0f0cb0fc9f642",
+			payload:  "#Atqupco0WyaOW2IGDKcshwxI9xO8HgD/P8Ddt46CbxDbrhdG8VmJdU0MIDf06CUvEvdnr1cp1fiMtlM/GrE92xAc1K5odTpCzUB+mjXgbaqtntBUbTToSUoT0ovrlPwzGjyp",
+			contains: "unknown version",
+		},
+		{
+			name:     "version 0",
+			convKey:  "36f04e558af246352dcf73b692fbd3646a2207bd8abd4b1cd26b234db84d9481",
+			payload:  "AK1AjUvoYW3IS7C/BGRUoqEC7ayTfDUgnEPNeWTF/reBZFaha6EAIRueE9D1B1RuoiuFScC0Q94yjIuxZD3JStQtE8JMNacWFs9rlYP+ZydtHhRucp+lxfdvFlaGV/sQlqZz",
+			contains: "unknown version 0",
+		},
+		{
+			name:     "invalid base64",
+			convKey:  "ca2527a037347b91bea0c8a30fc8d9600ffd81ec00038671e3a0f0cb0fc9f642",
+			payload:  "Atфupco0WyaOW2IGDKcshwxI9xO8HgD/P8Ddt46CbxDbrhdG8VmJZE0UICD06CUvEvdnr1cp1fiMtlM/GrE92xAc1EwsVCQEgWEu2gsHUVf4JAa3TpgkmFc3TWsax0v6n/Wq",
+			contains: "invalid base64",
+		},
+		{
+			name:     "invalid hmac",
+			convKey:  "cff7bd6a3e29a450fd27f6c125d5edeb0987c475fd1e8d97591e0d4d8a89763c",
+			payload:  "Agn/l3ULCEAS4V7LhGFM6IGA17jsDUaFCKhrbXDANholyySBfeh+EN8wNB9gaLlg4j6wdBYh+3oK+mnxWu3NKRbSvQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+			contains: "invalid hmac",
+		},
+		{
+			name:     "invalid padding",
+			convKey:  "5254827d29177622d40a7b67cad014fe7137700c3c523903ebbe3e1b74d40214",
+			payload:  "Anq2XbuLvCuONcr7V0UxTh8FAyWoZNEdBHXvdbNmDZHB573MI7R7rrTYftpqmvUpahmBC2sngmI14/L0HjOZ7lWGJlzdh6luiOnGPc46cGxf08MRC4CIuxx3i2Lm0KqgJ7vA",
+			contains: "invalid padding",
+		},
+		{
+			name:     "empty payload",
+			convKey:  "5cd2d13b9e355aeb2452afbd3786870dbeecb9d355b12cb0a3b6e9da5744cd35",
+			payload:  "",
+			contains: "invalid payload length: 0",
+		},
+		{
+			name:     "short payload",
+			convKey:  "873bb0fc665eb950a8e7d5971965539f6ebd645c83c08cd6a85aafbad0f0bc47",
+			payload:  "AqxgToSh3H7iLYRJjoWAM+vSv/Y1mgNlm6OWWjOYUClrFF8=",
+			contains: "invalid payload length: 48",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decrypt(tt.payload, mustKey(t, tt.convKey))
+			if err == nil {
+				t.Fatalf("Decrypt() expected error")
+			}
+			if !strings.Contains(err.Error(), tt.contains) {
+				t.Fatalf("Decrypt() error %q does not contain %q", err.Error(), tt.contains)
+			}
+		})
+	}
+}
+
+func TestCalcPaddedLen(t *testing.T) {
+	tests := []struct {
+		in   int
+		want int
+	}{
+		{1, 32}, {16, 32}, {32, 32}, {33, 64}, {37, 64}, {45, 64}, {49, 64}, {64, 64},
+		{65, 96}, {100, 128}, {111, 128}, {200, 224}, {250, 256}, {320, 320}, {383, 384},
+		{384, 384}, {400, 448}, {500, 512}, {1000, 1024}, {1024, 1024}, {65515, 65536},
+	}
+
+	for _, tt := range tests {
+		if got := calcPaddedLen(tt.in); got != tt.want {
+			t.Fatalf("calcPaddedLen(%d) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode hex %q: %v", s, err)
+	}
+	return b
+}
+
+func mustKey(t *testing.T, s string) [32]byte {
+	t.Helper()
+
+	var key [32]byte
+	copy(key[:], mustHex(t, s))
+	return key
+}
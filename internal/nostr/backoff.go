@@ -0,0 +1,51 @@
+package nostr
+
+import (
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrMaxAttempts is reported by Stream when it stops reconnecting to a relay.
+var ErrMaxAttempts = errors.New("giving up reconnecting")
+
+// resumeWindow is subtracted from the newest received created_at when Stream
+// resubscribes, since relays may store events with slightly older timestamps
+// after the ones they already sent. Duplicates are filtered out.
+const resumeWindow = time.Minute
+
+// ClientOption customizes a Client created by NewClient.
+type ClientOption func(*Client)
+
+// WithBackoff sets the delay before Stream's first reconnect and the cap it
+// doubles up to on consecutive failures.
+func WithBackoff(initial, max time.Duration) ClientOption {
+	return func(c *Client) {
+		c.backoff = initial
+		c.maxBackoff = max
+	}
+}
+
+// WithMaxAttempts makes Stream give up after n consecutive failed
+// connections. Zero, the default, retries until ctx is done.
+func WithMaxAttempts(n int) ClientOption {
+	return func(c *Client) {
+		c.maxAttempts = n
+	}
+}
+
+// backoffDelay returns how long to wait after the given number of
+// consecutive failures: the initial delay doubled per failure up to the cap,
+// of which a random half is kept so clients do not reconnect in lockstep.
+func (c *Client) backoffDelay(failures int) time.Duration {
+	d := c.backoff
+	for i := 1; i < failures && d < c.maxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, c.maxBackoff)
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
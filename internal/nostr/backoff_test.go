@@ -0,0 +1,53 @@
+package nostr
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	c := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), WithBackoff(time.Second, 10*time.Second))
+
+	tests := []struct {
+		failures int
+		ceiling  time.Duration
+	}{
+		{failures: 1, ceiling: time.Second},
+		{failures: 2, ceiling: 2 * time.Second},
+		{failures: 3, ceiling: 4 * time.Second},
+		{failures: 4, ceiling: 8 * time.Second},
+		{failures: 5, ceiling: 10 * time.Second},
+		{failures: 60, ceiling: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		for range 50 {
+			d := c.backoffDelay(tt.failures)
+			if d < tt.ceiling/2 || d > tt.ceiling {
+				t.Fatalf("backoffDelay(%d) = %s, want within [%s, %s]", tt.failures, d, tt.ceiling/2, tt.ceiling)
+			}
+		}
+	}
+}
+
+func TestResumeSince(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name   string
+		newest int64
+		want   time.Time
+	}{
+		{name: "nothing received", newest: 0, want: start},
+		{name: "recent event", newest: start.Unix() + 3600, want: start.Add(time.Hour - resumeWindow)},
+		{name: "window before start", newest: start.Unix() + 10, want: start},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resumeSince(start, tt.newest); !got.Equal(tt.want) {
+				t.Fatalf("resumeSince() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,59 +21,185 @@ type Client struct {
 	dialer      *websocket.Dialer
 	logger      *slog.Logger
 	readTimeout time.Duration
+	// backoff is the first reconnect delay of Stream, doubled up to maxBackoff.
 	backoff     time.Duration
+	maxBackoff  time.Duration
+	maxAttempts int
+	// pingInterval and pongTimeout control the keepalive of streaming subscriptions.
+	pingInterval time.Duration
+	pongTimeout  time.Duration
+	stats        *statsRegistry
 }
 
-// NewClient creates a Client with sane defaults.
-func NewClient(logger *slog.Logger) *Client {
+// NewClient creates a Client with sane defaults, adjusted by opts.
+func NewClient(logger *slog.Logger, opts ...ClientOption) *Client {
 	dialer := *websocket.DefaultDialer
 	dialer.Proxy = http.ProxyFromEnvironment
 
-	return &Client{
-		dialer:      &dialer,
-		logger:      logger,
-		readTimeout: 30 * time.Second,
-		backoff:     3 * time.Second,
+	c := &Client{
+		dialer:       &dialer,
+		logger:       logger,
+		readTimeout:  30 * time.Second,
+		backoff:      2 * time.Second,
+		maxBackoff:   2 * time.Minute,
+		pingInterval: 30 * time.Second,
+		pongTimeout:  15 * time.Second,
+		stats:        newStatsRegistry(),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// RelayStats returns the state and counters of relay, if the client has used it.
+func (c *Client) RelayStats(relay string) (RelayStats, bool) {
+	return c.stats.get(relay)
+}
+
+// Stats returns the state and counters of every relay the client has used,
+// sorted by URL.
+func (c *Client) Stats() []RelayStats {
+	return c.stats.all()
+}
+
+// dial opens a WebSocket connection to relay and records the attempt in the
+// relay's stats. Callers must pass the connection to hangUp when done.
+func (c *Client) dial(ctx context.Context, relay string) (*websocket.Conn, error) {
+	c.stats.dialing(relay)
+	start := time.Now()
+	conn, _, err := c.dialer.DialContext(ctx, relay, nil)
+	if err != nil {
+		err = fmt.Errorf("dial %s: %w", relay, err)
+		c.stats.failed(relay, StateFailed, err)
+		return nil, err
+	}
+	c.stats.connected(relay, time.Since(start))
+	return conn, nil
+}
+
+// hangUp closes conn and records why the connection ended; err may be nil.
+func (c *Client) hangUp(conn *websocket.Conn, relay string, err error) {
+	conn.Close()
+	if errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	c.stats.closed(relay, err)
 }
 
 // Stream subscribes to a single relay and emits events until ctx is done.
+// When filter.Since is nil only events created after the first connect are
+// requested. After a disconnect it reconnects with exponential backoff and
+// resubscribes from the newest event received, so notes published during the
+// outage are delivered once and none are repeated. Events dated in the future
+// count as received now.
 func (c *Client) Stream(ctx context.Context, relay string, filter Filter) (<-chan Event, <-chan error) {
-	events := make(chan Event, 64)
+	return c.Subscribe(ctx, relay, filter, nil)
+}
+
+// Subscribe is Stream with a hook: onMessage, which may be nil, receives the
+// relay's EOSE, CLOSED and NOTICE messages as well as MessageConnected and
+// MessageDisconnected whenever the connection is established or lost. With a
+// hook the event channel is unbuffered, so every event received before a
+// message has been taken from the channel when onMessage is called.
+// With WithMaxAttempts the stream ends after that many consecutive failed
+// connections, reporting an error wrapping ErrMaxAttempts.
+func (c *Client) Subscribe(ctx context.Context, relay string, filter Filter, onMessage func(RelayMessage)) (<-chan Event, <-chan error) {
+	buffer := 64
+	if onMessage != nil {
+		buffer = 0
+	}
+	events := make(chan Event, buffer)
 	errs := make(chan error, 1)
 
 	go func() {
 		defer close(events)
 		defer close(errs)
 
-		backoff := c.backoff
-		for {
-			if ctx.Err() != nil {
-				return
+		var (
+			since       time.Time
+			newest      int64
+			seen        = NewSeenSet(seenCapacity)
+			failures    int
+			connections int
+		)
+		if filter.Since != nil {
+			since = *filter.Since
+		}
+
+		for ctx.Err() == nil {
+			// answered は接続が EOSE かイベントを返したかを示し、失敗回数のリセットに使う
+			answered := false
+			sub := subscription{
+				filter: filter,
+				onMessage: func(msg RelayMessage) {
+					if msg.Type == MessageEOSE {
+						answered = true
+					}
+					if onMessage != nil {
+						onMessage(msg)
+					}
+				},
+				accept: func(evt Event) bool {
+					answered = true
+					if !seen.Add(evt.ID) {
+						return false
+					}
+					// 未来日時のイベントで再開位置が先へ飛ばないよう現在時刻で頭打ちにする
+					newest = max(newest, min(evt.CreatedAt, time.Now().Unix()))
+					return true
+				},
 			}
 
-			conn, _, err := c.dialer.DialContext(ctx, relay, nil)
-			if err != nil {
-				c.emitError(errs, fmt.Errorf("dial %s: %w", relay, err))
-				if !c.wait(ctx, backoff) {
+			conn, err := c.dial(ctx, relay)
+			if err == nil {
+				if connections > 0 {
+					c.stats.update(relay, func(rec *relayRecord) { rec.stats.Reconnects++ })
+				}
+				connections++
+				c.logger.Info("connected to relay", "relay", relay)
+				sub.report(RelayMessage{Relay: relay, Type: MessageConnected})
+				if since.IsZero() {
+					since = time.Now()
+				}
+				sub.filter.Since = resumeSince(since, newest)
+				err = c.runSubscription(ctx, conn, relay, sub, events)
+				c.hangUp(conn, relay, err)
+				if ctx.Err() != nil {
+					sub.report(RelayMessage{Relay: relay, Type: MessageDisconnected})
 					return
 				}
-				continue
+				if err == nil {
+					err = errors.New("subscription ended")
+				}
+				err = fmt.Errorf("relay %s: %w", relay, err)
+			}
+			if ctx.Err() != nil {
+				return
 			}
 
-			c.logger.Info("connected to relay", "relay", relay)
-			err = c.runSubscription(ctx, conn, relay, filter, events)
-			conn.Close()
-
-			if err != nil {
-				if errors.Is(err, context.Canceled) {
-					return
-				}
-				c.emitError(errs, fmt.Errorf("relay %s: %w", relay, err))
-				if !c.wait(ctx, backoff) {
-					return
+			if answered {
+				failures = 0
+			}
+			failures++
+			if c.maxAttempts > 0 && failures >= c.maxAttempts {
+				err = fmt.Errorf("%w after %d failed attempts: %w", ErrMaxAttempts, failures, err)
+				sub.report(RelayMessage{Relay: relay, Type: MessageDisconnected, Message: err.Error()})
+				c.stats.failed(relay, StateFailed, err)
+				select {
+				case errs <- err:
+				case <-ctx.Done():
 				}
-				continue
+				return
+			}
+
+			sub.report(RelayMessage{Relay: relay, Type: MessageDisconnected, Message: err.Error()})
+			c.emitError(errs, err)
+			c.stats.failed(relay, StateBackoff, err)
+			delay := c.backoffDelay(failures)
+			c.logger.Debug("reconnecting to relay", "relay", relay, "delay", delay, "failures", failures)
+			if !c.wait(ctx, delay) {
+				return
 			}
 		}
 	}()
@@ -80,16 +207,91 @@ func (c *Client) Stream(ctx context.Context, relay string, filter Filter) (<-cha
 	return events, errs
 }
 
+// resumeSince returns the since to subscribe with: the newest received
+// created_at minus resumeWindow, but never earlier than the original since.
+func resumeSince(since time.Time, newest int64) *time.Time {
+	if newest > 0 {
+		if resume := time.Unix(newest, 0).Add(-resumeWindow); resume.After(since) {
+			return &resume
+		}
+	}
+	return &since
+}
+
+// Fetch sends a one-shot REQ to relay and returns the stored events received before EOSE.
+func (c *Client) Fetch(ctx context.Context, relay string, filter Filter) ([]Event, error) {
+	conn, err := c.dial(ctx, relay)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event)
+	done := make(chan error, 1)
+	go func() {
+		defer close(events)
+		done <- c.runSubscription(ctx, conn, relay, subscription{filter: filter, closeOnEOSE: true}, events)
+	}()
+
+	var result []Event
+	for evt := range events {
+		result = append(result, evt)
+	}
+	err = <-done
+	c.hangUp(conn, relay, err)
+	if err != nil {
+		return result, fmt.Errorf("relay %s: %w", relay, err)
+	}
+	return result, nil
+}
+
+// Req sends filter to relay as-is and emits the matching events. EOSE,
+// CLOSED and NOTICE messages are passed to onMessage, which may be nil.
+// Unlike Stream it neither reconnects nor rewrites the filter: with
+// closeOnEOSE it ends after stored events, otherwise it runs until ctx is done
+// or the relay closes the subscription. errs receives at most one error.
+func (c *Client) Req(ctx context.Context, relay string, filter Filter, closeOnEOSE bool, onMessage func(RelayMessage)) (<-chan Event, <-chan error) {
+	events := make(chan Event, 64)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(events)
+		defer close(errs)
+
+		conn, err := c.dial(ctx, relay)
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		sub := subscription{filter: filter, closeOnEOSE: closeOnEOSE, onMessage: onMessage}
+		err = c.runSubscription(ctx, conn, relay, sub, events)
+		c.hangUp(conn, relay, err)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			errs <- fmt.Errorf("relay %s: %w", relay, err)
+		}
+	}()
+
+	return events, errs
+}
+
 // Publish sends a single event to the specified relay and waits for an OK response.
 func (c *Client) Publish(ctx context.Context, relay string, evt Event) error {
-	conn, _, err := c.dialer.DialContext(ctx, relay, nil)
+	_, err := c.PublishAck(ctx, relay, evt)
+	return err
+}
+
+// PublishAck is Publish that also returns the message of an accepted OK,
+// e.g. "duplicate: already have this event", which Publish discards.
+func (c *Client) PublishAck(ctx context.Context, relay string, evt Event) (string, error) {
+	conn, err := c.dial(ctx, relay)
 	if err != nil {
-		return fmt.Errorf("dial %s: %w", relay, err)
+		return "", err
 	}
-	defer conn.Close()
+	// 拒否は接続の問題ではないため LastError には残さない
+	defer c.hangUp(conn, relay, nil)
 
 	if err := conn.WriteJSON([]any{"EVENT", evt}); err != nil {
-		return fmt.Errorf("write EVENT: %w", err)
+		return "", fmt.Errorf("write EVENT: %w", err)
 	}
 
 	// Wait for one OK message with a bounded timeout.
@@ -101,26 +303,91 @@ func (c *Client) Publish(ctx context.Context, relay string, evt Event) error {
 		_ = conn.SetReadDeadline(time.Now().Add(c.readTimeout))
 	}
 
-	_, data, err := conn.ReadMessage()
-	if err != nil {
-		return fmt.Errorf("read OK: %w", err)
+	// OK より先に AUTH や NOTICE が届くリレーがあるため、OK 以外は読み飛ばす
+	var data []byte
+	for {
+		_, data, err = conn.ReadMessage()
+		if err != nil {
+			return "", fmt.Errorf("read OK: %w", err)
+		}
+		if msgType := messageType(data); msgType != "OK" {
+			c.logger.Debug("ignore message while waiting for OK", "relay", relay, "type", msgType)
+			continue
+		}
+		break
 	}
 
 	res, err := parseOKMessage(data)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if !res.OK {
-		return fmt.Errorf("relay %s rejected event %s: %s", relay, res.EventID, res.Message)
+		return "", &RejectedError{Relay: relay, EventID: res.EventID, Message: res.Message}
 	}
 
 	if res.EventID != "" && !strings.EqualFold(res.EventID, evt.ID) {
-		return fmt.Errorf("relay %s returned mismatched id: %s", relay, res.EventID)
+		return "", fmt.Errorf("relay %s returned mismatched id: %s", relay, res.EventID)
 	}
 
 	c.logger.Info("published event", "relay", relay, "id", evt.ID)
-	return nil
+	return res.Message, nil
+}
+
+// RejectedError is returned by Publish when a relay answers OK with false.
+type RejectedError struct {
+	Relay   string
+	EventID string
+	Message string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("relay %s rejected event %s: %s", e.Relay, e.EventID, e.Message)
+}
+
+// Reason returns the machine-readable prefix of the OK message (e.g. "pow",
+// "blocked", "rate-limited") as defined by NIP-01, or "" if there is none.
+func (e *RejectedError) Reason() string {
+	prefix, _, ok := strings.Cut(e.Message, ":")
+	if !ok || strings.ContainsAny(prefix, " \t") {
+		return ""
+	}
+	return prefix
+}
+
+// RequiredPoW reports whether the relay rejected the event for insufficient
+// proof of work and, if the message mentions one, the difficulty it asked for.
+// Relays phrase this differently ("pow: difficulty 18 is less than 24"), so the
+// largest number in the message is taken as the requirement.
+func (e *RejectedError) RequiredPoW() (int, bool) {
+	if e.Reason() != "pow" {
+		return 0, false
+	}
+	required := 0
+	for _, field := range strings.FieldsFunc(e.Message, func(r rune) bool { return r < '0' || r > '9' }) {
+		n, err := strconv.Atoi(field)
+		if err != nil || n > 256 {
+			continue
+		}
+		if n > required {
+			required = n
+		}
+	}
+	return required, true
+}
+
+// messageType returns the type of a relay message, or "" if it is not a JSON
+// array starting with a string.
+func messageType(data []byte) string {
+	var payload []json.RawMessage
+	if err := json.Unmarshal(data, &payload); err != nil || len(payload) == 0 {
+		return ""
+	}
+	var msgType string
+	if err := json.Unmarshal(payload[0], &msgType); err != nil {
+		return ""
+	}
+	return msgType
 }
 
 // okResult represents a parsed Nostr OK message.
@@ -167,18 +434,58 @@ func parseOKMessage(data []byte) (okResult, error) {
 	return res, nil
 }
 
-func (c *Client) runSubscription(ctx context.Context, conn *websocket.Conn, relay string, filter Filter, events chan<- Event) error {
-	subID := randomSubID()
+// subscription describes a single REQ sent by runSubscription.
+type subscription struct {
+	filter Filter
+	// closeOnEOSE ends the subscription once the relay has sent all stored events.
+	closeOnEOSE bool
+	// onMessage, when set, receives the EOSE, CLOSED and NOTICE messages of the subscription.
+	onMessage func(RelayMessage)
+	// accept, when set, decides whether a verified event is delivered.
+	accept func(Event) bool
+}
+
+func (s subscription) report(msg RelayMessage) {
+	if s.onMessage != nil {
+		s.onMessage(msg)
+	}
+}
+
+// Relay message types reported through RelayMessage.
+const (
+	MessageEOSE   = "EOSE"
+	MessageClosed = "CLOSED"
+	MessageNotice = "NOTICE"
+	// MessageConnected and MessageDisconnected are not sent by relays; Subscribe
+	// reports them on connection changes. Message holds the cause of a disconnect.
+	MessageConnected    = "CONNECTED"
+	MessageDisconnected = "DISCONNECTED"
+)
 
-	filterCopy := filter
-	now := time.Now()
-	filterCopy.Since = &now
+// RelayMessage is a non-EVENT message received while a subscription is open,
+// or a connection change reported by Subscribe.
+type RelayMessage struct {
+	Relay string
+	// Type is one of the Message* constants.
+	Type    string
+	Message string
+}
+
+func (c *Client) runSubscription(ctx context.Context, conn *websocket.Conn, relay string, sub subscription, events chan<- Event) error {
+	subID := randomSubID()
 
-	req := []any{"REQ", subID, filterCopy.toRequest()}
+	req := []any{"REQ", subID, sub.filter.toRequest()}
 	if err := conn.WriteJSON(req); err != nil {
 		return err
 	}
 
+	// 保存済みイベントだけを待つ購読は従来どおり無通信で打ち切り、ストリームは ping で生存確認する
+	keepalive := !sub.closeOnEOSE && c.pingInterval > 0
+	if keepalive {
+		stop := c.keepAlive(ctx, conn)
+		defer stop()
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -187,9 +494,15 @@ func (c *Client) runSubscription(ctx context.Context, conn *websocket.Conn, rela
 		default:
 		}
 
-		_ = conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+		_ = conn.SetReadDeadline(c.readDeadline(ctx, keepalive))
 		_, data, err := conn.ReadMessage()
 		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if keepalive && isTimeout(err) {
+				return fmt.Errorf("%w within %s", ErrRelayUnresponsive, c.pingInterval+c.pongTimeout)
+			}
 			return err
 		}
 
@@ -224,32 +537,57 @@ func (c *Client) runSubscription(ctx context.Context, conn *websocket.Conn, rela
 			}
 			if err := evt.Verify(); err != nil {
 				c.logger.Debug("ignore invalid event", "relay", relay, "error", err)
+				c.stats.count(relay, false)
+				continue
+			}
+			c.stats.count(relay, true)
+			if sub.accept != nil && !sub.accept(evt) {
 				continue
 			}
 			evt.Relay = relay
+			evt.Raw = payload[2]
 			select {
 			case events <- evt:
 			case <-ctx.Done():
 				return ctx.Err()
 			}
 		case "EOSE":
-			// keep the subscription open for streaming; no action needed.
-			continue
+			sub.report(RelayMessage{Relay: relay, Type: MessageEOSE})
+			if !sub.closeOnEOSE {
+				// keep the subscription open for streaming; no action needed.
+				continue
+			}
+			_ = conn.WriteJSON([]any{"CLOSE", subID})
+			return nil
+		case "CLOSED":
+			var recvSub, reason string
+			if len(payload) < 2 || json.Unmarshal(payload[1], &recvSub) != nil || recvSub != subID {
+				continue
+			}
+			if len(payload) > 2 {
+				_ = json.Unmarshal(payload[2], &reason)
+			}
+			sub.report(RelayMessage{Relay: relay, Type: MessageClosed, Message: reason})
+			return fmt.Errorf("subscription closed by relay: %s", reason)
 		case "NOTICE":
 			if len(payload) > 1 {
 				var notice string
 				if err := json.Unmarshal(payload[1], &notice); err == nil {
 					c.logger.Warn("relay notice", "relay", relay, "notice", notice)
+					sub.report(RelayMessage{Relay: relay, Type: MessageNotice, Message: notice})
 				}
 			}
 		}
 	}
 }
 
+// emitError reports err without blocking the stream. An error the consumer
+// has not read yet is not replaced; the latest one is still in RelayStats.
 func (c *Client) emitError(errs chan<- error, err error) {
 	select {
 	case errs <- err:
 	default:
+		c.logger.Debug("stream error not delivered", "error", err)
 	}
 }
 
@@ -87,3 +87,26 @@ func TestParseOKMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestRejectedErrorRequiredPoW(t *testing.T) {
+	tests := []struct {
+		message  string
+		wantOK   bool
+		wantBits int
+	}{
+		{message: "pow: difficulty 18 is less than 24", wantOK: true, wantBits: 24},
+		{message: "pow: insufficient proof of work", wantOK: true, wantBits: 0},
+		{message: "blocked: you are banned", wantOK: false},
+		{message: "proof of work required", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			err := &RejectedError{Relay: "wss://relay.example.com", EventID: "id", Message: tt.message}
+			bits, ok := err.RequiredPoW()
+			if ok != tt.wantOK || bits != tt.wantBits {
+				t.Fatalf("RequiredPoW() = (%d, %v), want (%d, %v)", bits, ok, tt.wantBits, tt.wantOK)
+			}
+		})
+	}
+}
@@ -26,6 +26,8 @@ type Event struct {
 	Content   string     `json:"content"`
 	Sig       string     `json:"sig"`
 	Relay     string     `json:"-"`
+	// Raw holds the event JSON exactly as received from the relay, if any.
+	Raw json.RawMessage `json:"-"`
 }
 
 // CreatedAtTime converts the timestamp to time.Time.
@@ -75,14 +77,18 @@ func (e Event) Verify() error {
 	return nil
 }
 
-// hashEvent calculates the event hash as specified in NIP-01.
-func hashEvent(e Event) ([32]byte, error) {
-	payload := []any{0, e.PubKey, e.CreatedAt, e.Kind, e.Tags, e.Content}
-	serialized, err := json.Marshal(payload)
+// ComputeID returns the NIP-01 event ID for the current contents of the event.
+func (e Event) ComputeID() (string, error) {
+	hash, err := hashEvent(e)
 	if err != nil {
-		return [32]byte{}, fmt.Errorf("marshal payload: %w", err)
+		return "", err
 	}
-	return sha256.Sum256(serialized), nil
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// hashEvent calculates the event hash as specified in NIP-01.
+func hashEvent(e Event) ([32]byte, error) {
+	return sha256.Sum256(serializeEvent(e.PubKey, e.CreatedAt, e.Kind, e.Tags, e.Content)), nil
 }
 
 // SignEvent computes the event ID and signature using the given private key.
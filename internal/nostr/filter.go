@@ -1,25 +1,49 @@
 package nostr
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // Filter mirrors a standard Nostr REQ filter.
 type Filter struct {
+	IDs     []string
 	Authors []string
 	Kinds   []int
-	Since   *time.Time
-	Until   *time.Time
-	Limit   int
+	// Tags maps a single-letter tag name (without '#') to accepted values, e.g. {"p": {pubkey}}.
+	Tags  map[string][]string
+	Since *time.Time
+	Until *time.Time
+	Limit int
+	// LimitZero sends "limit":0, asking for new events only; a zero Limit
+	// otherwise means no limit and is omitted.
+	LimitZero bool
+	// Extra holds fields this package does not interpret, such as NIP-50
+	// search or multi-letter tags. They are sent as given and ignored by Matches.
+	Extra map[string]json.RawMessage
 }
 
 func (f Filter) toRequest() map[string]any {
 	payload := make(map[string]any)
+	for key, raw := range f.Extra {
+		payload[key] = raw
+	}
 
+	if len(f.IDs) > 0 {
+		payload["ids"] = f.IDs
+	}
 	if len(f.Authors) > 0 {
 		payload["authors"] = f.Authors
 	}
 	if len(f.Kinds) > 0 {
 		payload["kinds"] = f.Kinds
 	}
+	for name, values := range f.Tags {
+		if len(values) > 0 {
+			payload["#"+name] = values
+		}
+	}
 	if f.Since != nil {
 		payload["since"] = f.Since.Unix()
 	}
@@ -28,7 +52,131 @@ func (f Filter) toRequest() map[string]any {
 	}
 	if f.Limit > 0 {
 		payload["limit"] = f.Limit
+	} else if f.LimitZero {
+		payload["limit"] = 0
 	}
 
 	return payload
 }
+
+// MarshalJSON encodes the filter in NIP-01 REQ form.
+func (f Filter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.toRequest())
+}
+
+// UnmarshalJSON decodes a NIP-01 filter object such as
+// {"kinds":[1],"#t":["nostr"],"since":1700000000,"limit":10}.
+// Fields other than the NIP-01 ones and single-letter tags are kept in Extra.
+func (f *Filter) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	var out Filter
+	for key, raw := range fields {
+		var err error
+		switch key {
+		case "ids":
+			err = json.Unmarshal(raw, &out.IDs)
+		case "authors":
+			err = json.Unmarshal(raw, &out.Authors)
+		case "kinds":
+			err = json.Unmarshal(raw, &out.Kinds)
+		case "since":
+			out.Since, err = unmarshalTimestamp(raw)
+		case "until":
+			out.Until, err = unmarshalTimestamp(raw)
+		case "limit":
+			err = json.Unmarshal(raw, &out.Limit)
+			out.LimitZero = err == nil && out.Limit == 0
+		default:
+			if len(key) != 2 || key[0] != '#' {
+				if out.Extra == nil {
+					out.Extra = make(map[string]json.RawMessage)
+				}
+				out.Extra[key] = raw
+				continue
+			}
+			var values []string
+			if err = json.Unmarshal(raw, &values); err == nil {
+				if out.Tags == nil {
+					out.Tags = make(map[string][]string)
+				}
+				out.Tags[key[1:]] = values
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("filter field %q: %w", key, err)
+		}
+	}
+
+	*f = out
+	return nil
+}
+
+func unmarshalTimestamp(raw json.RawMessage) (*time.Time, error) {
+	var unix int64
+	if err := json.Unmarshal(raw, &unix); err != nil {
+		return nil, err
+	}
+	t := time.Unix(unix, 0)
+	return &t, nil
+}
+
+// Matches reports whether evt satisfies the filter using NIP-01 semantics:
+// every populated field must match, values within a field are OR-ed, and
+// Limit is ignored because it only applies to the initial query.
+func (f Filter) Matches(evt Event) bool {
+	if len(f.IDs) > 0 && !containsString(f.IDs, evt.ID) {
+		return false
+	}
+	if len(f.Authors) > 0 && !containsString(f.Authors, evt.PubKey) {
+		return false
+	}
+	if len(f.Kinds) > 0 && !containsInt(f.Kinds, evt.Kind) {
+		return false
+	}
+	if f.Since != nil && evt.CreatedAt < f.Since.Unix() {
+		return false
+	}
+	if f.Until != nil && evt.CreatedAt > f.Until.Unix() {
+		return false
+	}
+	for name, values := range f.Tags {
+		if len(values) == 0 {
+			continue
+		}
+		if !hasTagValue(evt, name, values) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasTagValue(evt Event, name string, values []string) bool {
+	for _, tag := range evt.Tags {
+		if len(tag) >= 2 && tag[0] == name && containsString(values, tag[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(list []int, v int) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package nostr
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 	"time"
@@ -48,6 +49,34 @@ func TestFilterToRequest(t *testing.T) {
 				"limit":   42,
 			},
 		},
+		{
+			name: "ids and tag filters",
+			filter: Filter{
+				IDs:  []string{"id1"},
+				Tags: map[string][]string{"p": {"pub"}, "t": {"nostr"}, "e": nil},
+			},
+			want: map[string]any{
+				"ids": []string{"id1"},
+				"#p":  []string{"pub"},
+				"#t":  []string{"nostr"},
+			},
+		},
+		{
+			name:   "explicit zero limit",
+			filter: Filter{LimitZero: true},
+			want:   map[string]any{"limit": 0},
+		},
+		{
+			name: "extra fields are passed through",
+			filter: Filter{
+				Kinds: []int{KindTextNote},
+				Extra: map[string]json.RawMessage{"search": json.RawMessage(`"nostr"`)},
+			},
+			want: map[string]any{
+				"kinds":  []int{KindTextNote},
+				"search": json.RawMessage(`"nostr"`),
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -59,3 +88,99 @@ func TestFilterToRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterMatches(t *testing.T) {
+	evt := Event{
+		ID:        "id1",
+		PubKey:    "alice",
+		CreatedAt: 1_000,
+		Kind:      KindTextNote,
+		Tags:      [][]string{{"t", "nostr"}, {"p", "bob"}},
+	}
+	before := time.Unix(999, 0)
+	at := time.Unix(1_000, 0)
+	after := time.Unix(1_001, 0)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{name: "empty filter matches everything", filter: Filter{}, want: true},
+		{name: "id match", filter: Filter{IDs: []string{"other", "id1"}}, want: true},
+		{name: "id mismatch", filter: Filter{IDs: []string{"other"}}, want: false},
+		{name: "author and kind", filter: Filter{Authors: []string{"alice"}, Kinds: []int{0, 1}}, want: true},
+		{name: "kind mismatch", filter: Filter{Kinds: []int{7}}, want: false},
+		{name: "since is inclusive", filter: Filter{Since: &at}, want: true},
+		{name: "since after event", filter: Filter{Since: &after}, want: false},
+		{name: "until is inclusive", filter: Filter{Until: &at}, want: true},
+		{name: "until before event", filter: Filter{Until: &before}, want: false},
+		{name: "tag match", filter: Filter{Tags: map[string][]string{"t": {"go", "nostr"}}}, want: true},
+		{name: "all tags must match", filter: Filter{Tags: map[string][]string{"t": {"nostr"}, "p": {"carol"}}}, want: false},
+		{name: "limit is ignored", filter: Filter{Limit: 1, Kinds: []int{1}}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(evt); got != tt.want {
+				t.Fatalf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterJSON(t *testing.T) {
+	input := `{"ids":["id1"],"authors":["pub"],"kinds":[1,7],"#t":["nostr","go"],"#p":["target"],"since":100,"until":200,"limit":10}`
+
+	var f Filter
+	if err := f.UnmarshalJSON([]byte(input)); err != nil {
+		t.Fatalf("UnmarshalJSON() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(f.IDs, []string{"id1"}) || !reflect.DeepEqual(f.Authors, []string{"pub"}) ||
+		!reflect.DeepEqual(f.Kinds, []int{1, 7}) || f.Limit != 10 {
+		t.Fatalf("unexpected filter: %+v", f)
+	}
+	if !reflect.DeepEqual(f.Tags, map[string][]string{"t": {"nostr", "go"}, "p": {"target"}}) {
+		t.Fatalf("unexpected tags: %+v", f.Tags)
+	}
+	if f.Since == nil || f.Since.Unix() != 100 || f.Until == nil || f.Until.Unix() != 200 {
+		t.Fatalf("unexpected since/until: %v %v", f.Since, f.Until)
+	}
+
+	encoded, err := f.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON() unexpected error: %v", err)
+	}
+	var roundTrip Filter
+	if err := roundTrip.UnmarshalJSON(encoded); err != nil {
+		t.Fatalf("UnmarshalJSON(MarshalJSON()) unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(roundTrip.Tags, f.Tags) || roundTrip.Since.Unix() != 100 || roundTrip.Limit != 10 {
+		t.Fatalf("round trip mismatch: %+v", roundTrip)
+	}
+
+	var extra Filter
+	if err := extra.UnmarshalJSON([]byte(`{"search":"nostr","#tag":["x"],"limit":0}`)); err != nil {
+		t.Fatalf("UnmarshalJSON() with extra fields unexpected error: %v", err)
+	}
+	if len(extra.Extra) != 2 || string(extra.Extra["search"]) != `"nostr"` || !extra.LimitZero {
+		t.Fatalf("unexpected filter with extra fields: %+v", extra)
+	}
+	encoded, err = extra.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON() unexpected error: %v", err)
+	}
+	if got := string(encoded); got != `{"#tag":["x"],"limit":0,"search":"nostr"}` {
+		t.Fatalf("MarshalJSON() = %s", got)
+	}
+
+	for _, bad := range []string{
+		`{"kinds":["1"]}`,
+		`{"since":"yesterday"}`,
+		`[]`,
+	} {
+		if err := new(Filter).UnmarshalJSON([]byte(bad)); err == nil {
+			t.Fatalf("UnmarshalJSON(%s) expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package nostr
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ExtractHashtags returns the hashtags found in content, lowercased and
+// deduplicated in order of appearance. Both ASCII '#' and full-width '＃'
+// markers are recognised so Japanese text such as "＃のすきー" is supported.
+func ExtractHashtags(content string) []string {
+	var tags []string
+	seen := make(map[string]struct{})
+
+	prev := rune(0)
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRuneInString(content[i:])
+		if (r == '#' || r == '＃') && isHashtagBoundary(prev) {
+			end := i + size
+			for end < len(content) {
+				next, n := utf8.DecodeRuneInString(content[end:])
+				if !isHashtagRune(next) {
+					break
+				}
+				end += n
+			}
+			word := content[i+size : end]
+			if word != "" && !isAllDigits(word) {
+				tag := strings.ToLower(word)
+				if _, ok := seen[tag]; !ok {
+					seen[tag] = struct{}{}
+					tags = append(tags, tag)
+				}
+			}
+			if end > i+size {
+				prev, _ = utf8.DecodeLastRuneInString(content[:end])
+				i = end
+				continue
+			}
+		}
+		prev = r
+		i += size
+	}
+
+	return tags
+}
+
+// isHashtagBoundary reports whether a hashtag may start after prev.
+// URL fragments (example.com/#foo) and words like "C#" are not hashtags.
+func isHashtagBoundary(prev rune) bool {
+	if prev == 0 {
+		return true
+	}
+	if isHashtagRune(prev) {
+		return false
+	}
+	switch prev {
+	case '/', '#', '＃', '&', '?', '=':
+		return false
+	}
+	return true
+}
+
+func isHashtagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+func isAllDigits(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package nostr
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "no hashtags",
+			content: "hello nostr",
+			want:    nil,
+		},
+		{
+			name:    "ascii hashtags are lowercased",
+			content: "hello #Nostr and #GoLang",
+			want:    []string{"nostr", "golang"},
+		},
+		{
+			name:    "japanese hashtags",
+			content: "今日は #のすきー と #ノストラ 、#自炊部",
+			want:    []string{"のすきー", "ノストラ", "自炊部"},
+		},
+		{
+			name:    "full-width hash sign",
+			content: "＃絵描き さん集まれ",
+			want:    []string{"絵描き"},
+		},
+		{
+			name:    "duplicates are removed",
+			content: "#nostr #NOSTR #nostr",
+			want:    []string{"nostr"},
+		},
+		{
+			name:    "hashtag at line start and after punctuation",
+			content: "#first\n(#second) 「#third」",
+			want:    []string{"first", "second", "third"},
+		},
+		{
+			name:    "url fragments and inline hash are ignored",
+			content: "see https://example.com/page#section and C#",
+			want:    nil,
+		},
+		{
+			name:    "numeric only and bare hash are ignored",
+			content: "issue #123 and # alone",
+			want:    nil,
+		},
+		{
+			name:    "underscore and digits inside word",
+			content: "#go_1_25 released",
+			want:    []string{"go_1_25"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractHashtags(tt.content)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ExtractHashtags(%q) = %#v, want %#v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
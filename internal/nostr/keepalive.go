@@ -0,0 +1,75 @@
+package nostr
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrRelayUnresponsive is returned when a streaming connection stops
+// answering WebSocket pings. A relay that only has nothing to send keeps
+// answering them and is never disconnected for being idle.
+var ErrRelayUnresponsive = errors.New("relay did not answer ping")
+
+// WithKeepalive makes streaming subscriptions ping the relay every interval
+// and treat the connection as dead when nothing, not even a pong, arrives
+// within interval+timeout. An interval of zero disables pings, so an idle
+// connection is dropped after the read timeout instead.
+func WithKeepalive(interval, timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.pingInterval = interval
+		c.pongTimeout = timeout
+	}
+}
+
+// keepAlive pings conn until the returned stop function is called. When ctx
+// is done it expires the read deadline so a blocked read returns promptly.
+func (c *Client) keepAlive(ctx context.Context, conn *websocket.Conn) (stop func()) {
+	done := make(chan struct{})
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(c.readDeadline(ctx, true))
+	})
+
+	go func() {
+		ticker := time.NewTicker(c.pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				_ = conn.SetReadDeadline(time.Now())
+				return
+			case <-ticker.C:
+				// WriteControl は他の書き込みと並行して呼び出せる
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.pongTimeout)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// readDeadline returns the deadline for the next read: interval+timeout from
+// now with keepalive, the read timeout otherwise, and never later than ctx's.
+func (c *Client) readDeadline(ctx context.Context, keepalive bool) time.Time {
+	deadline := time.Now().Add(c.readTimeout)
+	if keepalive {
+		deadline = time.Now().Add(c.pingInterval + c.pongTimeout)
+	}
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		// ctx の期限が先に来る場合は読み込みもそこで打ち切る
+		deadline = d
+	}
+	return deadline
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
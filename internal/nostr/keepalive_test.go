@@ -0,0 +1,79 @@
+package nostr
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// silentRelay accepts a subscription and never sends anything. When answerPings
+// is false it also stops reading, so pings go unanswered.
+func silentRelay(t *testing.T, answerPings bool) string {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, _, err := conn.ReadMessage(); err != nil { // REQ
+			return
+		}
+		if !answerPings {
+			<-r.Context().Done()
+			return
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestKeepaliveIdleRelayStaysConnected(t *testing.T) {
+	relay := silentRelay(t, true)
+	c := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), WithKeepalive(20*time.Millisecond, 40*time.Millisecond))
+	c.readTimeout = 50 * time.Millisecond
+
+	// Stay subscribed well past the read timeout, then stop.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(300*time.Millisecond, cancel)
+	events, errs := c.Req(ctx, relay, Filter{Kinds: []int{KindTextNote}}, false, nil)
+	for range events {
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("idle relay answering pings was disconnected: %v", err)
+	}
+}
+
+func TestKeepaliveDeadRelayIsDisconnected(t *testing.T) {
+	relay := silentRelay(t, false)
+	c := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), WithKeepalive(20*time.Millisecond, 40*time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	events, errs := c.Req(ctx, relay, Filter{Kinds: []int{KindTextNote}}, false, nil)
+	for range events {
+	}
+	err := <-errs
+	if !errors.Is(err, ErrRelayUnresponsive) {
+		t.Fatalf("Req() error = %v, want ErrRelayUnresponsive", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("dead relay detected after %s", elapsed)
+	}
+}
@@ -0,0 +1,68 @@
+package nostr
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+)
+
+// PublicKey derives the x-only hex public key for a raw 32-byte private key.
+func PublicKey(priv []byte) (string, error) {
+	if len(priv) != 32 {
+		return "", fmt.Errorf("invalid private key length: %d", len(priv))
+	}
+
+	var scalar btcec.ModNScalar
+	if overflow := scalar.SetByteSlice(priv); overflow || scalar.IsZero() {
+		return "", errors.New("invalid private key")
+	}
+
+	sk, _ := btcec.PrivKeyFromBytes(priv)
+	return hex.EncodeToString(schnorr.SerializePubKey(sk.PubKey())), nil
+}
+
+// GeneratePrivateKey returns a new random 32-byte private key.
+func GeneratePrivateKey() ([]byte, error) {
+	sk, err := btcec.NewPrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("generate private key: %w", err)
+	}
+	key := sk.Key.Bytes()
+	return key[:], nil
+}
+
+// KeyEnv is the environment variable holding the user's nsec.
+const KeyEnv = "NOSTR_NSEC"
+
+// Keys holds the user's key pair.
+type Keys struct {
+	// Private is the raw 32-byte secret key.
+	Private []byte
+	// Public is the 32-byte x-only public key in hex.
+	Public string
+}
+
+// LoadKeysFromEnv reads NOSTR_NSEC and returns the raw private key and public key (both 32-byte).
+func LoadKeysFromEnv() (Keys, error) {
+	nsec := strings.TrimSpace(os.Getenv(KeyEnv))
+	if nsec == "" {
+		return Keys{}, errors.New("NOSTR_NSEC is not set")
+	}
+	priv, err := DecodeNsec(nsec)
+	if err != nil {
+		return Keys{}, fmt.Errorf("decode NOSTR_NSEC: %w", err)
+	}
+
+	// Derive public key using the same curve as verification.
+	pub, err := PublicKey(priv)
+	if err != nil {
+		return Keys{}, err
+	}
+
+	return Keys{Private: priv, Public: pub}, nil
+}
@@ -0,0 +1,84 @@
+package nostr
+
+import "strconv"
+
+// IsReplaceableKind reports whether relays keep only the latest event per
+// pubkey for kind (NIP-01: 0, 3 and 10000-19999).
+func IsReplaceableKind(kind int) bool {
+	return kind == 0 || kind == 3 || (kind >= 10000 && kind < 20000)
+}
+
+// IsAddressableKind reports whether relays keep only the latest event per
+// pubkey and d tag for kind (NIP-01: 30000-39999).
+func IsAddressableKind(kind int) bool {
+	return kind >= 30000 && kind < 40000
+}
+
+// IsEphemeralKind reports whether relays forward events of kind without
+// storing them (NIP-01: 20000-29999).
+func IsEphemeralKind(kind int) bool {
+	return kind >= 20000 && kind < 30000
+}
+
+// IsReplaceable reports whether the event is a replaceable event.
+func (e Event) IsReplaceable() bool {
+	return IsReplaceableKind(e.Kind)
+}
+
+// IsAddressable reports whether the event is an addressable (parameterized
+// replaceable) event.
+func (e Event) IsAddressable() bool {
+	return IsAddressableKind(e.Kind)
+}
+
+// Address returns "kind:pubkey:d" identifying the slot the event occupies.
+// Replaceable events use an empty d ("kind:pubkey:"); regular events have no
+// address and return "".
+func (e Event) Address() string {
+	switch {
+	case e.IsAddressable():
+		d, _ := e.TagValue("d")
+		return strconv.Itoa(e.Kind) + ":" + e.PubKey + ":" + d
+	case e.IsReplaceable():
+		return strconv.Itoa(e.Kind) + ":" + e.PubKey + ":"
+	default:
+		return ""
+	}
+}
+
+// supersedes reports whether e replaces other at the same address: the newer
+// created_at wins, and on a tie the lowest ID is kept as NIP-01 specifies.
+func (e Event) supersedes(other Event) bool {
+	if e.CreatedAt != other.CreatedAt {
+		return e.CreatedAt > other.CreatedAt
+	}
+	return e.ID < other.ID
+}
+
+// LatestPerAddress drops every replaceable or addressable event that is
+// superseded by another one at the same address, keeping the order of the
+// survivors. Regular events are left untouched.
+func LatestPerAddress(events []Event) []Event {
+	latest := make(map[string]int)
+	for i, evt := range events {
+		addr := evt.Address()
+		if addr == "" {
+			continue
+		}
+		if j, ok := latest[addr]; !ok || evt.supersedes(events[j]) {
+			latest[addr] = i
+		}
+	}
+	if len(latest) == 0 {
+		return events
+	}
+
+	out := events[:0:0]
+	for i, evt := range events {
+		if addr := evt.Address(); addr != "" && latest[addr] != i {
+			continue
+		}
+		out = append(out, evt)
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package nostr
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEventAddress(t *testing.T) {
+	tests := []struct {
+		name            string
+		evt             Event
+		wantReplaceable bool
+		wantAddressable bool
+		wantEphemeral   bool
+		wantAddress     string
+	}{
+		{name: "text note", evt: Event{Kind: 1, PubKey: "pk"}},
+		{name: "metadata", evt: Event{Kind: 0, PubKey: "pk"}, wantReplaceable: true, wantAddress: "0:pk:"},
+		{name: "follow list", evt: Event{Kind: 3, PubKey: "pk"}, wantReplaceable: true, wantAddress: "3:pk:"},
+		{name: "relay list", evt: Event{Kind: 10002, PubKey: "pk"}, wantReplaceable: true, wantAddress: "10002:pk:"},
+		{name: "ephemeral", evt: Event{Kind: 20000, PubKey: "pk"}, wantEphemeral: true},
+		{name: "article", evt: Event{Kind: 30023, PubKey: "pk", Tags: [][]string{{"d", "slug"}}}, wantAddressable: true, wantAddress: "30023:pk:slug"},
+		{name: "addressable without d", evt: Event{Kind: 30000, PubKey: "pk"}, wantAddressable: true, wantAddress: "30000:pk:"},
+		{name: "out of range", evt: Event{Kind: 40000, PubKey: "pk"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.evt.IsReplaceable(); got != tt.wantReplaceable {
+				t.Fatalf("IsReplaceable() = %v, want %v", got, tt.wantReplaceable)
+			}
+			if got := tt.evt.IsAddressable(); got != tt.wantAddressable {
+				t.Fatalf("IsAddressable() = %v, want %v", got, tt.wantAddressable)
+			}
+			if got := IsEphemeralKind(tt.evt.Kind); got != tt.wantEphemeral {
+				t.Fatalf("IsEphemeralKind() = %v, want %v", got, tt.wantEphemeral)
+			}
+			if got := tt.evt.Address(); got != tt.wantAddress {
+				t.Fatalf("Address() = %q, want %q", got, tt.wantAddress)
+			}
+		})
+	}
+}
+
+func TestLatestPerAddress(t *testing.T) {
+	events := []Event{
+		{ID: "note1", Kind: 1, PubKey: "a", CreatedAt: 5},
+		{ID: "meta-old", Kind: 0, PubKey: "a", CreatedAt: 1},
+		{ID: "art-b", Kind: 30023, PubKey: "a", CreatedAt: 3, Tags: [][]string{{"d", "x"}}},
+		{ID: "meta-new", Kind: 0, PubKey: "a", CreatedAt: 2},
+		{ID: "art-a", Kind: 30023, PubKey: "a", CreatedAt: 3, Tags: [][]string{{"d", "x"}}},
+		{ID: "art-other", Kind: 30023, PubKey: "a", CreatedAt: 1, Tags: [][]string{{"d", "y"}}},
+		{ID: "meta-b", Kind: 0, PubKey: "b", CreatedAt: 1},
+		{ID: "note2", Kind: 1, PubKey: "a", CreatedAt: 5},
+	}
+
+	var ids []string
+	for _, evt := range LatestPerAddress(events) {
+		ids = append(ids, evt.ID)
+	}
+	want := []string{"note1", "meta-new", "art-a", "art-other", "meta-b", "note2"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("LatestPerAddress() = %v, want %v", ids, want)
+	}
+}
@@ -0,0 +1,432 @@
+package nostr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// NIP-77 negentropy protocol constants.
+const (
+	negentropyVersion  = 0x61
+	negentropyBuckets  = 16
+	fingerprintSize    = 16
+	negentropyModeSkip = 0
+	negentropyModeFP   = 1
+	negentropyModeIDs  = 2
+)
+
+// negentropyInfinity is the timestamp of the bound past every item.
+const negentropyInfinity = math.MaxUint64
+
+var errShortNegentropyMessage = errors.New("negentropy message ends early")
+
+type negentropyItem struct {
+	createdAt uint64
+	id        [32]byte
+}
+
+// negentropyBound splits ranges: it sorts before every item with a smaller
+// timestamp, or the same timestamp and an ID starting with a smaller prefix.
+type negentropyBound struct {
+	createdAt uint64
+	prefix    []byte
+}
+
+var infiniteBound = negentropyBound{createdAt: negentropyInfinity}
+
+// Negentropy runs one side of NIP-77 range-based set reconciliation over a
+// fixed set of events. Only the ID and created_at of each event are used.
+//
+// A client calls Initiate and then Reconcile with every reply until it
+// returns no message; the IDs it reports tell which events each side lacks.
+// A relay answers each client message with Respond.
+type Negentropy struct {
+	items []negentropyItem
+	// frameLimit bounds the size of an outgoing message in bytes; zero is unlimited.
+	frameLimit int
+	client     bool
+
+	// Timestamps are delta-encoded within a message.
+	lastIn, lastOut uint64
+
+	have, need []string
+}
+
+// NewNegentropy prepares reconciliation of events. frameLimit bounds each
+// outgoing message in bytes before hex encoding; zero leaves them unbounded.
+func NewNegentropy(events []Event, frameLimit int) (*Negentropy, error) {
+	if frameLimit != 0 && frameLimit < 4096 {
+		return nil, fmt.Errorf("negentropy frame limit %d is below 4096 bytes", frameLimit)
+	}
+	items := make([]negentropyItem, 0, len(events))
+	seen := make(map[string]struct{}, len(events))
+	for _, evt := range events {
+		if _, ok := seen[evt.ID]; ok {
+			continue
+		}
+		seen[evt.ID] = struct{}{}
+		item := negentropyItem{createdAt: uint64(max(evt.CreatedAt, 0))}
+		if n, err := hex.Decode(item.id[:], []byte(evt.ID)); err != nil || n != len(item.id) {
+			return nil, fmt.Errorf("negentropy: invalid event id %q", evt.ID)
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return compareItems(items[i], items[j]) < 0 })
+	return &Negentropy{items: items, frameLimit: frameLimit}, nil
+}
+
+// Initiate returns the client's first message, sent with NEG-OPEN.
+func (n *Negentropy) Initiate() []byte {
+	n.client = true
+	n.lastOut = 0
+	out := []byte{negentropyVersion}
+	return n.splitRange(out, 0, len(n.items), infiniteBound)
+}
+
+// Reconcile processes a relay message on the client side. It returns the
+// next message to send, or nil once reconciliation is complete, together with
+// the IDs found so far only on the client (have) and only on the relay (need).
+func (n *Negentropy) Reconcile(msg []byte) (next []byte, have, need []string, err error) {
+	if !n.client {
+		return nil, nil, nil, errors.New("negentropy: Reconcile called before Initiate")
+	}
+	n.have, n.need = nil, nil
+	out, err := n.reconcile(msg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(out) == 1 {
+		// バージョンのみの応答は差分が残っていないことを表す
+		out = nil
+	}
+	return out, n.have, n.need, nil
+}
+
+// Respond processes a client message on the relay side and returns the reply.
+func (n *Negentropy) Respond(msg []byte) ([]byte, error) {
+	if n.client {
+		return nil, errors.New("negentropy: Respond called on a client")
+	}
+	return n.reconcile(msg)
+}
+
+func (n *Negentropy) reconcile(msg []byte) ([]byte, error) {
+	n.lastIn, n.lastOut = 0, 0
+	r := &negentropyReader{buf: msg}
+	out := []byte{negentropyVersion}
+
+	version, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if version != negentropyVersion {
+		if n.client {
+			return nil, fmt.Errorf("unsupported negentropy version 0x%x", version)
+		}
+		// NIP-77: the relay answers an unknown version with the one it speaks
+		return out, nil
+	}
+
+	var (
+		prevBound negentropyBound
+		prevIndex int
+		// skipping coalesces consecutive ranges that need no reply into one skip.
+		skipping bool
+	)
+	for r.len() > 0 {
+		var partial []byte
+		finishSkip := func() {
+			if skipping {
+				skipping = false
+				partial = n.appendBound(partial, prevBound)
+				partial = appendVarint(partial, negentropyModeSkip)
+			}
+		}
+
+		bound, err := n.readBound(r)
+		if err != nil {
+			return nil, err
+		}
+		mode, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+
+		lower := prevIndex
+		upper := n.lowerBound(prevIndex, bound)
+
+		switch mode {
+		case negentropyModeSkip:
+			skipping = true
+
+		case negentropyModeFP:
+			theirs, err := r.bytes(fingerprintSize)
+			if err != nil {
+				return nil, err
+			}
+			if bytes.Equal(theirs, n.fingerprint(lower, upper)) {
+				skipping = true
+			} else {
+				finishSkip()
+				partial = n.splitRange(partial, lower, upper, bound)
+			}
+
+		case negentropyModeIDs:
+			count, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			theirs := make(map[[32]byte]struct{})
+			for range count {
+				b, err := r.bytes(32)
+				if err != nil {
+					return nil, err
+				}
+				theirs[[32]byte(b)] = struct{}{}
+			}
+
+			for _, item := range n.items[lower:upper] {
+				if _, ok := theirs[item.id]; ok {
+					delete(theirs, item.id)
+				} else if n.client {
+					n.have = append(n.have, hex.EncodeToString(item.id[:]))
+				}
+			}
+
+			if n.client {
+				for id := range theirs {
+					n.need = append(n.need, hex.EncodeToString(id[:]))
+				}
+				// the relay sent its full list for this range, nothing is left to ask
+				skipping = true
+				break
+			}
+
+			// the relay replies with its own IDs, as many as fit in the frame
+			finishSkip()
+			end := bound
+			var ids []byte
+			sent := 0
+			for i := lower; i < upper; i++ {
+				// 参照実装と同じく、保留中のスキップ境界は数えずに判定する
+				if n.overLimit(len(out) + len(ids)) {
+					end = negentropyBound{createdAt: n.items[i].createdAt, prefix: n.items[i].id[:]}
+					upper = i
+					break
+				}
+				ids = append(ids, n.items[i].id[:]...)
+				sent++
+			}
+			partial = n.appendBound(partial, end)
+			partial = appendVarint(partial, negentropyModeIDs)
+			partial = appendVarint(partial, uint64(sent))
+			partial = append(partial, ids...)
+			out = append(out, partial...)
+			partial = nil
+
+		default:
+			return nil, fmt.Errorf("unexpected negentropy mode %d", mode)
+		}
+
+		if n.overLimit(len(out) + len(partial)) {
+			// 残りの範囲はまとめてフィンガープリントで送り、次の往復で続ける
+			out = n.appendBound(out, infiniteBound)
+			out = appendVarint(out, negentropyModeFP)
+			out = append(out, n.fingerprint(upper, len(n.items))...)
+			break
+		}
+		out = append(out, partial...)
+
+		prevIndex = upper
+		prevBound = bound
+	}
+	return out, nil
+}
+
+// overLimit reports whether a message of size bytes leaves too little room
+// in the frame for another range.
+func (n *Negentropy) overLimit(size int) bool {
+	return n.frameLimit > 0 && size > n.frameLimit-200
+}
+
+// splitRange appends items[lower:upper] to out either as an ID list or, for
+// larger ranges, as fingerprints of negentropyBuckets sub-ranges.
+func (n *Negentropy) splitRange(out []byte, lower, upper int, upperBound negentropyBound) []byte {
+	count := upper - lower
+	if count < negentropyBuckets*2 {
+		out = n.appendBound(out, upperBound)
+		out = appendVarint(out, negentropyModeIDs)
+		out = appendVarint(out, uint64(count))
+		for _, item := range n.items[lower:upper] {
+			out = append(out, item.id[:]...)
+		}
+		return out
+	}
+
+	perBucket := count / negentropyBuckets
+	extra := count % negentropyBuckets
+	curr := lower
+	for i := range negentropyBuckets {
+		size := perBucket
+		if i < extra {
+			size++
+		}
+		fp := n.fingerprint(curr, curr+size)
+		curr += size
+
+		next := upperBound
+		if curr != upper {
+			next = minimalBound(n.items[curr-1], n.items[curr])
+		}
+		out = n.appendBound(out, next)
+		out = appendVarint(out, negentropyModeFP)
+		out = append(out, fp...)
+	}
+	return out
+}
+
+// fingerprint hashes the sum of the IDs in items[lower:upper], taken as
+// little-endian 256-bit numbers, followed by their count.
+func (n *Negentropy) fingerprint(lower, upper int) []byte {
+	var sum [32]byte
+	for _, item := range n.items[lower:upper] {
+		var carry uint64
+		for i := 0; i < 32; i += 4 {
+			v := uint64(binary.LittleEndian.Uint32(sum[i:])) + uint64(binary.LittleEndian.Uint32(item.id[i:])) + carry
+			binary.LittleEndian.PutUint32(sum[i:], uint32(v))
+			carry = v >> 32
+		}
+	}
+	h := sha256.Sum256(appendVarint(sum[:], uint64(upper-lower)))
+	return h[:fingerprintSize]
+}
+
+// lowerBound returns the index of the first item at or after bound, searching from begin.
+func (n *Negentropy) lowerBound(begin int, bound negentropyBound) int {
+	return begin + sort.Search(len(n.items)-begin, func(i int) bool {
+		return compareToBound(n.items[begin+i], bound) >= 0
+	})
+}
+
+func (n *Negentropy) appendBound(out []byte, bound negentropyBound) []byte {
+	if bound.createdAt == negentropyInfinity {
+		n.lastOut = negentropyInfinity
+		out = appendVarint(out, 0)
+	} else {
+		// 0 は無限大を表すため差分に 1 を足す
+		out = appendVarint(out, bound.createdAt-n.lastOut+1)
+		n.lastOut = bound.createdAt
+	}
+	out = appendVarint(out, uint64(len(bound.prefix)))
+	return append(out, bound.prefix...)
+}
+
+func (n *Negentropy) readBound(r *negentropyReader) (negentropyBound, error) {
+	delta, err := r.varint()
+	if err != nil {
+		return negentropyBound{}, err
+	}
+	var bound negentropyBound
+	if delta == 0 {
+		bound.createdAt = negentropyInfinity
+	} else {
+		bound.createdAt = n.lastIn + delta - 1
+	}
+	n.lastIn = bound.createdAt
+
+	size, err := r.varint()
+	if err != nil {
+		return negentropyBound{}, err
+	}
+	if size > 32 {
+		return negentropyBound{}, fmt.Errorf("negentropy bound prefix of %d bytes", size)
+	}
+	if bound.prefix, err = r.bytes(int(size)); err != nil {
+		return negentropyBound{}, err
+	}
+	return bound, nil
+}
+
+// minimalBound returns the shortest bound separating prev from curr.
+func minimalBound(prev, curr negentropyItem) negentropyBound {
+	if prev.createdAt != curr.createdAt {
+		return negentropyBound{createdAt: curr.createdAt}
+	}
+	shared := 0
+	for shared < len(curr.id)-1 && prev.id[shared] == curr.id[shared] {
+		shared++
+	}
+	return negentropyBound{createdAt: curr.createdAt, prefix: curr.id[:shared+1]}
+}
+
+func compareItems(a, b negentropyItem) int {
+	return compareToBound(a, negentropyBound{createdAt: b.createdAt, prefix: b.id[:]})
+}
+
+func compareToBound(item negentropyItem, bound negentropyBound) int {
+	switch {
+	case item.createdAt < bound.createdAt:
+		return -1
+	case item.createdAt > bound.createdAt:
+		return 1
+	}
+	return bytes.Compare(item.id[:], bound.prefix)
+}
+
+// appendVarint appends v as a big-endian base-128 integer with the high bit
+// set on every byte but the last.
+func appendVarint(out []byte, v uint64) []byte {
+	var buf [10]byte
+	i := len(buf) - 1
+	buf[i] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		i--
+		buf[i] = byte(v&0x7f) | 0x80
+	}
+	return append(out, buf[i:]...)
+}
+
+type negentropyReader struct {
+	buf []byte
+}
+
+func (r *negentropyReader) len() int { return len(r.buf) }
+
+func (r *negentropyReader) byte() (byte, error) {
+	if len(r.buf) == 0 {
+		return 0, errShortNegentropyMessage
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b, nil
+}
+
+func (r *negentropyReader) bytes(n int) ([]byte, error) {
+	if len(r.buf) < n {
+		return nil, errShortNegentropyMessage
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b, nil
+}
+
+func (r *negentropyReader) varint() (uint64, error) {
+	var v uint64
+	for range 10 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<7 | uint64(b&0x7f)
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, errors.New("negentropy varint too long")
+}
@@ -0,0 +1,307 @@
+package nostr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// negentropyEvents returns events whose IDs are derived from ids; several
+// share a created_at so bounds need ID prefixes.
+func negentropyEvents(ids []int) []Event {
+	events := make([]Event, 0, len(ids))
+	for _, i := range ids {
+		sum := sha256.Sum256([]byte(fmt.Sprint(i)))
+		events = append(events, Event{ID: hex.EncodeToString(sum[:]), CreatedAt: int64(1_700_000_000 + i/3)})
+	}
+	return events
+}
+
+func span(from, to int) []int {
+	var out []int
+	for i := from; i < to; i++ {
+		out = append(out, i)
+	}
+	return out
+}
+
+func idsOf(events []Event) []string {
+	out := make([]string, 0, len(events))
+	for _, evt := range events {
+		out = append(out, evt.ID)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestNegentropyReconcile(t *testing.T) {
+	tests := []struct {
+		name       string
+		client     []int
+		relay      []int
+		frameLimit int
+	}{
+		{name: "both empty"},
+		{name: "client empty", relay: span(0, 10)},
+		{name: "relay empty", client: span(0, 10)},
+		{name: "identical", client: span(0, 500), relay: span(0, 500)},
+		{name: "small overlap", client: span(0, 20), relay: span(10, 30)},
+		{name: "large overlap", client: span(0, 3000), relay: append(span(5, 2000), span(2100, 3500)...)},
+		{name: "frame limit", client: span(0, 100), relay: span(50, 5000), frameLimit: 4096},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientEvents := negentropyEvents(tt.client)
+			relayEvents := negentropyEvents(tt.relay)
+			client, err := NewNegentropy(clientEvents, tt.frameLimit)
+			if err != nil {
+				t.Fatalf("NewNegentropy(client) error: %v", err)
+			}
+			relay, err := NewNegentropy(relayEvents, tt.frameLimit)
+			if err != nil {
+				t.Fatalf("NewNegentropy(relay) error: %v", err)
+			}
+
+			var have, need []string
+			msg := client.Initiate()
+			rounds := 0
+			for msg != nil {
+				if rounds++; rounds > 50 {
+					t.Fatalf("reconciliation did not finish")
+				}
+				if tt.frameLimit > 0 && len(msg) > tt.frameLimit {
+					t.Fatalf("client message of %d bytes exceeds the frame limit", len(msg))
+				}
+				reply, err := relay.Respond(msg)
+				if err != nil {
+					t.Fatalf("Respond() error: %v", err)
+				}
+				if tt.frameLimit > 0 && len(reply) > tt.frameLimit {
+					t.Fatalf("relay message of %d bytes exceeds the frame limit", len(reply))
+				}
+				next, h, n, err := client.Reconcile(reply)
+				if err != nil {
+					t.Fatalf("Reconcile() error: %v", err)
+				}
+				have = append(have, h...)
+				need = append(need, n...)
+				msg = next
+			}
+
+			sort.Strings(have)
+			sort.Strings(need)
+			wantHave := idsOf(difference(clientEvents, relayEvents))
+			wantNeed := idsOf(difference(relayEvents, clientEvents))
+			if fmt.Sprint(have) != fmt.Sprint(wantHave) {
+				t.Fatalf("have %d IDs, want %d", len(have), len(wantHave))
+			}
+			if fmt.Sprint(need) != fmt.Sprint(wantNeed) {
+				t.Fatalf("need %d IDs, want %d", len(need), len(wantNeed))
+			}
+		})
+	}
+}
+
+func difference(a, b []Event) []Event {
+	inB := make(map[string]bool, len(b))
+	for _, evt := range b {
+		inB[evt.ID] = true
+	}
+	var out []Event
+	for _, evt := range a {
+		if !inB[evt.ID] {
+			out = append(out, evt)
+		}
+	}
+	return out
+}
+
+// Reference messages for a client holding items 0-39 and a relay holding
+// items 20-59 of negentropyEvents, produced by the go-nostr (v0.38.2) port of the
+// hoytech/negentropy reference implementation, which strfry also speaks.
+const (
+	refInitiate = "" +
+		"6186aacfe20200015fa8325ac1981d67039205be427ea7ab0200014c26afdde46dff57f8670d06cb30855b02000142d3" +
+		"4aa845b12f725bfcbabc0805da3c02000153592b1469e98eb7d889e48cd4a349c102000181c8db5862eeeb9cd26d366c" +
+		"9c5da9390200014c565fcada1e334052444d2329188597020001b3f2c2955bd1353d26adefd167eff32d020001902794" +
+		"4bc7e18bd5381a3beea2eca6520101c201db9e68295e265b5fe8d93bf1ca2be4c402015901f75d4dd64ee8ade09be0de" +
+		"2b141919100200016de0f08ec0d36149dcb7ba2c420cd1b00101eb019287b7148eb8b607ce310e511085bb6402019f01" +
+		"a8fd85d3630420cb108f43369288186b020001a31953b8228948507b71687b775d13910101ae013afbc6bfb00156a463" +
+		"e71efc9998f70e00000118136ea47d7ca31f74ba4d514b110b81"
+	refReply = "" +
+		"6186aacfe202000200020002000200020002000200020002000200020002000201f5ca38f748a1d6eaf726b8a42fb575" +
+		"c3c71f1864a8143301782de13da2d9202b0601ae0000000216aea92132c4cbeb263e6ac2bf6c183b5d81737f179f21ef" +
+		"dc5863739672f0f4700b918943df0962bc7a1824c0555a389347b4febdc7cf9d1254406d80ce44e3f93d914f9348c9cc" +
+		"0ff8a79716700b9fcd4d2f3e711608004eb8f138bcba7f14d9d59eced1ded07f84c145592f65bdf854358e009c5cd705" +
+		"f5215bf18697fed10344cb730c420480a0477b505ae68af508fb90f96cf0ec54c6ad16949dd427f13a71ee45a3c0db9a" +
+		"9865f7313dd3372cf60dca6479d46261f3542eb9346e4a04d673475cb40a568e8da8a045ced110137e159f890ac4da88" +
+		"3b6b17dc651b3a804925fc0e7096fc653718202dc30b0c580b8ab87eac11a700cba03a7c021bc35b0c31489056e0916d" +
+		"59fe3add79e63f095af3ffb81604691f21cad442a85c7be617811786ad1ae74adfdd20dd0372abaaebc6246e343aebd0" +
+		"1da0bfc4c02bf0106c0e17daca5f3e175f448bacace3bc0da47d0655a74c8dd0dc497a3afbdad95f1f1a6562590ef19d" +
+		"1045d06c4055742d38288e9e6dcd71ccde5cee80f1d5a774eb98010bd9270f9b100b6214a21754fd33bdc8d41b2bc9f9" +
+		"dd16ff54d3c34ffd71031b4af5197ec30a926f48cf40e11a7dbc470048a21e4003b7a3c07c5dab1baa2858dcd1057d3e" +
+		"ae7f7d5f782167e24b61153c01551450a628cee722509f652941cfc0d1f2d127b04555b7246d84019b4d27710a3f3aff" +
+		"6e7764375b1e06e05d02d20bbd7e394ad5999a4cebabac9619732c343a4cac99470c03e23ba2bdc2bc2fca346db65618" +
+		"7102ce806ac732e06a62df0dbb2829e511a770556d398e1a6e7688b6ef52555962d008fff894223582c484517cea7da4" +
+		"9ee67800adc7fc88663e1e967e9b793e908f8eae83c74dba9bcccce6a5535b4b462bd9994537bfe15c6208ef0f7750c1" +
+		"11548cf90b6ea1d0d0a66f6bff40dbef07cb45ec436263c7d6c837649cce43f2729138e72cc315207057ac82599a59be" +
+		"72765a477f22d14a54"
+)
+
+func TestNegentropyReferenceVectors(t *testing.T) {
+	clientEvents := negentropyEvents(span(0, 40))
+	relayEvents := negentropyEvents(span(20, 60))
+	client, err := NewNegentropy(clientEvents, 0)
+	if err != nil {
+		t.Fatalf("NewNegentropy(client) error: %v", err)
+	}
+	relay, err := NewNegentropy(relayEvents, 0)
+	if err != nil {
+		t.Fatalf("NewNegentropy(relay) error: %v", err)
+	}
+
+	if got := hex.EncodeToString(client.Initiate()); got != refInitiate {
+		t.Fatalf("Initiate() = %s\nwant %s", got, refInitiate)
+	}
+
+	initiate, _ := hex.DecodeString(refInitiate)
+	reply, err := relay.Respond(initiate)
+	if err != nil {
+		t.Fatalf("Respond() error: %v", err)
+	}
+	if got := hex.EncodeToString(reply); got != refReply {
+		t.Fatalf("Respond() = %s\nwant %s", got, refReply)
+	}
+
+	refReplyBytes, _ := hex.DecodeString(refReply)
+	next, have, need, err := client.Reconcile(refReplyBytes)
+	if err != nil {
+		t.Fatalf("Reconcile() error: %v", err)
+	}
+	if next != nil {
+		t.Fatalf("Reconcile() = %x, want the reconciliation to be complete", next)
+	}
+	sort.Strings(have)
+	sort.Strings(need)
+	if fmt.Sprint(have) != fmt.Sprint(idsOf(difference(clientEvents, relayEvents))) {
+		t.Fatalf("have = %v, want items 0-19", have)
+	}
+	if fmt.Sprint(need) != fmt.Sprint(idsOf(difference(relayEvents, clientEvents))) {
+		t.Fatalf("need = %v, want items 40-59", need)
+	}
+}
+
+// refFrameLimitTranscript holds the SHA-256 of every message exchanged by the
+// reference implementation between a client with items 0-99 and a relay with
+// items 50-799 under a 4096-byte frame limit, alternating client and relay.
+var refFrameLimitTranscript = []struct {
+	relay  bool
+	digest string
+}{
+	{false, "fc3f86b9e438d163e98fe85258c279bbda12f4cb0bd03275c7c6f4820cf8b889"},
+	{true, "de418a6d9b46f0a3526ff915fc647e5c368a08cfd4b9a7e948b675f693a09c33"},
+	{false, "82e34806f6a6b93bc888a01ff121e1c305fd65a3658a6a49df47b937a9c4ed2f"},
+	{true, "19e52624dba18735165896dd00c6183cf1eaae4eb63abdd18417f6a73bc0dfb4"},
+	{false, "b8c04ee6be06f786a3349f7dbdbeb74c9a3e4c6cb7482965a3ade56956d6fbd3"},
+	{true, "410b81f865192e8b25658109c53dfad1666595b817732487c006f0d41b142512"},
+	{false, "82b8a9cb92f9cd6cc46d96cfa5cba499bc1a31f3f77d2c36ae6726710fb0b3ba"},
+	{true, "8e4a7f09692e4d42242d125713c106dc0b2e047d7edc1ddb651c2cd9c437fc45"},
+	{false, "5019a3e21cc07e395c565bf5e7b951e827d751c2816c0534693ab3fb3276e440"},
+	{true, "b970a913357f1b75edfe74a26269402002ab5eae173f3b1d06cf5be9d7a615b6"},
+	{false, "00249770a8f799b6d377a1be8a829712f99e4045a9df80bbd608e531a8fb4b65"},
+	{true, "532713fa51b39d27c7d902f7e576339ead4cfc121f977d7375812632e47397a9"},
+	{false, "0ade54225d27884588fd24d2cadb5cbe0e1b3f154f8027ddb8507f0d3863258c"},
+	{true, "fffc4c43012b6daf876fa09bc1c84ddbec345a6d5ac43a864e0f681903b39103"},
+}
+
+func TestNegentropyReferenceFrameLimitTranscript(t *testing.T) {
+	clientEvents := negentropyEvents(span(0, 100))
+	relayEvents := negentropyEvents(span(50, 800))
+	client, err := NewNegentropy(clientEvents, 4096)
+	if err != nil {
+		t.Fatalf("NewNegentropy(client) error: %v", err)
+	}
+	relay, err := NewNegentropy(relayEvents, 4096)
+	if err != nil {
+		t.Fatalf("NewNegentropy(relay) error: %v", err)
+	}
+
+	var have, need []string
+	msg := client.Initiate()
+	for i, want := range refFrameLimitTranscript {
+		if msg == nil {
+			t.Fatalf("message %d missing, reconciliation ended early", i)
+		}
+		if sum := sha256.Sum256(msg); hex.EncodeToString(sum[:]) != want.digest {
+			t.Fatalf("message %d (relay %v) of %d bytes differs from the reference", i, want.relay, len(msg))
+		}
+		if want.relay {
+			next, h, n, err := client.Reconcile(msg)
+			if err != nil {
+				t.Fatalf("Reconcile() error: %v", err)
+			}
+			have = append(have, h...)
+			need = append(need, n...)
+			msg = next
+		} else if msg, err = relay.Respond(msg); err != nil {
+			t.Fatalf("Respond() error: %v", err)
+		}
+	}
+	if msg != nil {
+		t.Fatalf("reconciliation continues past the reference transcript")
+	}
+	if len(have) != 50 || len(need) != 700 {
+		t.Fatalf("have %d and need %d IDs, want 50 and 700", len(have), len(need))
+	}
+}
+
+func TestNegentropyUnknownVersion(t *testing.T) {
+	relay, err := NewNegentropy(negentropyEvents(span(0, 3)), 0)
+	if err != nil {
+		t.Fatalf("NewNegentropy() error: %v", err)
+	}
+	reply, err := relay.Respond([]byte{0x62, 0x00})
+	if err != nil {
+		t.Fatalf("Respond() error: %v", err)
+	}
+	if !bytes.Equal(reply, []byte{negentropyVersion}) {
+		t.Fatalf("Respond() = %x, want the supported version only", reply)
+	}
+
+	client, _ := NewNegentropy(nil, 0)
+	client.Initiate()
+	if _, _, _, err := client.Reconcile([]byte{0x62}); err == nil {
+		t.Fatalf("Reconcile() accepted an unsupported version")
+	}
+	if _, _, _, err := client.Reconcile([]byte{negentropyVersion, 0x00, 0x00, negentropyModeFP}); err == nil {
+		t.Fatalf("Reconcile() accepted a truncated fingerprint")
+	}
+}
+
+func TestAppendVarint(t *testing.T) {
+	tests := []struct {
+		v    uint64
+		want string
+	}{
+		{0, "00"},
+		{1, "01"},
+		{127, "7f"},
+		{128, "8100"},
+		{16383, "ff7f"},
+		{16384, "818000"},
+	}
+	for _, tt := range tests {
+		got := appendVarint(nil, tt.v)
+		if hex.EncodeToString(got) != tt.want {
+			t.Fatalf("appendVarint(%d) = %x, want %s", tt.v, got, tt.want)
+		}
+		v, err := (&negentropyReader{buf: got}).varint()
+		if err != nil || v != tt.v {
+			t.Fatalf("varint(%x) = %d, %v; want %d", got, v, err, tt.v)
+		}
+	}
+}
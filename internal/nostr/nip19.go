@@ -0,0 +1,295 @@
+package nostr
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/bech32"
+)
+
+// NIP-19 human readable parts for bare keys and ids.
+const (
+	HRPPubKey  = "npub"
+	HRPPrivKey = "nsec"
+	HRPNote    = "note"
+	HRPAddr    = "naddr"
+)
+
+// NIP-19 TLV types.
+const (
+	tlvSpecial = 0
+	tlvRelay   = 1
+	tlvAuthor  = 2
+	tlvKind    = 3
+)
+
+// EntityPointer identifies an addressable event, as encoded in an naddr.
+type EntityPointer struct {
+	Identifier string
+	PubKey     string
+	Kind       int
+	// Relays are hints where the event may be found.
+	Relays []string
+}
+
+// EncodeNpub encodes a 32-byte hex public key as a NIP-19 npub string.
+func EncodeNpub(pubHex string) (string, error) {
+	raw, err := decodeHex32(pubHex)
+	if err != nil {
+		return "", fmt.Errorf("pubkey: %w", err)
+	}
+	return encodeBech32(HRPPubKey, raw)
+}
+
+// DecodeNpub decodes a NIP-19 npub string into a lowercase hex public key.
+func DecodeNpub(npub string) (string, error) {
+	raw, err := decodeBech32(HRPPubKey, npub)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) != 32 {
+		return "", fmt.Errorf("unexpected npub length: %d", len(raw))
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// EncodeNsec encodes a raw 32-byte private key as a NIP-19 nsec string.
+func EncodeNsec(priv []byte) (string, error) {
+	if len(priv) != 32 {
+		return "", fmt.Errorf("invalid private key length: %d", len(priv))
+	}
+	return encodeBech32(HRPPrivKey, priv)
+}
+
+// DecodeNsec decodes a NIP-19 nsec bech32 string and returns the raw 32-byte private key.
+func DecodeNsec(nsec string) ([]byte, error) {
+	raw, err := decodeBech32(HRPPrivKey, nsec)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("unexpected nsec length: %d", len(raw))
+	}
+	// For nsec, payload is just the 32-byte private key.
+	return raw, nil
+}
+
+// EncodeNaddr encodes p as a NIP-19 naddr string.
+func EncodeNaddr(p EntityPointer) (string, error) {
+	author, err := decodeHex32(p.PubKey)
+	if err != nil {
+		return "", fmt.Errorf("pubkey: %w", err)
+	}
+	if p.Kind < 0 || p.Kind > MaxKind {
+		return "", fmt.Errorf("invalid kind: %d", p.Kind)
+	}
+
+	var buf []byte
+	buf, err = appendTLV(buf, tlvSpecial, []byte(p.Identifier))
+	if err != nil {
+		return "", fmt.Errorf("identifier: %w", err)
+	}
+	for _, relay := range p.Relays {
+		if buf, err = appendTLV(buf, tlvRelay, []byte(relay)); err != nil {
+			return "", fmt.Errorf("relay: %w", err)
+		}
+	}
+	buf, _ = appendTLV(buf, tlvAuthor, author)
+	buf, _ = appendTLV(buf, tlvKind, binary.BigEndian.AppendUint32(nil, uint32(p.Kind)))
+
+	return encodeBech32(HRPAddr, buf)
+}
+
+// DecodeNaddr decodes a NIP-19 naddr string. Unknown TLV types are ignored.
+func DecodeNaddr(naddr string) (EntityPointer, error) {
+	raw, err := decodeBech32(HRPAddr, strings.TrimSpace(naddr))
+	if err != nil {
+		return EntityPointer{}, err
+	}
+
+	var (
+		p                                 EntityPointer
+		hasIdentifier, hasAuthor, hasKind bool
+	)
+	for len(raw) > 0 {
+		if len(raw) < 2 || len(raw) < 2+int(raw[1]) {
+			return EntityPointer{}, fmt.Errorf("truncated TLV")
+		}
+		typ, value := raw[0], raw[2:2+int(raw[1])]
+		raw = raw[2+len(value):]
+
+		switch typ {
+		case tlvSpecial:
+			p.Identifier, hasIdentifier = string(value), true
+		case tlvRelay:
+			p.Relays = append(p.Relays, string(value))
+		case tlvAuthor:
+			if len(value) != 32 {
+				return EntityPointer{}, fmt.Errorf("unexpected author length: %d", len(value))
+			}
+			p.PubKey, hasAuthor = hex.EncodeToString(value), true
+		case tlvKind:
+			if len(value) != 4 {
+				return EntityPointer{}, fmt.Errorf("unexpected kind length: %d", len(value))
+			}
+			p.Kind, hasKind = int(binary.BigEndian.Uint32(value)), true
+		}
+	}
+	if !hasIdentifier || !hasAuthor || !hasKind {
+		return EntityPointer{}, fmt.Errorf("naddr must contain identifier, author and kind")
+	}
+	return p, nil
+}
+
+func appendTLV(buf []byte, typ byte, value []byte) ([]byte, error) {
+	if len(value) > 255 {
+		return buf, fmt.Errorf("value too long: %d bytes", len(value))
+	}
+	buf = append(buf, typ, byte(len(value)))
+	return append(buf, value...), nil
+}
+
+// ParsePubKey accepts either a 64-character hex public key or an npub and
+// returns the lowercase hex form.
+func ParsePubKey(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, HRPPubKey+"1") {
+		return DecodeNpub(s)
+	}
+	raw, err := decodeHex32(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid pubkey %q: %w", s, err)
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+func decodeHex32(s string) ([]byte, error) {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("unexpected length: %d", len(raw))
+	}
+	return raw, nil
+}
+
+func encodeBech32(hrp string, data []byte) (string, error) {
+	fiveBits, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	return bech32.Encode(hrp, fiveBits)
+}
+
+func decodeBech32(wantHRP, s string) ([]byte, error) {
+	hrp, data, err := bech32Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if hrp != wantHRP {
+		return nil, fmt.Errorf("unexpected HRP: %s", hrp)
+	}
+
+	// Convert 5-bit groups back to 8-bit bytes.
+	return convertBits(data, 5, 8, false)
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Decode decodes a bech32 string into its HRP and 5-bit data without the
+// checksum. Unlike bech32.Decode it does not enforce BIP-173's 90 character
+// limit, which NIP-19 TLV entities such as naddr routinely exceed.
+func bech32Decode(s string) (string, []byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, fmt.Errorf("mixed case bech32 string")
+	}
+	s = strings.ToLower(s)
+
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, fmt.Errorf("invalid bech32 separator position")
+	}
+	hrp := s[:sep]
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", nil, fmt.Errorf("invalid bech32 hrp character")
+		}
+	}
+
+	data := make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, s[i])
+		if v < 0 {
+			return "", nil, fmt.Errorf("invalid bech32 character %q", s[i])
+		}
+		data = append(data, byte(v))
+	}
+
+	values := make([]byte, 0, len(hrp)*2+1+len(data))
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]>>5)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]&31)
+	}
+	values = append(values, data...)
+	if bech32Polymod(values) != 1 {
+		return "", nil, fmt.Errorf("bech32 checksum failed")
+	}
+
+	return hrp, data[:len(data)-6], nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+// convertBits converts a slice of data where each element is fromBits wide into
+// a slice where each element is toBits wide. It is used for bech32 encoding/decoding.
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	var ret []byte
+	var acc uint
+	var bits uint
+	maxv := uint((1 << toBits) - 1)
+	maxAcc := uint((1 << (fromBits + toBits - 1)) - 1)
+
+	for _, value := range data {
+		v := uint(value)
+		if v>>fromBits != 0 {
+			return nil, fmt.Errorf("invalid data range: %d", value)
+		}
+		acc = ((acc << fromBits) | v) & maxAcc
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			ret = append(ret, byte((acc>>bits)&maxv))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			ret = append(ret, byte((acc<<(toBits-bits))&maxv))
+		}
+	} else if bits >= fromBits {
+		return nil, fmt.Errorf("illegal zero padding")
+	} else if ((acc << (toBits - bits)) & maxv) != 0 {
+		return nil, fmt.Errorf("non-zero padding")
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,131 @@
+package nostr
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestNpubRoundTrip(t *testing.T) {
+	// Example from NIP-19.
+	const (
+		npub   = "npub10elfcs4fr0l0r8af98jlmgdh9c8tcxjvz9qkw038js35mp4dma8qzvjptg"
+		pubHex = "7e7e9c42a91bfef19fa929e5fda1b72e0ebc1a4c1141673e2794234d86addf4e"
+	)
+
+	got, err := DecodeNpub(npub)
+	if err != nil {
+		t.Fatalf("DecodeNpub() unexpected error: %v", err)
+	}
+	if got != pubHex {
+		t.Fatalf("DecodeNpub() = %s, want %s", got, pubHex)
+	}
+
+	encoded, err := EncodeNpub(pubHex)
+	if err != nil {
+		t.Fatalf("EncodeNpub() unexpected error: %v", err)
+	}
+	if encoded != npub {
+		t.Fatalf("EncodeNpub() = %s, want %s", encoded, npub)
+	}
+}
+
+func TestDecodeNsec(t *testing.T) {
+	// Example from NIP-19.
+	const (
+		nsec    = "nsec1vl029mgpspedva04g90vltkh6fvh240zqtv9k0t9af8935ke9laqsnlfe5"
+		privHex = "67dea2ed018072d675f5415ecfaed7d2597555e202d85b3d65ea4e58d2d92ffa"
+	)
+
+	got, err := DecodeNsec(nsec)
+	if err != nil {
+		t.Fatalf("DecodeNsec() unexpected error: %v", err)
+	}
+	if hex.EncodeToString(got) != privHex {
+		t.Fatalf("DecodeNsec() = %x, want %s", got, privHex)
+	}
+
+	if _, err := DecodeNsec("npub10elfcs4fr0l0r8af98jlmgdh9c8tcxjvz9qkw038js35mp4dma8qzvjptg"); err == nil || !strings.Contains(err.Error(), "unexpected HRP") {
+		t.Fatalf("DecodeNsec() with npub error = %v, want unexpected HRP", err)
+	}
+}
+
+func TestParsePubKey(t *testing.T) {
+	const pubHex = "7e7e9c42a91bfef19fa929e5fda1b72e0ebc1a4c1141673e2794234d86addf4e"
+
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{name: "hex", in: pubHex},
+		{name: "uppercase hex", in: strings.ToUpper(pubHex)},
+		{name: "npub", in: "npub10elfcs4fr0l0r8af98jlmgdh9c8tcxjvz9qkw038js35mp4dma8qzvjptg"},
+		{name: "short hex", in: "abcd", wantErr: true},
+		{name: "garbage", in: "not-a-key", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePubKey(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParsePubKey(%q) expected error", tt.in)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePubKey(%q) unexpected error: %v", tt.in, err)
+			}
+			if got != pubHex {
+				t.Fatalf("ParsePubKey(%q) = %s, want %s", tt.in, got, pubHex)
+			}
+		})
+	}
+}
+
+func TestNaddr(t *testing.T) {
+	// Vectors shared with other NIP-19 implementations.
+	p := EntityPointer{
+		Identifier: "banana",
+		PubKey:     "3bf0c63fcb93463407af97a5e5ee64fa883d107ef9e558472c4eb9aaaefa459d",
+		Kind:       30023,
+		Relays:     []string{"wss://relay.nostr.example.mydomain.example.com", "wss://nostr.banana.com"},
+	}
+	const want = "naddr1qqrxyctwv9hxzqfwwaehxw309aex2mrp0yhxummnw3ezuetcv9khqmr99ekhjer0d4skjm3wv4uxzmtsd3jjucm0d5q3vamnwvaz7tmwdaehgu3wvfskuctwvyhxxmmdqgsrhuxx8l9ex335q7he0f09aej04zpazpl0ne2cgukyawd24mayt8grqsqqqa28a3lkds"
+
+	got, err := EncodeNaddr(p)
+	if err != nil {
+		t.Fatalf("EncodeNaddr() unexpected error: %v", err)
+	}
+	if got != want {
+		t.Fatalf("EncodeNaddr() = %s, want %s", got, want)
+	}
+
+	decoded, err := DecodeNaddr(want)
+	if err != nil {
+		t.Fatalf("DecodeNaddr() unexpected error: %v", err)
+	}
+	if decoded.Identifier != p.Identifier || decoded.PubKey != p.PubKey || decoded.Kind != p.Kind ||
+		len(decoded.Relays) != 2 || decoded.Relays[1] != "wss://nostr.banana.com" {
+		t.Fatalf("DecodeNaddr() = %+v, want %+v", decoded, p)
+	}
+
+	withoutRelays, err := DecodeNaddr("naddr1qq98yetxv4ex2mnrv4esygrl54h466tz4v0re4pyuavvxqptsejl0vxcmnhfl60z3rth2xkpjspsgqqqw4rsf34vl5")
+	if err != nil {
+		t.Fatalf("DecodeNaddr() unexpected error: %v", err)
+	}
+	if withoutRelays.Identifier != "references" || withoutRelays.Kind != 30023 || len(withoutRelays.Relays) != 0 ||
+		withoutRelays.PubKey != "7fa56f5d6962ab1e3cd424e758c3002b8665f7b0d8dcee9fe9e288d7751ac194" {
+		t.Fatalf("DecodeNaddr() = %+v", withoutRelays)
+	}
+
+	for _, bad := range []string{
+		want[:len(want)-1] + "q",
+		"npub1kp346656z8f6a5xd43y4k2ppv7k0wctjjpudja0uxsdjyeggq7usdvl7hp",
+	} {
+		if _, err := DecodeNaddr(bad); err == nil {
+			t.Fatalf("DecodeNaddr(%s) expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package nostr
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrNegentropyUnavailable is returned by Reconcile when the relay does not
+// support NIP-77 or refuses the query, so callers can fall back to a REQ.
+var ErrNegentropyUnavailable = errors.New("negentropy unavailable")
+
+// NegentropyFrameLimit bounds each negentropy message in bytes; hex encoding
+// doubles it on the wire.
+const NegentropyFrameLimit = 128 * 1024
+
+// maxIDsPerFetch bounds the IDs asked for in a single REQ by FetchIDs.
+const maxIDsPerFetch = 500
+
+// Diff is the outcome of reconciling a local set of events with a relay.
+type Diff struct {
+	// Have lists the IDs held locally but missing on the relay.
+	Have []string
+	// Need lists the IDs the relay holds that are missing locally.
+	Need []string
+}
+
+// Reconcile compares local with the events matching filter on relay using
+// NIP-77 negentropy, without transferring the events themselves.
+func (c *Client) Reconcile(ctx context.Context, relay string, filter Filter, local []Event) (Diff, error) {
+	neg, err := NewNegentropy(local, NegentropyFrameLimit)
+	if err != nil {
+		return Diff{}, err
+	}
+	conn, err := c.dial(ctx, relay)
+	if err != nil {
+		return Diff{}, err
+	}
+
+	diff, err := c.reconcile(ctx, conn, relay, filter, neg)
+	if errors.Is(err, ErrNegentropyUnavailable) {
+		// 非対応は接続の問題ではないため LastError には残さない
+		c.hangUp(conn, relay, nil)
+	} else {
+		c.hangUp(conn, relay, err)
+	}
+	if err != nil {
+		return Diff{}, fmt.Errorf("relay %s: %w", relay, err)
+	}
+	return diff, nil
+}
+
+func (c *Client) reconcile(ctx context.Context, conn *websocket.Conn, relay string, filter Filter, neg *Negentropy) (Diff, error) {
+	subID := randomSubID()
+	open := []any{"NEG-OPEN", subID, filter.toRequest(), hex.EncodeToString(neg.Initiate())}
+	if err := conn.WriteJSON(open); err != nil {
+		return Diff{}, err
+	}
+
+	var (
+		diff     Diff
+		answered bool
+	)
+	for {
+		_ = conn.SetReadDeadline(c.readDeadline(ctx, false))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return Diff{}, ctx.Err()
+			}
+			if !answered && isTimeout(err) {
+				return Diff{}, fmt.Errorf("%w: no reply to NEG-OPEN", ErrNegentropyUnavailable)
+			}
+			return Diff{}, err
+		}
+
+		var payload []json.RawMessage
+		if err := json.Unmarshal(data, &payload); err != nil || len(payload) < 2 {
+			continue
+		}
+		var msgType, arg string
+		if json.Unmarshal(payload[0], &msgType) != nil || json.Unmarshal(payload[1], &arg) != nil {
+			continue
+		}
+
+		switch msgType {
+		case "NEG-MSG":
+			if arg != subID || len(payload) < 3 {
+				continue
+			}
+			var encoded string
+			if err := json.Unmarshal(payload[2], &encoded); err != nil {
+				return Diff{}, fmt.Errorf("decode NEG-MSG: %w", err)
+			}
+			msg, err := hex.DecodeString(encoded)
+			if err != nil {
+				return Diff{}, fmt.Errorf("decode NEG-MSG: %w", err)
+			}
+			answered = true
+
+			next, have, need, err := neg.Reconcile(msg)
+			if err != nil {
+				return Diff{}, err
+			}
+			diff.Have = append(diff.Have, have...)
+			diff.Need = append(diff.Need, need...)
+			if next == nil {
+				_ = conn.WriteJSON([]any{"NEG-CLOSE", subID})
+				return diff, nil
+			}
+			if err := conn.WriteJSON([]any{"NEG-MSG", subID, hex.EncodeToString(next)}); err != nil {
+				return Diff{}, err
+			}
+		case "NEG-ERR":
+			if arg != subID {
+				continue
+			}
+			var reason string
+			if len(payload) > 2 {
+				_ = json.Unmarshal(payload[2], &reason)
+			}
+			return Diff{}, fmt.Errorf("%w: %s", ErrNegentropyUnavailable, reason)
+		case "CLOSED":
+			if arg == subID {
+				return Diff{}, fmt.Errorf("%w: closed", ErrNegentropyUnavailable)
+			}
+		case "NOTICE":
+			// NIP-77 を知らないリレーは NEG-OPEN を NOTICE で拒否する
+			if !answered {
+				return Diff{}, fmt.Errorf("%w: %s", ErrNegentropyUnavailable, arg)
+			}
+			c.logger.Warn("relay notice", "relay", relay, "notice", arg)
+		}
+	}
+}
+
+// FetchMissing returns the events matching filter on relay whose IDs are not
+// in local. When the relay supports NIP-77 only the missing events are
+// requested; otherwise it falls back to Fetch and drops the known ones.
+func (c *Client) FetchMissing(ctx context.Context, relay string, filter Filter, local []Event) ([]Event, error) {
+	diff, err := c.Reconcile(ctx, relay, filter, local)
+	if err == nil {
+		return c.FetchIDs(ctx, relay, diff.Need)
+	}
+	if !errors.Is(err, ErrNegentropyUnavailable) {
+		return nil, err
+	}
+
+	c.logger.Info("negentropy unavailable, falling back to REQ", "relay", relay, "error", err)
+	events, err := c.Fetch(ctx, relay, filter)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]struct{}, len(local))
+	for _, evt := range local {
+		known[evt.ID] = struct{}{}
+	}
+	missing := events[:0]
+	for _, evt := range events {
+		if _, ok := known[evt.ID]; !ok {
+			missing = append(missing, evt)
+		}
+	}
+	return missing, nil
+}
+
+// FetchIDs fetches the events with the given IDs from relay, in batches.
+func (c *Client) FetchIDs(ctx context.Context, relay string, ids []string) ([]Event, error) {
+	var out []Event
+	for start := 0; start < len(ids); start += maxIDsPerFetch {
+		batch := ids[start:min(start+maxIDsPerFetch, len(ids))]
+		events, err := c.Fetch(ctx, relay, Filter{IDs: batch, Limit: len(batch)})
+		out = append(out, events...)
+		if err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,184 @@
+package nostr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// seenCapacity bounds how many event IDs are remembered for deduplication.
+const seenCapacity = 4096
+
+// SeenSet remembers recently seen event IDs, evicting the oldest ones once
+// capacity is reached so long-running streams do not grow without bound.
+type SeenSet struct {
+	mu    sync.Mutex
+	ids   map[string]struct{}
+	order []string
+	next  int
+}
+
+// NewSeenSet returns an empty SeenSet remembering up to capacity IDs.
+func NewSeenSet(capacity int) *SeenSet {
+	return &SeenSet{
+		ids:   make(map[string]struct{}, capacity),
+		order: make([]string, 0, capacity),
+	}
+}
+
+// Add records id and reports whether it was not seen before.
+func (s *SeenSet) Add(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+	if len(s.order) < cap(s.order) {
+		s.order = append(s.order, id)
+	} else {
+		delete(s.ids, s.order[s.next])
+		s.order[s.next] = id
+		s.next = (s.next + 1) % len(s.order)
+	}
+	s.ids[id] = struct{}{}
+	return true
+}
+
+// FetchMany queries all relays concurrently and returns the union of their
+// stored events, deduplicated by ID and sorted newest first. It only fails
+// when every relay fails.
+func (c *Client) FetchMany(ctx context.Context, relays []string, filter Filter) ([]Event, error) {
+	if len(relays) == 0 {
+		return nil, errors.New("no relays")
+	}
+
+	type result struct {
+		relay  string
+		events []Event
+		err    error
+	}
+	results := make(chan result, len(relays))
+	for _, relay := range relays {
+		go func(relay string) {
+			events, err := c.Fetch(ctx, relay, filter)
+			results <- result{relay: relay, events: events, err: err}
+		}(relay)
+	}
+
+	seen := make(map[string]struct{})
+	var merged []Event
+	var errs []error
+	for range relays {
+		res := <-results
+		if res.err != nil {
+			c.logger.Warn("fetch failed", "relay", res.relay, "error", res.err)
+			errs = append(errs, res.err)
+		}
+		for _, evt := range res.events {
+			if _, ok := seen[evt.ID]; ok {
+				continue
+			}
+			seen[evt.ID] = struct{}{}
+			merged = append(merged, evt)
+		}
+	}
+	if len(errs) == len(relays) {
+		return nil, fmt.Errorf("all relays failed: %w", errors.Join(errs...))
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].CreatedAt > merged[j].CreatedAt
+	})
+	return merged, nil
+}
+
+// FetchLatest is FetchMany for callers that want the current state: every
+// replaceable or addressable event is reduced to the newest version per
+// address, since relays may lag behind each other.
+func (c *Client) FetchLatest(ctx context.Context, relays []string, filter Filter) ([]Event, error) {
+	events, err := c.FetchMany(ctx, relays, filter)
+	if err != nil {
+		return nil, err
+	}
+	return LatestPerAddress(events), nil
+}
+
+// StreamMany subscribes to every relay and merges their events into one
+// channel, dropping events already delivered by another relay.
+func (c *Client) StreamMany(ctx context.Context, relays []string, filter Filter) (<-chan Event, <-chan error) {
+	events := make(chan Event, 64)
+	errs := make(chan error, len(relays))
+	seen := NewSeenSet(seenCapacity)
+
+	var wg sync.WaitGroup
+	for _, relay := range relays {
+		relayEvents, relayErrs := c.Stream(ctx, relay, filter)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for evt := range relayEvents {
+				if !seen.Add(evt.ID) {
+					continue
+				}
+				select {
+				case events <- evt:
+				case <-ctx.Done():
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for err := range relayErrs {
+				c.emitError(errs, err)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(events)
+		close(errs)
+	}()
+
+	return events, errs
+}
+
+// PublishResult is the outcome of publishing an event to one relay.
+type PublishResult struct {
+	Relay string
+	Err   error
+}
+
+// PublishMany sends evt to all relays concurrently and returns one result per
+// relay in the order given. It only fails when every relay fails.
+func (c *Client) PublishMany(ctx context.Context, relays []string, evt Event) ([]PublishResult, error) {
+	if len(relays) == 0 {
+		return nil, errors.New("no relays")
+	}
+
+	results := make([]PublishResult, len(relays))
+	var wg sync.WaitGroup
+	for i, relay := range relays {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = PublishResult{Relay: relay, Err: c.Publish(ctx, relay, evt)}
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, res := range results {
+		if res.Err != nil {
+			c.logger.Warn("publish failed", "relay", res.Relay, "error", res.Err)
+			errs = append(errs, res.Err)
+		}
+	}
+	if len(errs) == len(relays) {
+		return results, fmt.Errorf("all relays failed: %w", errors.Join(errs...))
+	}
+	return results, nil
+}
@@ -0,0 +1,25 @@
+package nostr
+
+import "testing"
+
+func TestSeenSet(t *testing.T) {
+	seen := NewSeenSet(2)
+
+	if !seen.Add("a") || !seen.Add("b") {
+		t.Fatalf("first additions should be new")
+	}
+	if seen.Add("a") {
+		t.Fatalf("duplicate id should be rejected")
+	}
+
+	// Adding a third id evicts the oldest one.
+	if !seen.Add("c") {
+		t.Fatalf("c should be new")
+	}
+	if !seen.Add("a") {
+		t.Fatalf("a should have been evicted and accepted again")
+	}
+	if seen.Add("c") {
+		t.Fatalf("c should still be remembered")
+	}
+}
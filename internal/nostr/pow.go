@@ -0,0 +1,147 @@
+package nostr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TagNonce carries the NIP-13 proof-of-work nonce and committed target.
+const TagNonce = "nonce"
+
+// noncePlaceholder is substituted into the serialized event while mining so the
+// JSON encoding only has to be computed once.
+const noncePlaceholder = "\x00noscli-nonce\x00"
+
+// Difficulty returns the number of leading zero bits of a hex event ID.
+func Difficulty(id string) int {
+	count := 0
+	for i := 0; i < len(id); i++ {
+		v, err := strconv.ParseUint(id[i:i+1], 16, 8)
+		if err != nil {
+			return count
+		}
+		if v == 0 {
+			count += 4
+			continue
+		}
+		count += bits.LeadingZeros8(uint8(v)) - 4
+		break
+	}
+	return count
+}
+
+// PoWDifficulty returns the effective NIP-13 difficulty of the event. When the
+// nonce tag commits to a target lower than the actual difficulty, the target is
+// used so that lucky hashes do not count as more work than was committed.
+func (e Event) PoWDifficulty() int {
+	actual := Difficulty(e.ID)
+	for _, tag := range e.Tags {
+		if len(tag) < 3 || tag[0] != TagNonce {
+			continue
+		}
+		if target, err := strconv.Atoi(tag[2]); err == nil && target < actual {
+			return target
+		}
+		break
+	}
+	return actual
+}
+
+// MinePoW searches for a nonce tag that gives the event ID at least target
+// leading zero bits, using all CPU cores until found or ctx is cancelled.
+// Any existing nonce tag is replaced and evt.ID is set to the mined ID; the
+// event must be signed afterwards.
+func MinePoW(ctx context.Context, evt *Event, target int) error {
+	if target <= 0 {
+		return nil
+	}
+	if target > 256 {
+		return fmt.Errorf("invalid pow target: %d", target)
+	}
+
+	tags := make([][]string, 0, len(evt.Tags)+1)
+	for _, tag := range evt.Tags {
+		if len(tag) > 0 && tag[0] == TagNonce {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	tags = append(tags, []string{TagNonce, noncePlaceholder, strconv.Itoa(target)})
+
+	serialized := serializeEvent(evt.PubKey, evt.CreatedAt, evt.Kind, tags, evt.Content)
+	placeholder := appendJSONString(nil, noncePlaceholder)
+	prefix, suffix, ok := strings.Cut(string(serialized), string(placeholder))
+	if !ok {
+		return errors.New("nonce placeholder not found in payload")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := runtime.NumCPU()
+	found := make(chan uint64, 1)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(start uint64) {
+			defer wg.Done()
+			buf := make([]byte, 0, len(prefix)+len(suffix)+24)
+			for i := uint64(0); ; i++ {
+				// ctx の確認は一定間隔に抑えてハッシュ計算を優先する
+				if i%1024 == 0 && ctx.Err() != nil {
+					return
+				}
+				nonce := start + i*uint64(workers)
+				buf = append(buf[:0], prefix...)
+				buf = append(buf, '"')
+				buf = strconv.AppendUint(buf, nonce, 10)
+				buf = append(buf, '"')
+				buf = append(buf, suffix...)
+				hash := sha256.Sum256(buf)
+				if leadingZeroBits(hash) >= target {
+					select {
+					case found <- nonce:
+					default:
+					}
+					cancel()
+					return
+				}
+			}
+		}(uint64(w))
+	}
+	wg.Wait()
+
+	select {
+	case nonce := <-found:
+		tags[len(tags)-1][1] = strconv.FormatUint(nonce, 10)
+		evt.Tags = tags
+		hash, err := hashEvent(*evt)
+		if err != nil {
+			return err
+		}
+		evt.ID = hex.EncodeToString(hash[:])
+		return nil
+	default:
+		return ctx.Err()
+	}
+}
+
+func leadingZeroBits(hash [32]byte) int {
+	count := 0
+	for _, b := range hash {
+		if b == 0 {
+			count += 8
+			continue
+		}
+		return count + bits.LeadingZeros8(b)
+	}
+	return count
+}
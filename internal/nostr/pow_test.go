@@ -0,0 +1,91 @@
+package nostr
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDifficulty(t *testing.T) {
+	tests := []struct {
+		id   string
+		want int
+	}{
+		{id: "ffff", want: 0},
+		{id: "7fff", want: 1},
+		{id: "0fff", want: 4},
+		{id: "00ff", want: 8},
+		{id: "000000000e9d97a1ab09fc381030b346cdd7a142ad57e6df0b46dc9bef6c7e2d", want: 36},
+		{id: "0000", want: 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if got := Difficulty(tt.id); got != tt.want {
+				t.Fatalf("Difficulty(%q) = %d, want %d", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventPoWDifficulty(t *testing.T) {
+	evt := Event{
+		ID:   "000000ff" + strings.Repeat("f", 56),
+		Tags: [][]string{{"nonce", "1", "16"}},
+	}
+	if got := evt.PoWDifficulty(); got != 16 {
+		t.Fatalf("PoWDifficulty() with lower commitment = %d, want 16", got)
+	}
+
+	evt.Tags = [][]string{{"nonce", "1", "30"}}
+	if got := evt.PoWDifficulty(); got != 24 {
+		t.Fatalf("PoWDifficulty() with higher commitment = %d, want 24", got)
+	}
+}
+
+func TestMinePoW(t *testing.T) {
+	evt := mustValidEvent(t)
+	evt.Tags = append(evt.Tags, []string{"nonce", "stale", "1"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := MinePoW(ctx, &evt, 12); err != nil {
+		t.Fatalf("MinePoW() unexpected error: %v", err)
+	}
+
+	if got := Difficulty(evt.ID); got < 12 {
+		t.Fatalf("Difficulty() = %d, want >= 12", got)
+	}
+	hash := computeEventHash(t, evt)
+	if Difficulty(evt.ID) != leadingZeroBits(hash) {
+		t.Fatalf("mined ID does not match event hash")
+	}
+
+	nonceTags := 0
+	for _, tag := range evt.Tags {
+		if tag[0] == TagNonce {
+			nonceTags++
+			if len(tag) != 3 || tag[2] != "12" {
+				t.Fatalf("unexpected nonce tag: %#v", tag)
+			}
+		}
+	}
+	if nonceTags != 1 {
+		t.Fatalf("nonce tags = %d, want 1", nonceTags)
+	}
+}
+
+func TestMinePoWCancelled(t *testing.T) {
+	evt := mustValidEvent(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := MinePoW(ctx, &evt, 200)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("MinePoW() error = %v, want context.Canceled", err)
+	}
+}
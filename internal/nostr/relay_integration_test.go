@@ -0,0 +1,372 @@
+package nostr_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"noscli/internal/nostr"
+	"noscli/internal/relaytest"
+)
+
+var testKey = bytes.Repeat([]byte{0x02}, 32)
+
+func newClient(opts ...nostr.ClientOption) *nostr.Client {
+	return nostr.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), opts...)
+}
+
+func newRelay(t *testing.T) *relaytest.Relay {
+	t.Helper()
+	relay := relaytest.New()
+	t.Cleanup(relay.Close)
+	return relay
+}
+
+func signedNote(t *testing.T, content string, createdAt int64) nostr.Event {
+	t.Helper()
+	pub, err := nostr.PublicKey(testKey)
+	if err != nil {
+		t.Fatalf("PublicKey() error: %v", err)
+	}
+	evt := nostr.Event{PubKey: pub, CreatedAt: createdAt, Kind: nostr.KindTextNote, Tags: [][]string{}, Content: content}
+	if err := nostr.SignEvent(&evt, testKey); err != nil {
+		t.Fatalf("SignEvent() error: %v", err)
+	}
+	return evt
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func receive(t *testing.T, events <-chan nostr.Event) nostr.Event {
+	t.Helper()
+	select {
+	case evt := <-events:
+		return evt
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for an event")
+		return nostr.Event{}
+	}
+}
+
+func TestClientPublishAndFetch(t *testing.T) {
+	relay := newRelay(t)
+	relay.SetReject(func(evt nostr.Event) string {
+		if strings.Contains(evt.Content, "spam") {
+			return "blocked: no spam"
+		}
+		return ""
+	})
+	client := newClient()
+	ctx := context.Background()
+
+	for i, content := range []string{"first", "second", "third"} {
+		if err := client.Publish(ctx, relay.URL, signedNote(t, content, int64(1_700_000_000+i))); err != nil {
+			t.Fatalf("Publish(%q) error: %v", content, err)
+		}
+	}
+
+	err := client.Publish(ctx, relay.URL, signedNote(t, "buy spam", 1_700_000_100))
+	var rejected *nostr.RejectedError
+	if !errors.As(err, &rejected) || rejected.Reason() != "blocked" {
+		t.Fatalf("Publish(spam) error = %v, want blocked rejection", err)
+	}
+
+	events, err := client.Fetch(ctx, relay.URL, nostr.Filter{Kinds: []int{nostr.KindTextNote}, Limit: 2})
+	if err != nil {
+		t.Fatalf("Fetch() error: %v", err)
+	}
+	if len(events) != 2 || events[0].Content != "third" || events[1].Content != "second" {
+		t.Fatalf("Fetch() = %+v, want the two newest notes", events)
+	}
+	if events[0].Relay != relay.URL || len(events[0].Raw) == 0 {
+		t.Fatalf("fetched event missing relay or raw JSON: %+v", events[0])
+	}
+	if len(relay.Events()) != 3 {
+		t.Fatalf("relay stored %d events, want 3", len(relay.Events()))
+	}
+}
+
+func TestClientFetchLatest(t *testing.T) {
+	stale, fresh := newRelay(t), newRelay(t)
+	client := newClient()
+	ctx := context.Background()
+
+	pub, err := nostr.PublicKey(testKey)
+	if err != nil {
+		t.Fatalf("PublicKey() error: %v", err)
+	}
+	var versions []nostr.Event
+	for i, content := range []string{`{"name":"old"}`, `{"name":"new"}`} {
+		evt := nostr.Event{PubKey: pub, CreatedAt: int64(1_700_000_000 + i), Kind: 0, Tags: [][]string{}, Content: content}
+		if err := nostr.SignEvent(&evt, testKey); err != nil {
+			t.Fatalf("SignEvent() error: %v", err)
+		}
+		versions = append(versions, evt)
+	}
+	stale.Publish(versions[0])
+	fresh.Publish(versions[1])
+	relays := []string{stale.URL, fresh.URL}
+	filter := nostr.Filter{Authors: []string{pub}, Kinds: []int{0}}
+
+	// FetchMany keeps every version; FetchLatest only the current one.
+	all, err := client.FetchMany(ctx, relays, filter)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("FetchMany() = %d events, %v; want both versions", len(all), err)
+	}
+	latest, err := client.FetchLatest(ctx, relays, filter)
+	if err != nil || len(latest) != 1 || latest[0].ID != versions[1].ID {
+		t.Fatalf("FetchLatest() = %+v, %v; want the newest version", latest, err)
+	}
+}
+
+func TestClientStreamResumesAfterDisconnect(t *testing.T) {
+	relay := newRelay(t)
+	client := newClient(nostr.WithBackoff(10*time.Millisecond, 20*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, _ := client.Stream(ctx, relay.URL, nostr.Filter{Kinds: []int{nostr.KindTextNote}})
+	waitFor(t, "subscription", func() bool { return len(relay.Filters()) == 1 })
+
+	now := time.Now().Unix()
+	first := signedNote(t, "before outage", now)
+	relay.Publish(first)
+	if got := receive(t, events); got.ID != first.ID {
+		t.Fatalf("received %q, want the first note", got.Content)
+	}
+
+	relay.DropConnections()
+	during := signedNote(t, "during outage", now+1)
+	relay.Publish(during)
+
+	// The resumed REQ covers the first note again; it must not be repeated.
+	if got := receive(t, events); got.ID != during.ID {
+		t.Fatalf("received %q after reconnect, want the note published during the outage", got.Content)
+	}
+	select {
+	case evt := <-events:
+		t.Fatalf("unexpected extra event %q", evt.Content)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	filters := relay.Filters()
+	resumed := filters[len(filters)-1]
+	if resumed.Since == nil || resumed.Since.Unix() > first.CreatedAt {
+		t.Fatalf("resumed since = %v, want at or before %d", resumed.Since, first.CreatedAt)
+	}
+	stats, _ := client.RelayStats(relay.URL)
+	if stats.Reconnects < 1 || stats.Events < 2 {
+		t.Fatalf("stats = %+v, want a reconnect and both events counted", stats)
+	}
+}
+
+func TestClientStreamResumesAfterFutureDatedEvent(t *testing.T) {
+	relay := newRelay(t)
+	client := newClient(nostr.WithBackoff(10*time.Millisecond, 20*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, _ := client.Stream(ctx, relay.URL, nostr.Filter{Kinds: []int{nostr.KindTextNote}})
+	waitFor(t, "subscription", func() bool { return len(relay.Filters()) == 1 })
+
+	future := signedNote(t, "from the future", time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC).Unix())
+	relay.Publish(future)
+	if got := receive(t, events); got.ID != future.ID {
+		t.Fatalf("received %q, want the future-dated note", got.Content)
+	}
+
+	relay.DropConnections()
+	during := signedNote(t, "during outage", time.Now().Unix()+1)
+	relay.Publish(during)
+
+	if got := receive(t, events); got.ID != during.ID {
+		t.Fatalf("received %q after reconnect, want the note published during the outage", got.Content)
+	}
+	filters := relay.Filters()
+	resumed := filters[len(filters)-1]
+	if resumed.Since == nil || resumed.Since.After(time.Now()) {
+		t.Fatalf("resumed since = %v, want it clamped to the present", resumed.Since)
+	}
+}
+
+func TestClientToleratesFaults(t *testing.T) {
+	relay := newRelay(t)
+	for i := range 4 {
+		relay.Publish(signedNote(t, "note", int64(1_700_000_000+i)))
+	}
+	forged := signedNote(t, "forged", 1_700_000_010)
+	forged.Content = "tampered"
+	relay.Publish(forged)
+
+	relay.SetFaults(relaytest.Faults{Malformed: true, DropEvery: 2})
+	client := newClient()
+	events, err := client.Fetch(context.Background(), relay.URL, nostr.Filter{Kinds: []int{nostr.KindTextNote}})
+	if err != nil {
+		t.Fatalf("Fetch() error: %v", err)
+	}
+
+	// Five events were sent newest first; every second one was dropped, and
+	// the forged one (sent first) fails verification.
+	if len(events) != 2 {
+		t.Fatalf("Fetch() returned %d events, want 2", len(events))
+	}
+	stats, _ := client.RelayStats(relay.URL)
+	if stats.Invalid != 1 || stats.Events != 2 {
+		t.Fatalf("stats = %+v, want 1 invalid and 2 valid events", stats)
+	}
+
+	relay.SetFaults(relaytest.Faults{Delay: 200 * time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Fetch(ctx, relay.URL, nostr.Filter{Limit: 1}); err == nil {
+		t.Fatalf("Fetch() against a slow relay should time out")
+	}
+}
+
+func TestClientReqReportsRelayMessages(t *testing.T) {
+	relay := newRelay(t)
+	client := newClient()
+
+	var (
+		mu   sync.Mutex
+		msgs []nostr.RelayMessage
+	)
+	onMessage := func(msg nostr.RelayMessage) {
+		mu.Lock()
+		defer mu.Unlock()
+		msgs = append(msgs, msg)
+	}
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(msgs)
+	}
+
+	events, errs := client.Req(context.Background(), relay.URL, nostr.Filter{Kinds: []int{nostr.KindTextNote}}, false, onMessage)
+	waitFor(t, "EOSE", func() bool { return count() == 1 })
+	relay.Notice("slow down")
+	waitFor(t, "NOTICE", func() bool { return count() == 2 })
+	relay.CloseSubscriptions("error: shutting down")
+
+	for range events {
+	}
+	err := <-errs
+	if err == nil || !strings.Contains(err.Error(), "error: shutting down") {
+		t.Fatalf("Req() error = %v, want CLOSED reason", err)
+	}
+
+	want := []string{nostr.MessageEOSE, nostr.MessageNotice, nostr.MessageClosed}
+	if count() != len(want) {
+		t.Fatalf("messages = %+v, want %v", msgs, want)
+	}
+	for i, msg := range msgs {
+		if msg.Type != want[i] || msg.Relay != relay.URL {
+			t.Fatalf("message %d = %+v, want %s", i, msg, want[i])
+		}
+	}
+	if msgs[1].Message != "slow down" {
+		t.Fatalf("NOTICE message = %q", msgs[1].Message)
+	}
+}
+
+func TestClientAuthRequired(t *testing.T) {
+	relay := newRelay(t)
+	relay.RequireAuth("challenge")
+	client := newClient()
+
+	err := client.Publish(context.Background(), relay.URL, signedNote(t, "hello", 1_700_000_000))
+	var rejected *nostr.RejectedError
+	if !errors.As(err, &rejected) || rejected.Reason() != "auth-required" {
+		t.Fatalf("Publish() error = %v, want auth-required rejection", err)
+	}
+
+	_, err = client.Fetch(context.Background(), relay.URL, nostr.Filter{Limit: 1})
+	if err == nil || !strings.Contains(err.Error(), "auth-required") {
+		t.Fatalf("Fetch() error = %v, want auth-required CLOSED", err)
+	}
+}
+
+func TestClientFetchMissing(t *testing.T) {
+	for _, negentropy := range []bool{true, false} {
+		t.Run(fmt.Sprintf("negentropy=%v", negentropy), func(t *testing.T) {
+			relay := newRelay(t)
+			if negentropy {
+				relay.EnableNegentropy()
+			}
+			var remote []nostr.Event
+			for i := range 5 {
+				evt := signedNote(t, fmt.Sprintf("note %d", i), int64(1_700_000_000+i))
+				relay.Publish(evt)
+				remote = append(remote, evt)
+			}
+			onlyLocal := signedNote(t, "local only", 1_700_000_100)
+			local := []nostr.Event{remote[0], remote[2], remote[4], onlyLocal}
+
+			client := newClient()
+			filter := nostr.Filter{Kinds: []int{nostr.KindTextNote}}
+			missing, err := client.FetchMissing(context.Background(), relay.URL, filter, local)
+			if err != nil {
+				t.Fatalf("FetchMissing() error: %v", err)
+			}
+			var got []string
+			for _, evt := range missing {
+				got = append(got, evt.Content)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != "note 1,note 3" {
+				t.Fatalf("FetchMissing() = %v, want note 1 and note 3", got)
+			}
+
+			// With negentropy only the missing IDs are requested.
+			filters := relay.Filters()
+			if negentropy && (len(filters) != 1 || len(filters[0].IDs) != 2) {
+				t.Fatalf("REQ filters = %+v, want a single request by ID", filters)
+			}
+			if !negentropy && (len(filters) != 1 || len(filters[0].Kinds) != 1) {
+				t.Fatalf("REQ filters = %+v, want the fallback REQ", filters)
+			}
+		})
+	}
+}
+
+func TestClientReconcile(t *testing.T) {
+	relay := newRelay(t)
+	relay.EnableNegentropy()
+	shared := signedNote(t, "shared", 1_700_000_000)
+	remote := signedNote(t, "remote", 1_700_000_001)
+	local := signedNote(t, "local", 1_700_000_002)
+	relay.Publish(shared)
+	relay.Publish(remote)
+
+	diff, err := newClient().Reconcile(context.Background(), relay.URL, nostr.Filter{}, []nostr.Event{shared, local})
+	if err != nil {
+		t.Fatalf("Reconcile() error: %v", err)
+	}
+	if len(diff.Have) != 1 || diff.Have[0] != local.ID || len(diff.Need) != 1 || diff.Need[0] != remote.ID {
+		t.Fatalf("Reconcile() = %+v, want have [local] need [remote]", diff)
+	}
+
+	relay = newRelay(t)
+	_, err = newClient().Reconcile(context.Background(), relay.URL, nostr.Filter{}, nil)
+	if !errors.Is(err, nostr.ErrNegentropyUnavailable) {
+		t.Fatalf("Reconcile() without NIP-77 error = %v, want ErrNegentropyUnavailable", err)
+	}
+}
@@ -0,0 +1,76 @@
+package nostr
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// serializeEvent builds the NIP-01 commitment [0,pubkey,created_at,kind,tags,content]
+// whose SHA-256 is the event ID.
+//
+// NIP-01 fixes the serialization byte for byte: no whitespace, and in strings
+// only \", \\, \n, \r, \t, \b and \f are escaped, the remaining control
+// characters below 0x20 become \u00XX and everything else is written as
+// UTF-8. encoding/json cannot be used because it also escapes '<',
+// '>', '&' and U+2028/U+2029, so events containing them would get IDs that no
+// other implementation computes, and their valid signatures would fail to verify.
+func serializeEvent(pubkey string, createdAt int64, kind int, tags [][]string, content string) []byte {
+	buf := make([]byte, 0, 128+len(content))
+	buf = append(buf, "[0,"...)
+	buf = appendJSONString(buf, pubkey)
+	buf = append(buf, ',')
+	buf = strconv.AppendInt(buf, createdAt, 10)
+	buf = append(buf, ',')
+	buf = strconv.AppendInt(buf, int64(kind), 10)
+	buf = append(buf, ",["...)
+	for i, tag := range tags {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, '[')
+		for j, v := range tag {
+			if j > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendJSONString(buf, v)
+		}
+		buf = append(buf, ']')
+	}
+	buf = append(buf, "],"...)
+	buf = appendJSONString(buf, content)
+	return append(buf, ']')
+}
+
+// appendJSONString quotes s using only the escapes listed in NIP-01, plus
+// \u00XX for the remaining control characters as JSON requires.
+func appendJSONString(buf []byte, s string) []byte {
+	const hexDigits = "0123456789abcdef"
+
+	buf = append(buf, '"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf = append(buf, '\\', '"')
+		case '\\':
+			buf = append(buf, '\\', '\\')
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		case '\b':
+			buf = append(buf, '\\', 'b')
+		case '\f':
+			buf = append(buf, '\\', 'f')
+		default:
+			if r < 0x20 {
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[r>>4], hexDigits[r&0xf])
+				continue
+			}
+			// 不正な UTF-8 は encoding/json と同じく U+FFFD に置き換わる
+			buf = utf8.AppendRune(buf, r)
+		}
+	}
+	return append(buf, '"')
+}
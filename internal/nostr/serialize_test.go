@@ -0,0 +1,101 @@
+package nostr
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSerializeEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    [][]string
+		content string
+		want    string
+	}{
+		{
+			name:    "plain",
+			tags:    [][]string{{"e", "abc", ""}, {"t", "nostr"}},
+			content: "hello",
+			want:    `[0,"pk",1700000000,1,[["e","abc",""],["t","nostr"]],"hello"]`,
+		},
+		{
+			name:    "html characters and line separators stay verbatim",
+			tags:    [][]string{},
+			content: "<a href=\"x\">&amp;</a>\u2028",
+			want:    "[0,\"pk\",1700000000,1,[],\"<a href=\\\"x\\\">&amp;</a>\u2028\"]",
+		},
+		{
+			name:    "nip-01 escapes",
+			tags:    nil,
+			content: "line\nbreak\ttab\rcr\\back\b\f\x01\x7f",
+			want:    `[0,"pk",1700000000,1,[],"line\nbreak\ttab\rcr\\back\b\f\u0001` + "\x7f" + `"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(serializeEvent("pk", 1700000000, 1, tt.tags, tt.content))
+			if got != tt.want {
+				t.Fatalf("serializeEvent() = %s, want %s", got, tt.want)
+			}
+			var decoded []any
+			if err := json.Unmarshal([]byte(got), &decoded); err != nil {
+				t.Fatalf("serialized event is not valid JSON: %v", err)
+			}
+			if decoded[5] != tt.content {
+				t.Fatalf("content round trip = %q, want %q", decoded[5], tt.content)
+			}
+		})
+	}
+}
+
+// TestComputeIDGolden pins event IDs for content that encoding/json would
+// escape differently. The expected IDs come from an independent NIP-01
+// implementation (go-nostr).
+func TestComputeIDGolden(t *testing.T) {
+	const pubkey = "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	tests := []struct {
+		name    string
+		tags    [][]string
+		content string
+		want    string
+	}{
+		{
+			name:    "html characters",
+			tags:    [][]string{{"t", "<x>&"}},
+			content: "<b>a & b</b>",
+			want:    "9426e51ecbc305282fb2465032a30d524b4bf9e7e8719ceca6c187b4acc1d154",
+		},
+		{
+			name:    "line and paragraph separators",
+			tags:    [][]string{},
+			content: "a\u2028b\u2029c",
+			want:    "8981b492ec269409acc7605512a8fd07fd579dfedec36e8067b8cfe98a6ec4ed",
+		},
+		{
+			name:    "control characters",
+			tags:    [][]string{{"e", "\x1b[31m"}},
+			content: "\x00\x01\x1f\x7f \b\f\n\r\t \"\\",
+			want:    "5d2e6c22e57f8c92e6e757894bff66003766f20a44823819b25ea077fcd6e6d9",
+		},
+		{
+			name:    "non-BMP runes",
+			tags:    [][]string{{"emoji", "🦩"}},
+			content: "🤙 𝄞 \U0010FFFF",
+			want:    "9a10072c238fc4d16984481b2fef25455669d16177b1a3d0f4805a9a7790ad79",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt := Event{PubKey: pubkey, CreatedAt: 1_700_000_000, Kind: KindTextNote, Tags: tt.tags, Content: tt.content}
+			got, err := evt.ComputeID()
+			if err != nil {
+				t.Fatalf("ComputeID() error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("ComputeID() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package nostr
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RelayState is the connection state of a relay as seen by a Client.
+type RelayState string
+
+const (
+	StateConnecting RelayState = "connecting"
+	StateConnected  RelayState = "connected"
+	// StateBackoff means Stream lost the connection and waits before redialing.
+	StateBackoff RelayState = "backing-off"
+	// StateFailed means the last dial failed. Stream moves a relay it is going
+	// to redial on to StateBackoff, so a relay left here is not being retried.
+	StateFailed RelayState = "failed"
+	// StateClosed means every connection to the relay was closed normally.
+	StateClosed RelayState = "closed"
+)
+
+// RelayStats is a snapshot of a relay's state and counters.
+type RelayStats struct {
+	Relay string
+	State RelayState
+	// Changed is when State last changed.
+	Changed time.Time
+	// Events counts verified events received; Invalid counts events dropped
+	// because their ID or signature did not verify.
+	Events  int64
+	Invalid int64
+	// Reconnects counts connections Stream made after its first one.
+	Reconnects int64
+	// Latency is the WebSocket handshake time of the latest connection.
+	Latency   time.Duration
+	LastError string
+}
+
+type relayRecord struct {
+	stats RelayStats
+	// open counts live connections, since Stream, Fetch and Publish may overlap.
+	open int
+}
+
+// statsRegistry holds RelayStats for every relay a Client has talked to.
+type statsRegistry struct {
+	mu     sync.Mutex
+	relays map[string]*relayRecord
+}
+
+func newStatsRegistry() *statsRegistry {
+	return &statsRegistry{relays: make(map[string]*relayRecord)}
+}
+
+func (r *statsRegistry) update(relay string, fn func(rec *relayRecord)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec, ok := r.relays[relay]
+	if !ok {
+		rec = &relayRecord{stats: RelayStats{Relay: relay}}
+		r.relays[relay] = rec
+	}
+	fn(rec)
+}
+
+func (rec *relayRecord) setState(state RelayState) {
+	if rec.stats.State != state {
+		rec.stats.State = state
+		rec.stats.Changed = time.Now()
+	}
+}
+
+func (r *statsRegistry) dialing(relay string) {
+	r.update(relay, func(rec *relayRecord) {
+		if rec.open == 0 {
+			rec.setState(StateConnecting)
+		}
+	})
+}
+
+func (r *statsRegistry) connected(relay string, latency time.Duration) {
+	r.update(relay, func(rec *relayRecord) {
+		rec.open++
+		rec.stats.Latency = latency
+		rec.setState(StateConnected)
+	})
+}
+
+// failed records err; state applies only while no other connection is open.
+func (r *statsRegistry) failed(relay string, state RelayState, err error) {
+	r.update(relay, func(rec *relayRecord) {
+		rec.stats.LastError = err.Error()
+		if rec.open == 0 {
+			rec.setState(state)
+		}
+	})
+}
+
+// closed records the end of a connection, with the error that ended it if any.
+func (r *statsRegistry) closed(relay string, err error) {
+	r.update(relay, func(rec *relayRecord) {
+		rec.open--
+		if err != nil {
+			rec.stats.LastError = err.Error()
+		}
+		if rec.open == 0 {
+			rec.setState(StateClosed)
+		}
+	})
+}
+
+func (r *statsRegistry) count(relay string, valid bool) {
+	r.update(relay, func(rec *relayRecord) {
+		if valid {
+			rec.stats.Events++
+		} else {
+			rec.stats.Invalid++
+		}
+	})
+}
+
+func (r *statsRegistry) get(relay string) (RelayStats, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec, ok := r.relays[relay]
+	if !ok {
+		return RelayStats{}, false
+	}
+	return rec.stats, true
+}
+
+func (r *statsRegistry) all() []RelayStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]RelayStats, 0, len(r.relays))
+	for _, rec := range r.relays {
+		out = append(out, rec.stats)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Relay < out[j].Relay })
+	return out
+}
+
+// Probe connects to relay, requests one stored event and waits for EOSE, then
+// returns the relay's stats. The error is that of the probe itself.
+func (c *Client) Probe(ctx context.Context, relay string) (RelayStats, error) {
+	_, err := c.Fetch(ctx, relay, Filter{Limit: 1})
+	stats, _ := c.stats.get(relay)
+	return stats, err
+}
@@ -0,0 +1,45 @@
+package nostr
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStatsRegistryStates(t *testing.T) {
+	r := newStatsRegistry()
+	const relay = "wss://relay"
+
+	r.dialing(relay)
+	if st, _ := r.get(relay); st.State != StateConnecting {
+		t.Fatalf("state = %s, want connecting", st.State)
+	}
+
+	// Two overlapping connections: the relay stays connected until both close.
+	r.connected(relay, 10*time.Millisecond)
+	r.dialing(relay)
+	r.connected(relay, 20*time.Millisecond)
+	r.closed(relay, nil)
+	if st, _ := r.get(relay); st.State != StateConnected || st.Latency != 20*time.Millisecond {
+		t.Fatalf("stats = %+v, want connected with latest latency", st)
+	}
+	r.closed(relay, errors.New("read: reset"))
+	if st, _ := r.get(relay); st.State != StateClosed || st.LastError != "read: reset" {
+		t.Fatalf("stats = %+v, want closed with last error", st)
+	}
+
+	r.failed(relay, StateBackoff, errors.New("dial: refused"))
+	r.count(relay, true)
+	r.count(relay, false)
+	st, ok := r.get(relay)
+	if !ok || st.State != StateBackoff || st.Events != 1 || st.Invalid != 1 || st.LastError != "dial: refused" {
+		t.Fatalf("stats = %+v", st)
+	}
+
+	if _, ok := r.get("wss://other"); ok {
+		t.Fatalf("unknown relay should have no stats")
+	}
+	if all := r.all(); len(all) != 1 || all[0].Relay != relay {
+		t.Fatalf("all() = %+v", all)
+	}
+}
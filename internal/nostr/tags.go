@@ -0,0 +1,56 @@
+package nostr
+
+import (
+	"strconv"
+	"time"
+)
+
+// Well-known tag names handled by noscli.
+const (
+	// TagContentWarning marks sensitive content (NIP-36).
+	TagContentWarning = "content-warning"
+	// TagExpiration holds the unix time after which the event should be discarded (NIP-40).
+	TagExpiration = "expiration"
+)
+
+// TagValue returns the first value of the first tag named name.
+// ok is false when no such tag exists; a tag without a value yields "".
+func (e Event) TagValue(name string) (string, bool) {
+	for _, tag := range e.Tags {
+		if len(tag) == 0 || tag[0] != name {
+			continue
+		}
+		if len(tag) < 2 {
+			return "", true
+		}
+		return tag[1], true
+	}
+	return "", false
+}
+
+// ContentWarning returns the NIP-36 reason and whether the event is marked sensitive.
+func (e Event) ContentWarning() (string, bool) {
+	return e.TagValue(TagContentWarning)
+}
+
+// Expiration returns the NIP-40 expiration time if the event carries a valid one.
+func (e Event) Expiration() (time.Time, bool) {
+	v, ok := e.TagValue(TagExpiration)
+	if !ok {
+		return time.Time{}, false
+	}
+	ts, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(ts, 0).UTC(), true
+}
+
+// IsExpired reports whether the event has an expiration at or before now.
+func (e Event) IsExpired(now time.Time) bool {
+	exp, ok := e.Expiration()
+	if !ok {
+		return false
+	}
+	return !now.Before(exp)
+}
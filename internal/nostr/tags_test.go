@@ -0,0 +1,86 @@
+package nostr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventContentWarning(t *testing.T) {
+	tests := []struct {
+		name       string
+		tags       [][]string
+		wantReason string
+		wantOK     bool
+	}{
+		{
+			name: "no tag",
+			tags: [][]string{{"t", "nostr"}},
+		},
+		{
+			name:       "tag with reason",
+			tags:       [][]string{{"t", "nostr"}, {"content-warning", "spoiler"}},
+			wantReason: "spoiler",
+			wantOK:     true,
+		},
+		{
+			name:   "tag without reason",
+			tags:   [][]string{{"content-warning"}},
+			wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt := Event{Tags: tt.tags}
+			reason, ok := evt.ContentWarning()
+			if reason != tt.wantReason || ok != tt.wantOK {
+				t.Fatalf("ContentWarning() = (%q, %v), want (%q, %v)", reason, ok, tt.wantReason, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestEventIsExpired(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name string
+		tags [][]string
+		want bool
+	}{
+		{
+			name: "no expiration",
+			tags: [][]string{},
+			want: false,
+		},
+		{
+			name: "expiration in the future",
+			tags: [][]string{{"expiration", "1700000100"}},
+			want: false,
+		},
+		{
+			name: "expiration in the past",
+			tags: [][]string{{"expiration", "1699999900"}},
+			want: true,
+		},
+		{
+			name: "expiration equal to now",
+			tags: [][]string{{"expiration", "1700000000"}},
+			want: true,
+		},
+		{
+			name: "malformed expiration is ignored",
+			tags: [][]string{{"expiration", "tomorrow"}},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt := Event{Tags: tt.tags}
+			if got := evt.IsExpired(now); got != tt.want {
+				t.Fatalf("IsExpired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package nostr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// MaxKind is the largest kind allowed by NIP-01.
+	MaxKind = 65535
+	// maxCreatedAtSkew is how far in the future created_at may be before
+	// Validate reports it.
+	maxCreatedAtSkew = 24 * time.Hour
+)
+
+// requiredFields are the members every NIP-01 event object must have.
+var requiredFields = []string{"id", "pubkey", "created_at", "kind", "tags", "content", "sig"}
+
+// ParseEventStrict decodes a single event object, rejecting missing members
+// and members of the wrong JSON type instead of zero-filling them.
+func ParseEventStrict(data []byte) (Event, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Event{}, fmt.Errorf("not a JSON object: %w", err)
+	}
+	for _, name := range requiredFields {
+		raw, ok := fields[name]
+		if !ok || string(raw) == "null" {
+			return Event{}, fmt.Errorf("missing field %q", name)
+		}
+	}
+
+	var evt Event
+	if err := json.Unmarshal(data, &evt); err != nil {
+		return Event{}, fmt.Errorf("invalid field type: %w", err)
+	}
+	// encoding/json decodes null as "" or a nil slice, so look for it explicitly
+	var tags [][]*string
+	if err := json.Unmarshal(fields["tags"], &tags); err != nil {
+		return Event{}, fmt.Errorf("invalid field type: %w", err)
+	}
+	for i, tag := range tags {
+		if tag == nil {
+			return Event{}, fmt.Errorf("tag %d is not an array", i)
+		}
+		for j, v := range tag {
+			if v == nil {
+				return Event{}, fmt.Errorf("tag %d value %d is not a string", i, j)
+			}
+		}
+	}
+	evt.Raw = data
+	return evt, nil
+}
+
+// Validate reports NIP-01 structural problems that Verify does not cover:
+// hex encodings and lengths, the kind range, created_at sanity and empty
+// tags. It does not check the ID or signature themselves.
+func (e Event) Validate(now time.Time) []error {
+	var problems []error
+	if !isLowerHex(e.ID, 64) {
+		problems = append(problems, errors.New("id must be 64 lowercase hex characters"))
+	}
+	if !isLowerHex(e.PubKey, 64) {
+		problems = append(problems, errors.New("pubkey must be 64 lowercase hex characters"))
+	}
+	if !isLowerHex(e.Sig, 128) {
+		problems = append(problems, errors.New("sig must be 128 lowercase hex characters"))
+	}
+	if e.Kind < 0 || e.Kind > MaxKind {
+		problems = append(problems, fmt.Errorf("kind %d is outside 0-%d", e.Kind, MaxKind))
+	}
+	if e.CreatedAt <= 0 {
+		problems = append(problems, fmt.Errorf("created_at %d is not a positive unix timestamp", e.CreatedAt))
+	} else if e.CreatedAtTime().After(now.Add(maxCreatedAtSkew)) {
+		problems = append(problems, fmt.Errorf("created_at %d is too far in the future", e.CreatedAt))
+	}
+	for i, tag := range e.Tags {
+		if len(tag) == 0 {
+			problems = append(problems, fmt.Errorf("tag %d is empty", i))
+		}
+	}
+	return problems
+}
+
+func isLowerHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
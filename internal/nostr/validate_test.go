@@ -0,0 +1,78 @@
+package nostr
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEventStrict(t *testing.T) {
+	valid := mustValidEvent(t)
+	b, err := json.Marshal(valid)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	if evt, err := ParseEventStrict(b); err != nil || evt.ID != valid.ID {
+		t.Fatalf("ParseEventStrict(valid) = %+v, %v", evt, err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "not an object", input: `["EVENT"]`, want: "not a JSON object"},
+		{name: "missing sig", input: `{"id":"a","pubkey":"b","created_at":1,"kind":1,"tags":[],"content":""}`, want: `missing field "sig"`},
+		{name: "null tags", input: `{"id":"a","pubkey":"b","created_at":1,"kind":1,"tags":null,"content":"","sig":"c"}`, want: `missing field "tags"`},
+		{name: "numeric tag value", input: `{"id":"a","pubkey":"b","created_at":1,"kind":1,"tags":[["t",1]],"content":"","sig":"c"}`, want: "invalid field type"},
+		{name: "tag not an array", input: `{"id":"a","pubkey":"b","created_at":1,"kind":1,"tags":[null],"content":"","sig":"c"}`, want: "tag 0 is not an array"},
+		{name: "null tag value", input: `{"id":"a","pubkey":"b","created_at":1,"kind":1,"tags":[["e",null]],"content":"","sig":"c"}`, want: "tag 0 value 1 is not a string"},
+		{name: "fractional created_at", input: `{"id":"a","pubkey":"b","created_at":1.5,"kind":1,"tags":[],"content":"","sig":"c"}`, want: "invalid field type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseEventStrict([]byte(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ParseEventStrict() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventValidate(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name   string
+		mutate func(*Event)
+		want   []string
+	}{
+		{name: "valid", mutate: func(*Event) {}},
+		{name: "uppercase id", mutate: func(e *Event) { e.ID = strings.ToUpper(e.ID) }, want: []string{"id must be"}},
+		{name: "short pubkey", mutate: func(e *Event) { e.PubKey = e.PubKey[:10] }, want: []string{"pubkey must be"}},
+		{name: "bad sig", mutate: func(e *Event) { e.Sig = "zz" }, want: []string{"sig must be"}},
+		{name: "kind out of range", mutate: func(e *Event) { e.Kind = 70000 }, want: []string{"kind 70000"}},
+		{name: "zero created_at", mutate: func(e *Event) { e.CreatedAt = 0 }, want: []string{"not a positive"}},
+		{name: "future created_at", mutate: func(e *Event) { e.CreatedAt = now.Add(48 * time.Hour).Unix() }, want: []string{"too far in the future"}},
+		{name: "empty tag", mutate: func(e *Event) { e.Tags = append(e.Tags, []string{}) }, want: []string{"tag 1 is empty"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt := mustValidEvent(t)
+			tt.mutate(&evt)
+			problems := evt.Validate(now)
+			if len(problems) != len(tt.want) {
+				t.Fatalf("Validate() = %v, want %d problems", problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(problems[i].Error(), want) {
+					t.Fatalf("problem %d = %v, want %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,478 @@
+// Package relaytest provides an in-memory NIP-01 relay for tests and offline
+// development, in the spirit of net/http/httptest.
+package relaytest
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"noscli/internal/nostr"
+)
+
+// Faults configures misbehaviour applied to messages the relay sends.
+type Faults struct {
+	// Delay is waited before each message.
+	Delay time.Duration
+	// DropEvery silently drops every nth EVENT message; zero drops nothing.
+	DropEvery int
+	// Malformed sends a frame that is not valid JSON before each message.
+	Malformed bool
+}
+
+// Relay is a NIP-01 relay listening on a local httptest server. It stores
+// every accepted event in memory and serves REQ, EVENT and CLOSE.
+type Relay struct {
+	// URL is the ws:// address of the relay.
+	URL string
+
+	server   *httptest.Server
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	events  []nostr.Event
+	conns   map[*conn]struct{}
+	filters []nostr.Filter
+	dials   int
+	faults  Faults
+	sent    int
+	reject  func(nostr.Event) string
+	auth    string
+	neg     bool
+}
+
+// New starts a relay. Callers should call Close when finished.
+func New() *Relay {
+	r := &Relay{conns: make(map[*conn]struct{})}
+	r.server = httptest.NewServer(http.HandlerFunc(r.serve))
+	r.URL = "ws" + strings.TrimPrefix(r.server.URL, "http")
+	return r
+}
+
+// Close disconnects all clients and shuts the relay down.
+func (r *Relay) Close() {
+	r.DropConnections()
+	r.server.Close()
+}
+
+// SetFaults replaces the faults applied to outgoing messages.
+func (r *Relay) SetFaults(f Faults) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.faults = f
+	r.sent = 0
+}
+
+// SetReject installs fn to vet incoming events: a non-empty result rejects
+// the event with that OK message, e.g. "blocked: not allowed".
+func (r *Relay) SetReject(fn func(nostr.Event) string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reject = fn
+}
+
+// RequireAuth makes the relay send a NIP-42 AUTH challenge on connect and
+// refuse REQ and EVENT with "auth-required:" afterwards. The relay does not
+// accept AUTH responses; it is meant to test how clients cope with refusal.
+func (r *Relay) RequireAuth(challenge string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.auth = challenge
+}
+
+// EnableNegentropy makes the relay answer NIP-77 NEG-OPEN. By default it
+// replies with a NOTICE, like relays that do not support negentropy.
+func (r *Relay) EnableNegentropy() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.neg = true
+}
+
+// Publish stores evt and delivers it to matching subscriptions, as if another
+// client had sent it. The event is not verified, so tests can inject invalid ones.
+func (r *Relay) Publish(evt nostr.Event) {
+	r.store(evt)
+	r.broadcast(evt)
+}
+
+// Events returns the stored events in the order they were accepted.
+func (r *Relay) Events() []nostr.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]nostr.Event(nil), r.events...)
+}
+
+// Filters returns every filter received in a REQ, in order.
+func (r *Relay) Filters() []nostr.Filter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]nostr.Filter(nil), r.filters...)
+}
+
+// Dials reports how many WebSocket connections the relay has accepted.
+func (r *Relay) Dials() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dials
+}
+
+// Notice sends a NOTICE to every connected client.
+func (r *Relay) Notice(message string) {
+	for _, c := range r.connections() {
+		c.send("NOTICE", message)
+	}
+}
+
+// CloseSubscriptions ends every open subscription with a CLOSED message.
+func (r *Relay) CloseSubscriptions(reason string) {
+	for _, c := range r.connections() {
+		for _, id := range c.subIDs() {
+			c.unsubscribe(id)
+			c.send("CLOSED", id, reason)
+		}
+	}
+}
+
+// DropConnections closes every client connection without a close handshake.
+func (r *Relay) DropConnections() {
+	for _, c := range r.connections() {
+		c.ws.Close()
+	}
+}
+
+func (r *Relay) connections() []*conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]*conn, 0, len(r.conns))
+	for c := range r.conns {
+		out = append(out, c)
+	}
+	return out
+}
+
+// store adds evt unless an event with the same ID exists and reports whether it was new.
+func (r *Relay) store(evt nostr.Event) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.events {
+		if existing.ID == evt.ID {
+			return false
+		}
+	}
+	r.events = append(r.events, evt)
+	return true
+}
+
+// query returns stored events matching filter, newest first, up to its limit.
+func (r *Relay) query(filter nostr.Filter) []nostr.Event {
+	r.mu.Lock()
+	var out []nostr.Event
+	for _, evt := range r.events {
+		if filter.Matches(evt) {
+			out = append(out, evt)
+		}
+	}
+	r.mu.Unlock()
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt > out[j].CreatedAt })
+	if filter.Limit > 0 && len(out) > filter.Limit {
+		out = out[:filter.Limit]
+	}
+	return out
+}
+
+func (r *Relay) broadcast(evt nostr.Event) {
+	for _, c := range r.connections() {
+		for _, id := range c.matching(evt) {
+			c.send("EVENT", id, evt)
+		}
+	}
+}
+
+// outgoing applies the configured faults to the next message of type msgType
+// and reports whether it should be sent.
+func (r *Relay) outgoing(msgType string) (send bool, delay time.Duration, malformed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if msgType == "EVENT" && r.faults.DropEvery > 0 {
+		r.sent++
+		if r.sent%r.faults.DropEvery == 0 {
+			return false, 0, false
+		}
+	}
+	return true, r.faults.Delay, r.faults.Malformed
+}
+
+func (r *Relay) serve(w http.ResponseWriter, req *http.Request) {
+	ws, err := r.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return
+	}
+	c := &conn{relay: r, ws: ws, subs: make(map[string][]nostr.Filter), negs: make(map[string]*nostr.Negentropy)}
+
+	r.mu.Lock()
+	r.conns[c] = struct{}{}
+	r.dials++
+	challenge := r.auth
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.conns, c)
+		r.mu.Unlock()
+		ws.Close()
+	}()
+
+	if challenge != "" {
+		c.send("AUTH", challenge)
+	}
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.handle(data)
+	}
+}
+
+// conn is one client connection.
+type conn struct {
+	relay *Relay
+	ws    *websocket.Conn
+
+	writeMu sync.Mutex
+
+	mu   sync.Mutex
+	subs map[string][]nostr.Filter
+	negs map[string]*nostr.Negentropy
+}
+
+func (c *conn) handle(data []byte) {
+	var msg []json.RawMessage
+	if err := json.Unmarshal(data, &msg); err != nil || len(msg) == 0 {
+		c.send("NOTICE", "invalid: message is not a JSON array")
+		return
+	}
+	var msgType string
+	if err := json.Unmarshal(msg[0], &msgType); err != nil {
+		c.send("NOTICE", "invalid: message type is not a string")
+		return
+	}
+
+	c.relay.mu.Lock()
+	authRequired := c.relay.auth != ""
+	negentropy := c.relay.neg
+	c.relay.mu.Unlock()
+
+	switch msgType {
+	case "EVENT":
+		c.handleEvent(msg, authRequired)
+	case "REQ":
+		c.handleReq(msg, authRequired)
+	case "CLOSE":
+		var id string
+		if len(msg) > 1 && json.Unmarshal(msg[1], &id) == nil {
+			c.unsubscribe(id)
+		}
+	case "NEG-OPEN", "NEG-MSG", "NEG-CLOSE":
+		if !negentropy {
+			c.send("NOTICE", fmt.Sprintf("unknown message type %q", msgType))
+			return
+		}
+		c.handleNegentropy(msgType, msg)
+	default:
+		c.send("NOTICE", fmt.Sprintf("unknown message type %q", msgType))
+	}
+}
+
+func (c *conn) handleNegentropy(msgType string, msg []json.RawMessage) {
+	var id string
+	if len(msg) < 2 || json.Unmarshal(msg[1], &id) != nil {
+		c.send("NOTICE", "invalid: "+msgType+" needs a subscription id")
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var encoded string
+	switch msgType {
+	case "NEG-CLOSE":
+		delete(c.negs, id)
+		return
+	case "NEG-OPEN":
+		var f nostr.Filter
+		if len(msg) < 4 || json.Unmarshal(msg[2], &f) != nil || json.Unmarshal(msg[3], &encoded) != nil {
+			c.send("NEG-ERR", id, "invalid: NEG-OPEN needs a filter and a message")
+			return
+		}
+		f.Limit = 0
+		neg, err := nostr.NewNegentropy(c.relay.query(f), nostr.NegentropyFrameLimit)
+		if err != nil {
+			c.send("NEG-ERR", id, "error: "+err.Error())
+			return
+		}
+		c.negs[id] = neg
+	case "NEG-MSG":
+		if len(msg) < 3 || json.Unmarshal(msg[2], &encoded) != nil {
+			c.send("NEG-ERR", id, "invalid: NEG-MSG needs a message")
+			return
+		}
+	}
+
+	neg, ok := c.negs[id]
+	if !ok {
+		c.send("NEG-ERR", id, "closed: unknown subscription")
+		return
+	}
+	query, err := hex.DecodeString(encoded)
+	if err != nil {
+		c.send("NEG-ERR", id, "invalid: message is not hex")
+		return
+	}
+	reply, err := neg.Respond(query)
+	if err != nil {
+		delete(c.negs, id)
+		c.send("NEG-ERR", id, "invalid: "+err.Error())
+		return
+	}
+	c.send("NEG-MSG", id, hex.EncodeToString(reply))
+}
+
+func (c *conn) handleEvent(msg []json.RawMessage, authRequired bool) {
+	if len(msg) < 2 {
+		c.send("NOTICE", "invalid: EVENT without event")
+		return
+	}
+	evt, err := nostr.ParseEventStrict(msg[1])
+	if err != nil {
+		c.send("NOTICE", "invalid: "+err.Error())
+		return
+	}
+	if authRequired {
+		c.send("OK", evt.ID, false, "auth-required: authenticate to publish")
+		return
+	}
+	if err := evt.Verify(); err != nil {
+		c.send("OK", evt.ID, false, "invalid: "+err.Error())
+		return
+	}
+
+	c.relay.mu.Lock()
+	reject := c.relay.reject
+	c.relay.mu.Unlock()
+	if reject != nil {
+		if reason := reject(evt); reason != "" {
+			c.send("OK", evt.ID, false, reason)
+			return
+		}
+	}
+
+	if !c.relay.store(evt) {
+		c.send("OK", evt.ID, true, "duplicate: already have this event")
+		return
+	}
+	c.send("OK", evt.ID, true, "")
+	c.relay.broadcast(evt)
+}
+
+func (c *conn) handleReq(msg []json.RawMessage, authRequired bool) {
+	var id string
+	if len(msg) < 3 || json.Unmarshal(msg[1], &id) != nil {
+		c.send("NOTICE", "invalid: REQ needs a subscription id and filters")
+		return
+	}
+	if authRequired {
+		c.send("CLOSED", id, "auth-required: authenticate to read")
+		return
+	}
+
+	filters := make([]nostr.Filter, 0, len(msg)-2)
+	for _, raw := range msg[2:] {
+		var f nostr.Filter
+		if err := json.Unmarshal(raw, &f); err != nil {
+			c.send("CLOSED", id, "invalid: "+err.Error())
+			return
+		}
+		filters = append(filters, f)
+	}
+
+	c.relay.mu.Lock()
+	c.relay.filters = append(c.relay.filters, filters...)
+	c.relay.mu.Unlock()
+
+	c.mu.Lock()
+	c.subs[id] = filters
+	c.mu.Unlock()
+
+	seen := make(map[string]struct{})
+	for _, f := range filters {
+		for _, evt := range c.relay.query(f) {
+			if _, ok := seen[evt.ID]; ok {
+				continue
+			}
+			seen[evt.ID] = struct{}{}
+			c.send("EVENT", id, evt)
+		}
+	}
+	c.send("EOSE", id)
+}
+
+func (c *conn) unsubscribe(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.subs, id)
+}
+
+func (c *conn) subIDs() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids := make([]string, 0, len(c.subs))
+	for id := range c.subs {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// matching returns the subscriptions whose filters match evt.
+func (c *conn) matching(evt nostr.Event) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ids []string
+	for id, filters := range c.subs {
+		for _, f := range filters {
+			if f.Matches(evt) {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// send writes a relay message, applying the relay's faults. Write errors are
+// ignored; the read loop notices the broken connection.
+func (c *conn) send(msgType string, args ...any) {
+	ok, delay, malformed := c.relay.outgoing(msgType)
+	if !ok {
+		return
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if malformed {
+		_ = c.ws.WriteMessage(websocket.TextMessage, []byte(`["EVENT",`))
+	}
+	_ = c.ws.WriteJSON(append([]any{msgType}, args...))
+}
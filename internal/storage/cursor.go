@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cursor persists a single timestamp in a small JSON file, e.g. the newest
+// notification already shown to the user, optionally with the IDs already
+// handled at that second.
+type Cursor struct {
+	path string
+}
+
+type cursorFile struct {
+	LastSeen int64    `json:"last_seen"`
+	Seen     []string `json:"seen,omitempty"`
+}
+
+// NewCursor returns a Cursor stored at path. The file is created on first Save.
+func NewCursor(path string) *Cursor {
+	return &Cursor{path: path}
+}
+
+// Load returns the stored timestamp, or the zero time if nothing was saved yet.
+func (c *Cursor) Load() (time.Time, error) {
+	t, _, err := c.LoadSeen()
+	return t, err
+}
+
+// LoadSeen returns the stored timestamp and the IDs saved with it.
+func (c *Cursor) LoadSeen() (time.Time, []string, error) {
+	b, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return time.Time{}, nil, nil
+	}
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	var f cursorFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return time.Time{}, nil, fmt.Errorf("decode %s: %w", c.path, err)
+	}
+	if f.LastSeen == 0 {
+		return time.Time{}, nil, nil
+	}
+	return time.Unix(f.LastSeen, 0).UTC(), f.Seen, nil
+}
+
+// Save atomically replaces the stored timestamp.
+func (c *Cursor) Save(t time.Time) error {
+	return c.SaveSeen(t, nil)
+}
+
+// SaveSeen atomically replaces the stored timestamp and the IDs already
+// handled at that second, which a query with an inclusive since returns again.
+func (c *Cursor) SaveSeen(t time.Time, ids []string) error {
+	b, err := json.Marshal(cursorFile{LastSeen: t.Unix(), Seen: ids})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path, b)
+}
+
+// Clear removes the stored timestamp so the next Load returns the zero time.
+func (c *Cursor) Clear() error {
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// writeFileAtomic writes data next to path and renames it into place so a
+// crash never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "cursor.json")
+	cursor := NewCursor(path)
+
+	got, err := cursor.Load()
+	if err != nil {
+		t.Fatalf("Load() on missing file unexpected error: %v", err)
+	}
+	if !got.IsZero() {
+		t.Fatalf("Load() on missing file = %v, want zero", got)
+	}
+
+	want := time.Unix(1_700_000_000, 0).UTC()
+	if err := cursor.Save(want); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	got, err = NewCursor(path).Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if !got.Equal(want) {
+		t.Fatalf("Load() = %v, want %v", got, want)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() unexpected error: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("cursor file mode = %o, want 600", perm)
+	}
+}
+
+func TestCursorClear(t *testing.T) {
+	cursor := NewCursor(filepath.Join(t.TempDir(), "cursor.json"))
+	if err := cursor.Clear(); err != nil {
+		t.Fatalf("Clear() on missing file unexpected error: %v", err)
+	}
+
+	if err := cursor.Save(time.Unix(1_700_000_000, 0)); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	if err := cursor.Clear(); err != nil {
+		t.Fatalf("Clear() unexpected error: %v", err)
+	}
+	got, err := cursor.Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if !got.IsZero() {
+		t.Fatalf("Load() after Clear() = %v, want zero", got)
+	}
+}
+
+func TestCursorSeen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cursor.json")
+	want := time.Unix(1_700_000_000, 0).UTC()
+	if err := NewCursor(path).SaveSeen(want, []string{"a", "b"}); err != nil {
+		t.Fatalf("SaveSeen() unexpected error: %v", err)
+	}
+
+	got, ids, err := NewCursor(path).LoadSeen()
+	if err != nil {
+		t.Fatalf("LoadSeen() unexpected error: %v", err)
+	}
+	if !got.Equal(want) || strings.Join(ids, ",") != "a,b" {
+		t.Fatalf("LoadSeen() = %v, %v; want %v, [a b]", got, ids, want)
+	}
+
+	// Save keeps the timestamp only.
+	if err := NewCursor(path).Save(want); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	if _, ids, _ := NewCursor(path).LoadSeen(); len(ids) != 0 {
+		t.Fatalf("LoadSeen() ids after Save() = %v, want none", ids)
+	}
+}
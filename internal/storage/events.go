@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+
+	"noscli/internal/nostr"
+)
+
+// EventsFile is the name of the append-only event log inside the data directory.
+const EventsFile = "events.jsonl"
+
+// EventStore is an embedded event cache backed by an append-only JSON lines
+// file. The whole log is loaded on open and indexed in memory by id, author
+// and kind; queries use the same semantics as a relay REQ filter.
+type EventStore struct {
+	mu   sync.RWMutex
+	file *os.File
+
+	events   []nostr.Event
+	byID     map[string]int
+	byAuthor map[string][]int
+	byKind   map[int][]int
+}
+
+// storedEvent is one line of the log. The relay is kept alongside the event
+// because nostr.Event does not serialize it.
+type storedEvent struct {
+	Relay string      `json:"relay,omitempty"`
+	Event nostr.Event `json:"event"`
+}
+
+// OpenEventStore opens (or creates) the event log in dir.
+func OpenEventStore(dir string) (*EventStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, EventsFile)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &EventStore{
+		file:     file,
+		byID:     make(map[string]int),
+		byAuthor: make(map[string][]int),
+		byKind:   make(map[int][]int),
+	}
+	if err := s.load(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *EventStore) load() error {
+	scanner := bufio.NewScanner(s.file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var rec storedEvent
+		// 書き込み途中で中断された行などは読み飛ばす
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil || rec.Event.ID == "" {
+			continue
+		}
+		rec.Event.Relay = rec.Relay
+		s.index(rec.Event)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return s.terminateLastLine()
+}
+
+// terminateLastLine appends a newline if the log ends with a partial record,
+// so the next append starts on a fresh line.
+func (s *EventStore) terminateLastLine() error {
+	info, err := s.file.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := s.file.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, err = s.file.Write([]byte{'\n'})
+	return err
+}
+
+// Close releases the underlying file.
+func (s *EventStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// Save verifies evt and appends it to the log unless an event with the same ID
+// is already stored.
+func (s *EventStore) Save(evt nostr.Event) error {
+	if err := evt.Verify(); err != nil {
+		return fmt.Errorf("refuse to store invalid event %s: %w", evt.ID, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.byID[evt.ID]; ok {
+		return nil
+	}
+
+	line, err := json.Marshal(storedEvent{Relay: evt.Relay, Event: evt})
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.index(evt)
+	return nil
+}
+
+// Get returns the stored event with the given ID.
+func (s *EventStore) Get(id string) (nostr.Event, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i, ok := s.byID[id]
+	if !ok {
+		return nostr.Event{}, false
+	}
+	return s.events[i], true
+}
+
+// Query returns the stored events matching filter, newest first, honouring Limit.
+func (s *EventStore) Query(filter nostr.Filter) ([]nostr.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []nostr.Event
+	for _, i := range s.candidates(filter) {
+		if evt := s.events[i]; filter.Matches(evt) {
+			result = append(result, evt)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].CreatedAt != result[j].CreatedAt {
+			return result[i].CreatedAt > result[j].CreatedAt
+		}
+		return result[i].ID < result[j].ID
+	})
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result, nil
+}
+
+// candidates narrows the scan using the most selective index available.
+// Filters may repeat a value, so the indexes are deduplicated.
+func (s *EventStore) candidates(filter nostr.Filter) []int {
+	var out []int
+	switch {
+	case len(filter.IDs) > 0:
+		for _, id := range filter.IDs {
+			if i, ok := s.byID[id]; ok {
+				out = append(out, i)
+			}
+		}
+	case len(filter.Authors) > 0:
+		for _, author := range filter.Authors {
+			out = append(out, s.byAuthor[author]...)
+		}
+	case len(filter.Kinds) > 0:
+		for _, kind := range filter.Kinds {
+			out = append(out, s.byKind[kind]...)
+		}
+	default:
+		out = make([]int, len(s.events))
+		for i := range out {
+			out[i] = i
+		}
+		return out
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+func (s *EventStore) index(evt nostr.Event) {
+	if _, ok := s.byID[evt.ID]; ok {
+		return
+	}
+	i := len(s.events)
+	s.events = append(s.events, evt)
+	s.byID[evt.ID] = i
+	s.byAuthor[evt.PubKey] = append(s.byAuthor[evt.PubKey], i)
+	s.byKind[evt.Kind] = append(s.byKind[evt.Kind], i)
+}
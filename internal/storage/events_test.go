@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"noscli/internal/nostr"
+)
+
+func TestEventStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenEventStore(dir)
+	if err != nil {
+		t.Fatalf("OpenEventStore() unexpected error: %v", err)
+	}
+
+	note1 := signedEvent(t, 1, 100, "first #nostr", [][]string{{"t", "nostr"}})
+	note2 := signedEvent(t, 1, 200, "second", [][]string{})
+	reaction := signedEvent(t, 7, 300, "+", [][]string{{"e", note1.ID}})
+	note1.Relay = "wss://relay.example.com"
+
+	for _, evt := range []nostr.Event{note1, note2, reaction, note1} {
+		if err := store.Save(evt); err != nil {
+			t.Fatalf("Save() unexpected error: %v", err)
+		}
+	}
+
+	invalid := note2
+	invalid.Content = "tampered"
+	if err := store.Save(invalid); err == nil {
+		t.Fatalf("Save() of tampered event expected error")
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	// Simulate an interrupted write at the end of the log.
+	f, err := os.OpenFile(filepath.Join(dir, EventsFile), os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatalf("open log: %v", err)
+	}
+	if _, err := f.WriteString(`{"event":{"id":"trunc`); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+	f.Close()
+
+	store, err = OpenEventStore(dir)
+	if err != nil {
+		t.Fatalf("reopen unexpected error: %v", err)
+	}
+
+	late := signedEvent(t, 1, 50, "saved after recovery", [][]string{})
+	if err := store.Save(late); err != nil {
+		t.Fatalf("Save() after recovery unexpected error: %v", err)
+	}
+	store.Close()
+	store, err = OpenEventStore(dir)
+	if err != nil {
+		t.Fatalf("reopen unexpected error: %v", err)
+	}
+	defer store.Close()
+	if _, ok := store.Get(late.ID); !ok {
+		t.Fatalf("event saved after a truncated line was lost")
+	}
+
+	got, ok := store.Get(note1.ID)
+	if !ok || got.Relay != "wss://relay.example.com" {
+		t.Fatalf("Get() = %+v, %v; want stored note with relay", got, ok)
+	}
+
+	tests := []struct {
+		name    string
+		filter  nostr.Filter
+		wantIDs []string
+	}{
+		{
+			name:    "all events newest first",
+			filter:  nostr.Filter{},
+			wantIDs: []string{reaction.ID, note2.ID, note1.ID, late.ID},
+		},
+		{
+			name:    "kind with limit",
+			filter:  nostr.Filter{Kinds: []int{1}, Limit: 1},
+			wantIDs: []string{note2.ID},
+		},
+		{
+			name:    "tag filter",
+			filter:  nostr.Filter{Tags: map[string][]string{"t": {"nostr"}}},
+			wantIDs: []string{note1.ID},
+		},
+		{
+			name:    "author and since",
+			filter:  nostr.Filter{Authors: []string{note1.PubKey}, Since: timePtr(150)},
+			wantIDs: []string{reaction.ID, note2.ID},
+		},
+		{
+			name:    "ids",
+			filter:  nostr.Filter{IDs: []string{reaction.ID, "missing"}},
+			wantIDs: []string{reaction.ID},
+		},
+		{
+			name:    "repeated values",
+			filter:  nostr.Filter{IDs: []string{note2.ID, note2.ID}},
+			wantIDs: []string{note2.ID},
+		},
+		{
+			name:    "repeated kinds",
+			filter:  nostr.Filter{Kinds: []int{7, 7}},
+			wantIDs: []string{reaction.ID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := store.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("Query() unexpected error: %v", err)
+			}
+			if len(events) != len(tt.wantIDs) {
+				t.Fatalf("Query() returned %d events, want %d", len(events), len(tt.wantIDs))
+			}
+			for i, evt := range events {
+				if evt.ID != tt.wantIDs[i] {
+					t.Fatalf("Query()[%d] = %s, want %s", i, evt.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
+
+func signedEvent(t *testing.T, kind int, createdAt int64, content string, tags [][]string) nostr.Event {
+	t.Helper()
+
+	priv := bytes.Repeat([]byte{0x01}, 32)
+	pub, err := nostr.PublicKey(priv)
+	if err != nil {
+		t.Fatalf("PublicKey() unexpected error: %v", err)
+	}
+	evt := nostr.Event{
+		PubKey:    pub,
+		CreatedAt: createdAt,
+		Kind:      kind,
+		Tags:      tags,
+		Content:   content,
+	}
+	if err := nostr.SignEvent(&evt, priv); err != nil {
+		t.Fatalf("SignEvent() unexpected error: %v", err)
+	}
+	return evt
+}
+
+func timePtr(unix int64) *time.Time {
+	t := time.Unix(unix, 0)
+	return &t
+}
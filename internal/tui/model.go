@@ -0,0 +1,414 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"noscli/internal/app/event"
+	"noscli/internal/app/notifications"
+	"noscli/internal/app/post"
+	"noscli/internal/app/timeline"
+	"noscli/internal/nostr"
+)
+
+// maxEntries bounds how many notes are kept in memory; older ones are dropped.
+const maxEntries = 500
+
+const (
+	composeHeight = 4
+	helpBrowse    = "j/k 移動  n 投稿  r 返信  l リアクション  t リポスト  q 終了"
+	helpCompose   = "ctrl+s 送信  esc キャンセル"
+)
+
+var (
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	statusStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(lipgloss.Color("7"))
+	helpStyle     = lipgloss.NewStyle().Faint(true)
+)
+
+// itemMsg carries an update from the timeline stream.
+type itemMsg struct {
+	item timeline.Item
+}
+
+// timelineDoneMsg is sent when the timeline stream has been closed.
+type timelineDoneMsg struct{}
+
+// actionMsg reports the outcome of a post, reply, reaction or repost.
+type actionMsg struct {
+	result string
+	err    error
+}
+
+type model struct {
+	ctx       context.Context
+	opts      Options
+	items     <-chan timeline.Item
+	poster    Poster
+	publisher Publisher
+	plain     timeline.PlainRenderer
+
+	// entries holds received notes, newest first.
+	entries []timeline.Entry
+	cursor  int
+	offset  int
+
+	compose   textarea.Model
+	composing bool
+	// replyTo is the note being replied to while composing, if any.
+	replyTo *timeline.Entry
+
+	relays     map[string]timeline.RelayStatus
+	status     string
+	statusErr  bool
+	width      int
+	height     int
+	streamDone bool
+}
+
+func newModel(ctx context.Context, opts Options, items <-chan timeline.Item, poster Poster, publisher Publisher) *model {
+	compose := textarea.New()
+	compose.Placeholder = "いまどうしてる？"
+	compose.ShowLineNumbers = false
+	compose.SetHeight(composeHeight)
+
+	return &model{
+		ctx:       ctx,
+		opts:      opts,
+		items:     items,
+		poster:    poster,
+		publisher: publisher,
+		plain:     timeline.PlainRenderer{ShowSensitive: opts.ShowSensitive},
+		compose:   compose,
+		relays:    make(map[string]timeline.RelayStatus),
+	}
+}
+
+// Init implements tea.Model.
+func (m *model) Init() tea.Cmd {
+	return m.wait()
+}
+
+// wait returns a command that delivers the next timeline update.
+func (m *model) wait() tea.Cmd {
+	return func() tea.Msg {
+		item, ok := <-m.items
+		if !ok {
+			return timelineDoneMsg{}
+		}
+		return itemMsg{item: item}
+	}
+}
+
+// Update implements tea.Model.
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.compose.SetWidth(msg.Width)
+		m.scroll()
+		return m, nil
+	case itemMsg:
+		m.apply(msg.item)
+		return m, m.wait()
+	case timelineDoneMsg:
+		m.streamDone = true
+		return m, nil
+	case actionMsg:
+		if msg.err != nil {
+			m.setStatus(msg.err.Error(), true)
+		} else {
+			m.setStatus(msg.result, false)
+		}
+		return m, nil
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC {
+			return m, tea.Quit
+		}
+		if m.composing {
+			return m.updateCompose(msg)
+		}
+		return m.updateBrowse(msg)
+	}
+	return m, nil
+}
+
+func (m *model) updateBrowse(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "j", "down":
+		m.move(1)
+	case "k", "up":
+		m.move(-1)
+	case "pgdown":
+		m.move(m.listHeight())
+	case "pgup":
+		m.move(-m.listHeight())
+	case "g", "home":
+		m.move(-len(m.entries))
+	case "G", "end":
+		m.move(len(m.entries))
+	case "n":
+		return m, m.startCompose(nil)
+	case "r":
+		if entry, ok := m.selected(); ok {
+			return m, m.startCompose(&entry)
+		}
+	case "l", "+":
+		if entry, ok := m.selected(); ok {
+			return m, m.react(entry)
+		}
+	case "t":
+		if entry, ok := m.selected(); ok {
+			return m, m.repost(entry)
+		}
+	}
+	return m, nil
+}
+
+func (m *model) updateCompose(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.stopCompose()
+		return m, nil
+	case tea.KeyCtrlS:
+		content := strings.TrimSpace(m.compose.Value())
+		if content == "" {
+			m.setStatus("本文が空です", true)
+			return m, nil
+		}
+		cmd := m.post(content, m.replyTo)
+		m.stopCompose()
+		return m, cmd
+	}
+	var cmd tea.Cmd
+	m.compose, cmd = m.compose.Update(msg)
+	return m, cmd
+}
+
+func (m *model) startCompose(replyTo *timeline.Entry) tea.Cmd {
+	m.composing = true
+	m.replyTo = replyTo
+	m.compose.Reset()
+	m.scroll()
+	return m.compose.Focus()
+}
+
+func (m *model) stopCompose() {
+	m.composing = false
+	m.replyTo = nil
+	m.compose.Blur()
+	m.compose.Reset()
+	m.scroll()
+}
+
+// add prepends entry, keeping the cursor on the note it pointed at unless it
+// was following the newest one.
+func (m *model) add(entry timeline.Entry) {
+	m.entries = append([]timeline.Entry{entry}, m.entries...)
+	if len(m.entries) > maxEntries {
+		m.entries = m.entries[:maxEntries]
+	}
+	if m.cursor > 0 {
+		m.cursor = min(m.cursor+1, len(m.entries)-1)
+		m.offset++
+	}
+	m.scroll()
+}
+
+func (m *model) move(delta int) {
+	if len(m.entries) == 0 {
+		return
+	}
+	m.cursor = max(0, min(m.cursor+delta, len(m.entries)-1))
+	m.scroll()
+}
+
+// scroll adjusts offset so the cursor stays within the timeline pane.
+func (m *model) scroll() {
+	height := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+	m.offset = max(0, min(m.offset, len(m.entries)-1))
+}
+
+func (m *model) selected() (timeline.Entry, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.entries) {
+		return timeline.Entry{}, false
+	}
+	return m.entries[m.cursor], true
+}
+
+// apply updates the model with a timeline stream item.
+func (m *model) apply(item timeline.Item) {
+	switch item.Type {
+	case timeline.ItemEvent:
+		m.add(item.Entry)
+	case timeline.ItemRelayStatus:
+		m.relays[item.Relay] = item.Status
+		if item.Err != nil {
+			m.setStatus(item.Err.Error(), true)
+		}
+	case timeline.ItemError:
+		m.setStatus(item.Err.Error(), true)
+	case timeline.ItemEOSE:
+		m.setStatus(fmt.Sprintf("%s: 保存済みの投稿を受信しました", item.Relay), false)
+	}
+}
+
+func (m *model) setStatus(text string, isErr bool) {
+	m.status = strings.TrimSpace(text)
+	m.statusErr = isErr
+}
+
+// post publishes a text note, or a reply to replyTo, through the post service.
+func (m *model) post(content string, replyTo *timeline.Entry) tea.Cmd {
+	req := post.Request{Relays: m.opts.Relays, Content: content}
+	if replyTo != nil {
+		req.ReplyTo = replyTo.ID
+		req.Tags = [][]string{{"p", replyTo.PubKey}}
+	}
+	return m.run(func(buf *bytes.Buffer) error {
+		return m.poster.Run(m.ctx, req, buf)
+	})
+}
+
+// react sends a NIP-25 "+" reaction to entry.
+func (m *model) react(entry timeline.Entry) tea.Cmd {
+	evt := nostr.Event{
+		Kind:    notifications.KindReaction,
+		Content: "+",
+		Tags:    [][]string{{"e", entry.ID, entry.Relay}, {"p", entry.PubKey}},
+	}
+	return m.publish(evt)
+}
+
+// repost sends a NIP-18 repost of entry, embedding the original event.
+func (m *model) repost(entry timeline.Entry) tea.Cmd {
+	raw := []byte(entry.Raw)
+	if len(raw) == 0 {
+		var err error
+		if raw, err = json.Marshal(entry.Event); err != nil {
+			return func() tea.Msg { return actionMsg{err: err} }
+		}
+	}
+	evt := nostr.Event{
+		Kind:    notifications.KindRepost,
+		Content: string(raw),
+		Tags:    [][]string{{"e", entry.ID, entry.Relay}, {"p", entry.PubKey}},
+	}
+	return m.publish(evt)
+}
+
+func (m *model) publish(evt nostr.Event) tea.Cmd {
+	req := event.Request{Relays: m.opts.Relays, Event: evt}
+	return m.run(func(buf *bytes.Buffer) error {
+		return m.publisher.Run(m.ctx, req, buf)
+	})
+}
+
+// run executes fn in the background and reports its output as an actionMsg.
+func (m *model) run(fn func(buf *bytes.Buffer) error) tea.Cmd {
+	return func() tea.Msg {
+		var buf bytes.Buffer
+		err := fn(&buf)
+		// 複数リレーの結果は最後の行だけをステータスバーに出す
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		return actionMsg{result: lines[len(lines)-1], err: err}
+	}
+}
+
+// listHeight is the number of timeline rows that fit above the other panes.
+func (m *model) listHeight() int {
+	height := m.height - 2 // status bar and help line
+	if m.composing {
+		height -= composeHeight + 1
+	}
+	return max(1, height)
+}
+
+// View implements tea.Model.
+func (m *model) View() string {
+	var b strings.Builder
+
+	height := m.listHeight()
+	end := min(m.offset+height, len(m.entries))
+	for i := m.offset; i < end; i++ {
+		line := m.line(m.entries[i])
+		if i == m.cursor {
+			line = selectedStyle.Render(line)
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	for i := end - m.offset; i < height; i++ {
+		b.WriteByte('\n')
+	}
+
+	help := helpBrowse
+	if m.composing {
+		label := "新規投稿"
+		if m.replyTo != nil {
+			label = "返信: " + m.replyTo.Author
+		}
+		b.WriteString(m.truncate(label))
+		b.WriteByte('\n')
+		b.WriteString(m.compose.View())
+		b.WriteByte('\n')
+		help = helpCompose
+	}
+
+	b.WriteString(statusStyle.Width(m.width).Render(m.truncate(m.statusBar())))
+	b.WriteByte('\n')
+	b.WriteString(helpStyle.Render(m.truncate(help)))
+	return b.String()
+}
+
+// line renders entry the same way as the plain timeline output.
+func (m *model) line(entry timeline.Entry) string {
+	var buf bytes.Buffer
+	if err := m.plain.Render(&buf, entry); err != nil {
+		return err.Error()
+	}
+	return m.truncate(strings.TrimSuffix(buf.String(), "\n"))
+}
+
+func (m *model) statusBar() string {
+	connected := 0
+	for _, status := range m.relays {
+		if status == timeline.RelayConnected {
+			connected++
+		}
+	}
+	bar := fmt.Sprintf(" relays %d/%d  notes %d", connected, len(m.opts.Relays), len(m.entries))
+	if m.streamDone {
+		bar += "  (停止)"
+	}
+	if m.status != "" {
+		status := m.status
+		if m.statusErr {
+			status = "エラー: " + status
+		}
+		bar += "  | " + status
+	}
+	return bar
+}
+
+func (m *model) truncate(s string) string {
+	if m.width <= 0 {
+		return s
+	}
+	return lipgloss.NewStyle().MaxWidth(m.width).Render(s)
+}
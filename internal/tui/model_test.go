@@ -0,0 +1,160 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"noscli/internal/app/event"
+	"noscli/internal/app/notifications"
+	"noscli/internal/app/post"
+	"noscli/internal/app/timeline"
+	"noscli/internal/nostr"
+)
+
+type recordingPoster struct {
+	reqs []post.Request
+}
+
+func (p *recordingPoster) Run(_ context.Context, req post.Request, w io.Writer) error {
+	p.reqs = append(p.reqs, req)
+	_, err := io.WriteString(w, "published: id:abcd relay:"+strings.Join(req.Relays, ",")+"\n")
+	return err
+}
+
+type recordingPublisher struct {
+	reqs []event.Request
+}
+
+func (p *recordingPublisher) Run(_ context.Context, req event.Request, _ io.Writer) error {
+	p.reqs = append(p.reqs, req)
+	return nil
+}
+
+func newTestModel() (*model, *recordingPoster, *recordingPublisher) {
+	poster := &recordingPoster{}
+	publisher := &recordingPublisher{}
+	opts := Options{Relays: []string{"wss://a", "wss://b"}}
+	m := newModel(context.Background(), opts, nil, poster, publisher)
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 20})
+	return m, poster, publisher
+}
+
+func key(s string) tea.KeyMsg {
+	switch s {
+	case "ctrl+s":
+		return tea.KeyMsg{Type: tea.KeyCtrlS}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func note(id, pubkey, content string) timeline.Entry {
+	return timeline.Entry{
+		Event:  nostr.Event{ID: id, PubKey: pubkey, Kind: nostr.KindTextNote, Content: content, Relay: "wss://a"},
+		Author: pubkey,
+	}
+}
+
+func TestModelKeepsSelectionWhenNotesArrive(t *testing.T) {
+	m, _, _ := newTestModel()
+
+	m.Update(itemMsg{item: timeline.Item{Type: timeline.ItemEvent, Entry: note("first", "alice", "one")}})
+	m.Update(itemMsg{item: timeline.Item{Type: timeline.ItemEvent, Entry: note("second", "bob", "two")}})
+	if got, _ := m.selected(); got.ID != "second" {
+		t.Fatalf("selected %q, want newest note while at the top", got.ID)
+	}
+
+	m.Update(key("j"))
+	m.Update(itemMsg{item: timeline.Item{Type: timeline.ItemEvent, Entry: note("third", "carol", "three")}})
+	if got, _ := m.selected(); got.ID != "first" {
+		t.Fatalf("selected %q, want selection to stay on first", got.ID)
+	}
+
+	m.Update(key("g"))
+	if got, _ := m.selected(); got.ID != "third" {
+		t.Fatalf("selected %q after g, want third", got.ID)
+	}
+}
+
+func TestModelReplyUsesPostService(t *testing.T) {
+	m, poster, _ := newTestModel()
+	m.Update(itemMsg{item: timeline.Item{Type: timeline.ItemEvent, Entry: note("note1", "alice", "hello")}})
+
+	m.Update(key("r"))
+	if !m.composing || m.replyTo == nil {
+		t.Fatalf("r should open the compose box in reply mode")
+	}
+	m.Update(key("hi there"))
+	_, cmd := m.Update(key("ctrl+s"))
+	if m.composing {
+		t.Fatalf("compose box should close after sending")
+	}
+	m.Update(cmd())
+
+	if len(poster.reqs) != 1 {
+		t.Fatalf("expected one post, got %d", len(poster.reqs))
+	}
+	req := poster.reqs[0]
+	if strings.Join(req.Relays, ",") != "wss://a,wss://b" || req.Content != "hi there" || req.ReplyTo != "note1" {
+		t.Fatalf("unexpected post request: %+v", req)
+	}
+	if len(req.Tags) != 1 || req.Tags[0][0] != "p" || req.Tags[0][1] != "alice" {
+		t.Fatalf("reply should tag the author: %v", req.Tags)
+	}
+	if !strings.Contains(m.status, "published: id:abcd") {
+		t.Fatalf("status = %q, want post result", m.status)
+	}
+}
+
+func TestModelReactAndRepost(t *testing.T) {
+	m, _, publisher := newTestModel()
+	entry := note("note1", "alice", "hello")
+	entry.Raw = []byte(`{"id":"note1"}`)
+	m.Update(itemMsg{item: timeline.Item{Type: timeline.ItemEvent, Entry: entry}})
+
+	_, cmd := m.Update(key("l"))
+	cmd()
+	_, cmd = m.Update(key("t"))
+	cmd()
+
+	if len(publisher.reqs) != 2 {
+		t.Fatalf("expected two published events, got %d", len(publisher.reqs))
+	}
+	reaction := publisher.reqs[0]
+	if len(reaction.Relays) != 2 || reaction.Event.Kind != notifications.KindReaction || reaction.Event.Content != "+" {
+		t.Fatalf("unexpected reaction: %+v", reaction)
+	}
+	repost := publisher.reqs[1].Event
+	if repost.Kind != notifications.KindRepost || repost.Content != `{"id":"note1"}` {
+		t.Fatalf("unexpected repost: %+v", repost)
+	}
+	if repost.Tags[0][0] != "e" || repost.Tags[0][1] != "note1" || repost.Tags[0][2] != "wss://a" {
+		t.Fatalf("repost should reference the note and its relay: %v", repost.Tags)
+	}
+}
+
+func TestModelStatusBarShowsRelayErrors(t *testing.T) {
+	m, _, _ := newTestModel()
+
+	m.Update(itemMsg{item: timeline.Item{Type: timeline.ItemRelayStatus, Relay: "wss://a", Status: timeline.RelayConnected}})
+	if bar := m.statusBar(); !strings.Contains(bar, "relays 1/2") {
+		t.Fatalf("status bar = %q, want one connected relay", bar)
+	}
+
+	m.Update(itemMsg{item: timeline.Item{
+		Type:   timeline.ItemRelayStatus,
+		Relay:  "wss://a",
+		Status: timeline.RelayDisconnected,
+		Err:    errors.New("dial wss://a: refused"),
+	}})
+	bar := m.statusBar()
+	if !strings.Contains(bar, "relays 0/2") || !strings.Contains(bar, "dial wss://a: refused") {
+		t.Fatalf("status bar = %q, want relay error", bar)
+	}
+}
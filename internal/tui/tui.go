@@ -0,0 +1,71 @@
+// Package tui implements the interactive terminal UI on top of the
+// application services used by the CLI commands.
+package tui
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"noscli/internal/app/event"
+	"noscli/internal/app/post"
+	"noscli/internal/app/timeline"
+)
+
+// Timeline streams notes; it is satisfied by *timeline.Service.
+type Timeline interface {
+	Stream(ctx context.Context, req timeline.Request) (<-chan timeline.Item, error)
+}
+
+// Poster publishes text notes; it is satisfied by *post.Service.
+type Poster interface {
+	Run(ctx context.Context, req post.Request, w io.Writer) error
+}
+
+// Publisher publishes arbitrary events; it is satisfied by *event.Service.
+type Publisher interface {
+	Run(ctx context.Context, req event.Request, w io.Writer) error
+}
+
+// Options configures the TUI session.
+type Options struct {
+	// Relays are streamed together, and posts, replies, reactions and
+	// reposts are sent to all of them.
+	Relays []string
+	// ShowSensitive renders content of NIP-36 content-warning events instead of a placeholder.
+	ShowSensitive bool
+	// MinPoW drops events whose NIP-13 difficulty is below this many bits.
+	MinPoW int
+	// MaxFutureSkew drops events dated further than this into the future.
+	MaxFutureSkew time.Duration
+}
+
+// Run starts the timeline and blocks until the user quits.
+func Run(ctx context.Context, opts Options, tl Timeline, poster Poster, publisher Publisher) error {
+	if len(opts.Relays) == 0 {
+		return errors.New("relay is required")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	items, err := tl.Stream(ctx, timeline.Request{
+		Relays:        opts.Relays,
+		ShowSensitive: opts.ShowSensitive,
+		MinPoW:        opts.MinPoW,
+		MaxFutureSkew: opts.MaxFutureSkew,
+	})
+	if err != nil {
+		return err
+	}
+
+	m := newModel(ctx, opts, items, poster, publisher)
+	_, err = tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	if errors.Is(err, tea.ErrProgramKilled) && ctx.Err() != nil {
+		return nil
+	}
+	return err
+}